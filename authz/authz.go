@@ -82,6 +82,10 @@ p, *, *, GET, /api/get-saml-login, *, *
 p, *, *, POST, /api/acs, *, *
 p, *, *, GET, /api/saml/metadata, *, *
 p, *, *, *, /api/saml/redirect, *, *
+p, *, *, *, /api/saml/slo, *, *
+p, *, *, GET, /api/saml/idp-initiated, *, *
+p, *, *, POST, /api/saml/artifact, *, *
+p, *, *, *, /api/wsfed, *, *
 p, *, *, *, /cas, *, *
 p, *, *, *, /scim, *, *
 p, *, *, *, /api/webauthn, *, *
@@ -99,6 +103,13 @@ p, *, *, GET, /api/get-all-actions, *, *
 p, *, *, GET, /api/get-all-roles, *, *
 p, *, *, GET, /api/get-invitation-info, *, *
 p, *, *, GET, /api/faceid-signin-begin, *, *
+p, *, *, GET, /api/get-device-auth, *, *
+p, *, *, POST, /api/approve-device-auth, *, *
+p, *, *, GET, /api/get-ciba-auths, *, *
+p, *, *, POST, /api/approve-ciba-auth, *, *
+p, *, *, GET, /api/get-user-consents, *, *
+p, *, *, POST, /api/grant-consent, *, *
+p, *, *, POST, /api/revoke-consent, *, *
 `
 
 		sa := stringadapter.NewAdapter(ruleText)
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/casdoor/casdoor/form"
@@ -32,6 +33,7 @@ const (
 	ResponseTypeIdToken = "id_token"
 	ResponseTypeSaml    = "saml"
 	ResponseTypeCas     = "cas"
+	ResponseTypeWsFed   = "wsfed"
 )
 
 type Response struct {
@@ -41,6 +43,7 @@ type Response struct {
 	Name   string      `json:"name"`
 	Data   interface{} `json:"data"`
 	Data2  interface{} `json:"data2"`
+	Data3  interface{} `json:"data3,omitempty"`
 }
 
 type Captcha struct {
@@ -263,6 +266,7 @@ func (c *ApiController) Signup() {
 	if application.HasPromptPage() && user.Type == "normal-user" {
 		// The prompt page needs the user to be signed in
 		c.SetSessionUsername(user.GetId())
+		c.setAuthMethods(object.AmrPassword)
 	}
 
 	if authForm.Email != "" {
@@ -295,19 +299,22 @@ func (c *ApiController) Signup() {
 // @Tag Login API
 // @Description logout the current user
 // @Param   id_token_hint   query        string  false        "id_token_hint"
+// @Param   client_id   query        string  false        "client_id"
 // @Param   post_logout_redirect_uri    query    string  false     "post_logout_redirect_uri"
 // @Param   state     query    string  false     "state"
+// @Param   logout_confirm_token     formData    string  false     "the token of the logout confirmation page"
 // @Success 200 {object} controllers.Response The Response object
 // @router /logout [post]
 func (c *ApiController) Logout() {
 	// https://openid.net/specs/openid-connect-rpinitiated-1_0-final.html
 	accessToken := c.Input().Get("id_token_hint")
+	clientId := c.Input().Get("client_id")
 	redirectUri := c.Input().Get("post_logout_redirect_uri")
 	state := c.Input().Get("state")
 
 	user := c.GetSessionUsername()
 
-	if accessToken == "" && redirectUri == "" {
+	if accessToken == "" && clientId == "" && redirectUri == "" {
 		// TODO https://github.com/casdoor/casdoor/pull/1494#discussion_r1095675265
 		if user == "" {
 			c.ResponseOk()
@@ -316,8 +323,14 @@ func (c *ApiController) Logout() {
 
 		c.ClearUserSession()
 		c.ClearTokenSession()
+		frontchannelLogoutUrls, err := c.logoutSessionApplications(user)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
 		owner, username := util.GetOwnerAndNameFromId(user)
-		_, err := object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), c.Ctx.Input.CruSession.SessionID())
+		_, err = object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), c.Ctx.Input.CruSession.SessionID())
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
@@ -326,73 +339,113 @@ func (c *ApiController) Logout() {
 		util.LogInfo(c.Ctx, "API: [%s] logged out", user)
 
 		application := c.GetSessionApplication()
+		// the front-channel logout URLs are loaded in iframes by the logout page
+		resp := &Response{Status: "ok", Data3: frontchannelLogoutUrls}
 		if application == nil || application.Name == "app-built-in" || application.HomepageUrl == "" {
-			c.ResponseOk(user)
+			c.ResponseJsonData(resp, user)
 			return
 		}
-		c.ResponseOk(user, application.HomepageUrl)
+		c.ResponseJsonData(resp, user, application.HomepageUrl)
 		return
 	} else {
 		// "post_logout_redirect_uri" has been made optional, see: https://github.com/casdoor/casdoor/issues/2151
-		// if redirectUri == "" {
-		// 	c.ResponseError(c.T("general:Missing parameter") + ": post_logout_redirect_uri")
-		// 	return
-		// }
-		if accessToken == "" {
+		// the client is identified by the id_token_hint or client_id, to validate the post_logout_redirect_uri against
+		if accessToken == "" && clientId == "" {
 			c.ResponseError(c.T("general:Missing parameter") + ": id_token_hint")
 			return
 		}
 
-		_, application, token, err := object.ExpireTokenByAccessToken(accessToken)
-		if err != nil {
-			c.ResponseError(err.Error())
-			return
-		}
-		if token == nil {
-			c.ResponseError(c.T("token:Token not found, invalid accessToken"))
-			return
-		}
-		if application == nil {
-			c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist")), token.Application)
-			return
-		}
+		var application *object.Application
+		var err error
+		if accessToken != "" {
+			var claims *object.Claims
+			application, claims, err = object.ParseIdTokenHint(accessToken, clientId)
+			if err != nil {
+				c.ResponseError(fmt.Sprintf(c.T("token:The id_token_hint is invalid: %s"), err.Error()))
+				return
+			}
 
-		if user == "" {
-			user = util.GetId(token.Organization, token.User)
-		}
+			hintUser, err := object.GetIdTokenHintUser(application, accessToken, claims)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+			if hintUser != nil {
+				if user != "" && user != hintUser.GetId() {
+					c.ResponseError(c.T("token:The id_token_hint is not issued to the signed-in user"))
+					return
+				}
+				user = hintUser.GetId()
+			}
 
-		c.ClearUserSession()
-		c.ClearTokenSession()
-		// TODO https://github.com/casdoor/casdoor/pull/1494#discussion_r1095675265
-		owner, username := util.GetOwnerAndNameFromId(user)
+			_, _, _, err = object.ExpireTokenByAccessToken(accessToken)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+		} else {
+			application, err = object.GetApplicationByClientId(clientId)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+			if application == nil {
+				c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist"), clientId))
+				return
+			}
 
-		_, err = object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), c.Ctx.Input.CruSession.SessionID())
-		if err != nil {
-			c.ResponseError(err.Error())
-			return
+			// without the id_token_hint, anyone could make the browser end the session, so the user confirms it first
+			if user != "" && !c.checkLogoutConfirmation() {
+				c.serveLogoutConfirmPage(application, clientId, redirectUri, state)
+				return
+			}
 		}
 
-		util.LogInfo(c.Ctx, "API: [%s] logged out", user)
+		frontchannelLogoutUrls := []string{}
+		if user != "" {
+			c.ClearUserSession()
+			c.ClearTokenSession()
+			frontchannelLogoutUrls, err = c.logoutSessionApplications(user)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			// TODO https://github.com/casdoor/casdoor/pull/1494#discussion_r1095675265
+			owner, username := util.GetOwnerAndNameFromId(user)
+
+			_, err = object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), c.Ctx.Input.CruSession.SessionID())
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			util.LogInfo(c.Ctx, "API: [%s] logged out", user)
+		}
 
 		if redirectUri == "" {
-			c.ResponseOk()
+			c.ResponseJsonData(&Response{Status: "ok", Data3: frontchannelLogoutUrls})
 			return
-		} else {
-			if application.IsRedirectUriValid(redirectUri) {
-				redirectUrl := redirectUri
-				if state != "" {
-					if strings.Contains(redirectUri, "?") {
-						redirectUrl = fmt.Sprintf("%s&state=%s", strings.TrimSuffix(redirectUri, "/"), state)
-					} else {
-						redirectUrl = fmt.Sprintf("%s?state=%s", strings.TrimSuffix(redirectUri, "/"), state)
-					}
-				}
-				c.Ctx.Redirect(http.StatusFound, redirectUrl)
+		}
+
+		if !application.IsPostLogoutRedirectUriValid(redirectUri) {
+			c.ResponseError(fmt.Sprintf(c.T("token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), redirectUri))
+			return
+		}
+
+		redirectUrl := redirectUri
+		if state != "" {
+			if strings.Contains(redirectUri, "?") {
+				redirectUrl = fmt.Sprintf("%s&state=%s", redirectUri, url.QueryEscape(state))
 			} else {
-				c.ResponseError(fmt.Sprintf(c.T("token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), redirectUri))
-				return
+				redirectUrl = fmt.Sprintf("%s?state=%s", redirectUri, url.QueryEscape(state))
 			}
 		}
+		if len(frontchannelLogoutUrls) != 0 {
+			c.serveFrontchannelLogoutPage(frontchannelLogoutUrls, redirectUrl)
+			return
+		}
+		c.Ctx.Redirect(http.StatusFound, redirectUrl)
 	}
 }
 
@@ -478,15 +531,27 @@ func (c *ApiController) GetUserinfo() {
 		return
 	}
 
-	scope, aud := c.GetSessionOidc()
+	scope, aud, claims := c.GetSessionOidc()
 	host := c.Ctx.Request.Host
 
-	userInfo, err := object.GetUserInfo(user, scope, aud, host)
+	userInfo, err := object.GetUserInfo(user, scope, aud, host, claims)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
 	}
 
+	// the client may have registered userinfo_signed_response_alg or userinfo_encrypted_response_alg
+	userInfoJwt, err := object.GetUserInfoJwt(userInfo, aud)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if userInfoJwt != "" {
+		c.Ctx.Output.Header("Content-Type", "application/jwt")
+		c.Ctx.Output.Body([]byte(userInfoJwt))
+		return
+	}
+
 	c.Data["json"] = userInfo
 	c.ServeJSON()
 }
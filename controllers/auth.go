@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/casdoor/casdoor/captcha"
 	"github.com/casdoor/casdoor/conf"
@@ -114,6 +115,42 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		}
 	}
 
+	// the user may have to sign in again or pass MFA for the max_age and acr values of the request
+	authRequest, err := c.getPushedAuthorizationRequest(application)
+	if err != nil {
+		c.ResponseError(err.Error(), nil)
+		return
+	}
+	acrValues, maxAge, claims := c.getAuthParams(authRequest)
+	if form.Type == ResponseTypeCode || form.Type == ResponseTypeToken || form.Type == ResponseTypeIdToken {
+		if !c.checkAuthRequirements(user, acrValues, maxAge, claims) {
+			return
+		}
+	}
+	authContext := &object.AuthContext{AuthTime: c.getAuthTime(), Amr: c.getAuthMethods(), Claims: claims}
+	authorizationDetails := c.getAuthorizationDetails(authRequest)
+
+	// third-party applications need the consent of the user before getting the code or token
+	if form.Type == ResponseTypeCode || form.Type == ResponseTypeToken || form.Type == ResponseTypeIdToken {
+		scope := c.Input().Get("scope")
+		if authRequest != nil {
+			scope = authRequest.Scope
+		}
+
+		required, err := c.isConsentRequired(application, user, scope, c.Input().Get("prompt"), authorizationDetails)
+		if err != nil {
+			c.ResponseError(err.Error(), nil)
+			return
+		}
+
+		if required {
+			// the consent screen needs the user to be signed in
+			c.SetSessionUsername(userId)
+			resp = &Response{Status: "ok", Msg: "", Data: "Consent", Data2: object.GetConsentRequest(application, scope, authorizationDetails)}
+			return
+		}
+	}
+
 	if form.Type == ResponseTypeLogin {
 		c.SetSessionUsername(userId)
 		util.LogInfo(c.Ctx, "API: [%s] signed in", userId)
@@ -127,12 +164,13 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		nonce := c.Input().Get("nonce")
 		challengeMethod := c.Input().Get("code_challenge_method")
 		codeChallenge := c.Input().Get("code_challenge")
+		requestUri := c.Input().Get("requestUri")
 
 		if challengeMethod != "S256" && challengeMethod != "null" && challengeMethod != "" {
 			c.ResponseError(c.T("auth:Challenge method should be S256"))
 			return
 		}
-		code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, c.Ctx.Request.Host, c.GetAcceptLanguage())
+		code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, c.Ctx.Request.Host, c.GetAcceptLanguage(), requestUri, object.GetOidcSid(c.Ctx.Input.CruSession.SessionID()), authContext, authorizationDetails)
 		if err != nil {
 			c.ResponseError(err.Error(), nil)
 			return
@@ -144,24 +182,79 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 			// The prompt page needs the user to be signed in
 			c.SetSessionUsername(userId)
 		}
+		if resp.Status == "ok" {
+			resp.Data3 = c.getSessionState(clientId, redirectUri)
+		}
 	} else if form.Type == ResponseTypeToken || form.Type == ResponseTypeIdToken { // implicit flow
 		if !object.IsGrantTypeValid(form.Type, application.GrantTypes) {
 			resp = &Response{Status: "error", Msg: fmt.Sprintf("error: grant_type: %s is not supported in this application", form.Type), Data: ""}
 		} else {
 			scope := c.Input().Get("scope")
 			nonce := c.Input().Get("nonce")
-			token, _ := object.GetTokenByUser(application, user, scope, nonce, c.Ctx.Request.Host)
+			requestUri := c.Input().Get("requestUri")
+			isPushed := false
+			if requestUri != "" {
+				authRequest, err := object.ConsumeAuthorizationRequest(application.ClientId, requestUri)
+				if err != nil {
+					c.ResponseError(err.Error(), nil)
+					return
+				}
+				if authRequest == nil {
+					c.ResponseError(c.T("token:The request_uri is invalid or has expired"))
+					return
+				}
+				scope, nonce = authRequest.Scope, authRequest.Nonce
+				isPushed = authRequest.IsPushed
+			}
+			// a request object passed by value isn't pushed, only a request_uri issued by the PAR endpoint is
+			if application.RequirePar && !isPushed {
+				c.ResponseError(c.T("token:The application requires pushed authorization requests"))
+				return
+			}
+
+			token, _ := object.GetTokenByUser(application, user, scope, nonce, c.Ctx.Request.Host, object.GetOidcSid(c.Ctx.Input.CruSession.SessionID()), authContext, authorizationDetails)
 			resp = tokenToResponse(token)
+			if resp.Status == "ok" && form.Type == ResponseTypeIdToken {
+				resp.Data, err = object.GetIdTokenResponse(application, token)
+				if err != nil {
+					c.ResponseError(err.Error(), nil)
+					return
+				}
+			}
 
 			resp.Data2 = user.NeedUpdatePassword
+			if resp.Status == "ok" {
+				resp.Data3 = c.getSessionState(application.ClientId, c.Input().Get("redirectUri"))
+			}
 		}
 	} else if form.Type == ResponseTypeSaml { // saml flow
-		res, redirectUrl, method, err := object.GetSamlResponse(application, user, form.SamlRequest, c.Ctx.Request.Host)
+		sid := object.GetOidcSid(c.Ctx.Input.CruSession.SessionID())
+		relayState := form.RelayState
+		var res, redirectUrl, method string
+		if form.IdpInitiated {
+			relayState = object.GetSamlIdpInitiatedRelayState(application, form.RelayState)
+			res, redirectUrl, method, err = object.GetSamlIdpInitiatedResponse(application, user, c.Ctx.Request.Host, sid)
+		} else {
+			res, redirectUrl, method, err = object.GetSamlResponse(application, user, form.SamlRequest, form.SamlQuery, c.Ctx.Request.Host, sid)
+		}
+		if err != nil {
+			c.ResponseError(err.Error(), nil)
+			return
+		}
+		resp = &Response{Status: "ok", Msg: "", Data: res, Data2: map[string]interface{}{"redirectUrl": redirectUrl, "method": method, "relayState": relayState, "needUpdatePassword": user.NeedUpdatePassword}}
+
+		if application.EnableSigninSession || application.HasPromptPage() {
+			// The prompt page needs the user to be signed in
+			c.SetSessionUsername(userId)
+		}
+	} else if form.Type == ResponseTypeWsFed { // WS-Federation passive requestor flow
+		sid := object.GetOidcSid(c.Ctx.Input.CruSession.SessionID())
+		res, redirectUrl, err := object.GetWsFedResponse(application, user, form.WsFedRealm, form.WsFedReply, c.Ctx.Request.Host, sid)
 		if err != nil {
 			c.ResponseError(err.Error(), nil)
 			return
 		}
-		resp = &Response{Status: "ok", Msg: "", Data: res, Data2: map[string]interface{}{"redirectUrl": redirectUrl, "method": method, "needUpdatePassword": user.NeedUpdatePassword}}
+		resp = &Response{Status: "ok", Msg: "", Data: res, Data2: map[string]interface{}{"redirectUrl": redirectUrl, "wctx": form.WsFedContext, "needUpdatePassword": user.NeedUpdatePassword}}
 
 		if application.EnableSigninSession || application.HasPromptPage() {
 			// The prompt page needs the user to be signed in
@@ -170,9 +263,16 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 	} else if form.Type == ResponseTypeCas {
 		// not oauth but CAS SSO protocol
 		service := c.Input().Get("service")
+		// the user signed in by the existing session hasn't presented the credentials, which renew asks for
+		isSingleSignOn := form.Username == "" && form.Provider == "" && c.GetSessionUsername() != ""
+		if isSingleSignOn && isCasRenew(c.Input().Get("renew")) {
+			c.ResponseError(c.T("auth:The authentication has expired, please sign in again"))
+			return
+		}
+
 		resp = wrapErrorResponse(nil)
 		if service != "" {
-			st, err := object.GenerateCasToken(userId, service)
+			st, err := object.GenerateCasToken(userId, service, !isSingleSignOn)
 			if err != nil {
 				resp = wrapErrorResponse(err)
 			} else {
@@ -209,6 +309,77 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 	return resp
 }
 
+// getPushedAuthorizationRequest returns the pushed authorization request referenced by the requestUri of the login,
+// nil is returned if there is none
+func (c *ApiController) getPushedAuthorizationRequest(application *object.Application) (*object.AuthorizationRequest, error) {
+	requestUri := c.Input().Get("requestUri")
+	if requestUri == "" {
+		return nil, nil
+	}
+	return object.GetAuthorizationRequest(application.ClientId, requestUri)
+}
+
+// getAuthParams returns the acr_values, max_age and claims parameters of the authorization request, the ones of a
+// pushed request take precedence over the query
+func (c *ApiController) getAuthParams(authRequest *object.AuthorizationRequest) (string, string, string) {
+	if authRequest != nil {
+		return authRequest.AcrValues, authRequest.MaxAge, authRequest.Claims
+	}
+	return c.Input().Get("acr_values"), c.Input().Get("max_age"), c.Input().Get("claims")
+}
+
+// getAuthorizationDetails returns the authorization_details parameter (RFC 9396) of the authorization request, the
+// one of a pushed request takes precedence over the query
+func (c *ApiController) getAuthorizationDetails(authRequest *object.AuthorizationRequest) string {
+	if authRequest != nil {
+		return authRequest.AuthorizationDetails
+	}
+	return c.Input().Get("authorization_details")
+}
+
+// checkAuthRequirements checks the authentication of the user against the max_age and the requested acr values, the
+// user has to sign in again when the authentication is older than max_age, and pass MFA when a multi-factor
+// authentication is requested. It responds and returns false when the authentication doesn't meet the requirements
+func (c *ApiController) checkAuthRequirements(user *object.User, acrValues string, maxAge string, claims string) bool {
+	claimsRequest, err := object.ParseClaimsRequest(claims)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return false
+	}
+
+	if maxAge != "" {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil || seconds < 0 {
+			c.ResponseError(fmt.Sprintf(c.T("auth:max_age: %s should be a non-negative integer"), maxAge))
+			return false
+		}
+
+		if time.Now().Unix()-c.getAuthTime() > seconds {
+			c.ResponseError(c.T("auth:The authentication has expired, please sign in again"))
+			return false
+		}
+	}
+
+	acrs, isEssential := claimsRequest.GetAcrValues(acrValues)
+	if object.IsAcrSatisfied(object.GetAcr(c.getAuthMethods()), acrs) {
+		return true
+	}
+
+	if util.InSlice(acrs, object.AcrMultiFactor) && user.IsMfaEnabled() {
+		// step up: the user passes MFA to complete the authentication
+		c.setMfaUserSession(user.GetId())
+		c.ResponseOk(object.NextMfa, user.GetPreferredMfaProps(true))
+		return false
+	}
+
+	// the requested acr values are voluntary unless the acr claim is essential
+	if isEssential {
+		c.ResponseError(fmt.Sprintf(c.T("auth:%s: the requested authentication context class can't be satisfied"), object.UnmetAuthenticationRequirements))
+		return false
+	}
+	return true
+}
+
 // GetApplicationLogin ...
 // @Title GetApplicationLogin
 // @Tag Login API
@@ -218,6 +389,8 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 // @Param   redirectUri    query    string  true        "redirect uri"
 // @Param   scope    query    string  true        "scope"
 // @Param   state    query    string  true        "state"
+// @Param   requestUri    query    string  false        "request_uri of a pushed authorization request"
+// @Param   request    query    string  false        "signed request object"
 // @Success 200 {object} controllers.Response The Response object
 // @router /get-app-login [get]
 func (c *ApiController) GetApplicationLogin() {
@@ -228,16 +401,41 @@ func (c *ApiController) GetApplicationLogin() {
 	state := c.Input().Get("state")
 	id := c.Input().Get("id")
 	loginType := c.Input().Get("type")
+	requestUri := c.Input().Get("requestUri")
+	request := c.Input().Get("request")
 
 	var application *object.Application
+	var authRequest *object.AuthorizationRequest
 	var msg string
 	var err error
 	if loginType == "code" {
-		msg, application, err = object.CheckOAuthLogin(clientId, responseType, redirectUri, scope, state, c.GetAcceptLanguage())
+		// a request object passed by value is verified once here, the login page then continues with its request_uri
+		if request != "" && requestUri == "" {
+			requestUri, authRequest, err = object.PushRequestObject(clientId, request, c.Ctx.Request.Host)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+		} else if requestUri != "" {
+			authRequest, err = object.GetAuthorizationRequest(clientId, requestUri)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+		}
+
+		msg, application, err = object.CheckOAuthLogin(clientId, responseType, redirectUri, scope, state, c.GetAcceptLanguage(), requestUri)
 		if err != nil {
 			c.ResponseError(err.Error())
 			return
 		}
+
+		if msg == "" && application != nil && authRequest == nil {
+			_, err = object.ParseAuthorizationDetails(application, c.Input().Get("authorization_details"))
+			if err != nil {
+				msg = err.Error()
+			}
+		}
 	} else if loginType == "cas" {
 		application, err = object.GetApplication(id)
 		if err != nil {
@@ -259,6 +457,8 @@ func (c *ApiController) GetApplicationLogin() {
 	application = object.GetMaskedApplication(application, "")
 	if msg != "" {
 		c.ResponseError(msg, application)
+	} else if authRequest != nil {
+		c.ResponseOk(application, map[string]interface{}{"requestUri": requestUri, "authRequest": authRequest})
 	} else {
 		c.ResponseOk(application)
 	}
@@ -329,6 +529,7 @@ func (c *ApiController) Login() {
 		}
 
 		var user *object.User
+		var amr string
 		if authForm.SigninMethod == "Face ID" {
 			if user, err = object.GetUserByFields(authForm.Organization, authForm.Username); err != nil {
 				c.ResponseError(err.Error(), nil)
@@ -359,6 +560,7 @@ func (c *ApiController) Login() {
 				c.ResponseError(err.Error(), nil)
 				return
 			}
+			amr = object.AmrFace
 
 		} else if authForm.Password == "" {
 			if user, err = object.GetUserByFields(authForm.Organization, authForm.Username); err != nil {
@@ -392,7 +594,9 @@ func (c *ApiController) Login() {
 			}
 
 			var checkDest string
+			amr = object.AmrOtp
 			if verificationCodeType == object.VerifyTypePhone {
+				amr = object.AmrSms
 				authForm.CountryCode = user.GetCountryCode(authForm.CountryCode)
 				var ok bool
 				if checkDest, ok = util.GetE164Number(authForm.Username, authForm.CountryCode); !ok {
@@ -471,6 +675,7 @@ func (c *ApiController) Login() {
 				isPasswordWithLdapEnabled = false
 			}
 			user, err = object.CheckUserPassword(authForm.Organization, authForm.Username, password, c.GetAcceptLanguage(), enableCaptcha, isSigninViaLdap, isPasswordWithLdapEnabled)
+			amr = object.AmrPassword
 		}
 
 		if err != nil {
@@ -495,6 +700,8 @@ func (c *ApiController) Login() {
 				c.ResponseError(err.Error())
 			}
 
+			c.setAuthMethods(amr)
+
 			if object.IsNeedPromptMfa(organization, user) {
 				// The prompt page needs the user to be signed in
 				c.SetSessionUsername(user.GetId())
@@ -629,6 +836,7 @@ func (c *ApiController) Login() {
 					c.ResponseError(err.Error())
 					return
 				}
+				c.setAuthMethods(object.AmrFederated)
 				resp = c.HandleLoggedIn(application, user, &authForm)
 
 				c.Ctx.Input.SetParam("recordUserId", user.GetId())
@@ -767,6 +975,7 @@ func (c *ApiController) Login() {
 					return
 				}
 
+				c.setAuthMethods(object.AmrFederated)
 				resp = c.HandleLoggedIn(application, user, &authForm)
 
 				c.Ctx.Input.SetParam("recordUserId", user.GetId())
@@ -846,12 +1055,14 @@ func (c *ApiController) Login() {
 				c.ResponseError(err.Error())
 				return
 			}
+			c.addMfaAuthMethod(object.GetMfaAmr(authForm.MfaType))
 		} else if authForm.RecoveryCode != "" {
 			err = object.MfaRecover(user, authForm.RecoveryCode)
 			if err != nil {
 				c.ResponseError(err.Error())
 				return
 			}
+			c.addMfaAuthMethod(object.AmrOtp)
 		} else {
 			c.ResponseError("missing passcode or recovery code")
 			return
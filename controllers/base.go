@@ -154,17 +154,18 @@ func (c *ApiController) ClearTokenSession() {
 	c.SetSessionToken("")
 }
 
-func (c *ApiController) GetSessionOidc() (string, string) {
+func (c *ApiController) GetSessionOidc() (string, string, string) {
 	sessionData := c.GetSessionData()
 	if sessionData != nil &&
 		sessionData.ExpireTime != 0 &&
 		sessionData.ExpireTime < time.Now().Unix() {
 		c.ClearUserSession()
-		return "", ""
+		return "", "", ""
 	}
 	scopeValue := c.GetSession("scope")
 	audValue := c.GetSession("aud")
-	var scope, aud string
+	claimsValue := c.GetSession("claims")
+	var scope, aud, claims string
 	var ok bool
 	if scope, ok = scopeValue.(string); !ok {
 		scope = ""
@@ -172,11 +173,18 @@ func (c *ApiController) GetSessionOidc() (string, string) {
 	if aud, ok = audValue.(string); !ok {
 		aud = ""
 	}
-	return scope, aud
+	if claims, ok = claimsValue.(string); !ok {
+		claims = ""
+	}
+	return scope, aud, claims
 }
 
 // SetSessionUsername ...
 func (c *ApiController) SetSessionUsername(user string) {
+	// the browser state of OIDC session management changes when the user signs in or out
+	if username, _ := c.GetSession("username").(string); username != user {
+		c.resetOpbs()
+	}
 	c.SetSession("username", user)
 }
 
@@ -223,6 +231,34 @@ func (c *ApiController) getMfaUserSession() string {
 	return userId.(string)
 }
 
+// setAuthMethods records the methods of the authentication the user just passed, they are reported to the
+// applications in the amr claim
+func (c *ApiController) setAuthMethods(amr ...string) {
+	c.SetSession("amr", strings.Join(amr, " "))
+	c.SetSession("authTime", time.Now().Unix())
+}
+
+// addMfaAuthMethod adds the method of the second factor to the ones of the first factor
+func (c *ApiController) addMfaAuthMethod(method string) {
+	amr := c.getAuthMethods()
+	for _, m := range []string{method, object.AmrMfa} {
+		if !util.InSlice(amr, m) {
+			amr = append(amr, m)
+		}
+	}
+	c.setAuthMethods(amr...)
+}
+
+func (c *ApiController) getAuthMethods() []string {
+	amr, _ := c.GetSession("amr").(string)
+	return strings.Fields(amr)
+}
+
+func (c *ApiController) getAuthTime() int64 {
+	authTime, _ := c.GetSession("authTime").(int64)
+	return authTime
+}
+
 func (c *ApiController) setExpireForSession() {
 	timestamp := time.Now().Unix()
 	timestamp += 3600 * 24
@@ -40,6 +40,11 @@ func queryUnescape(service string) string {
 	return s
 }
 
+// isCasRenew returns whether the renew parameter is set, the tickets issued from single sign-on sessions are rejected then
+func isCasRenew(renew string) bool {
+	return renew != "" && renew != "false"
+}
+
 func (c *RootController) CasValidate() {
 	ticket := c.Input().Get("ticket")
 	service := c.Input().Get("service")
@@ -48,7 +53,7 @@ func (c *RootController) CasValidate() {
 		c.Ctx.Output.Body([]byte("no\n"))
 		return
 	}
-	if ok, response, issuedService, _ := object.GetCasTokenByTicket(ticket); ok {
+	if ok, response, issuedService, _, err := object.GetCasTokenByTicket(ticket, isCasRenew(c.Input().Get("renew"))); err == nil && ok {
 		// check whether service is the one for which we previously issued token
 		if issuedService == service {
 			c.Ctx.Output.Body([]byte(fmt.Sprintf("yes\n%s\n", response.User)))
@@ -64,6 +69,7 @@ func (c *RootController) CasServiceValidate() {
 	format := c.Input().Get("format")
 	if !strings.HasPrefix(ticket, "ST") {
 		c.sendCasAuthenticationResponseErr(InvalidTicket, fmt.Sprintf("Ticket %s not recognized", ticket), format)
+		return
 	}
 	c.CasP3ProxyValidate()
 }
@@ -79,6 +85,7 @@ func (c *RootController) CasP3ServiceValidate() {
 	format := c.Input().Get("format")
 	if !strings.HasPrefix(ticket, "ST") {
 		c.sendCasAuthenticationResponseErr(InvalidTicket, fmt.Sprintf("Ticket %s not recognized", ticket), format)
+		return
 	}
 	c.CasP3ProxyValidate()
 }
@@ -98,7 +105,11 @@ func (c *RootController) CasP3ProxyValidate() {
 		c.sendCasAuthenticationResponseErr(InvalidRequest, "service and ticket must exist", format)
 		return
 	}
-	ok, response, issuedService, userId := object.GetCasTokenByTicket(ticket)
+	ok, response, issuedService, userId, err := object.GetCasTokenByTicket(ticket, isCasRenew(c.Input().Get("renew")))
+	if err != nil {
+		c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+		return
+	}
 	// find the token
 	if ok {
 		// check whether service is the one for which we previously issued token
@@ -117,7 +128,11 @@ func (c *RootController) CasP3ProxyValidate() {
 
 	if pgtUrl != "" && serviceResponse.Failure == nil {
 		// that means we are in proxy web flow
-		pgt := object.StoreCasTokenForPgt(serviceResponse.Success, service, userId)
+		pgt, err := object.StoreCasTokenForPgt(serviceResponse.Success, service, userId)
+		if err != nil {
+			c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+			return
+		}
 		pgtiou := serviceResponse.Success.ProxyGrantingTicket
 		// todo: check whether it is https
 		pgtUrlObj, err := url.Parse(pgtUrl)
@@ -169,7 +184,11 @@ func (c *RootController) CasProxy() {
 		return
 	}
 
-	ok, authenticationSuccess, issuedService, userId := object.GetCasTokenByPgt(pgt)
+	ok, authenticationSuccess, issuedService, userId, err := object.GetCasTokenByPgt(pgt)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
 	if !ok {
 		c.sendCasProxyResponseErr(UnauthorizedService, "service not authorized", format)
 		return
@@ -180,7 +199,11 @@ func (c *RootController) CasProxy() {
 		newAuthenticationSuccess.Proxies = &object.CasProxies{}
 	}
 	newAuthenticationSuccess.Proxies.Proxies = append(newAuthenticationSuccess.Proxies.Proxies, issuedService)
-	proxyTicket := object.StoreCasTokenForProxyTicket(&newAuthenticationSuccess, targetService, userId)
+	proxyTicket, err := object.StoreCasTokenForProxyTicket(&newAuthenticationSuccess, targetService, userId)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
 
 	serviceResponse := object.CasServiceResponse{
 		Xmlns: "http://www.yale.edu/tp/cas",
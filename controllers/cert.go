@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -183,3 +184,45 @@ func (c *ApiController) DeleteCert() {
 	c.Data["json"] = wrapActionResponse(object.DeleteCert(&cert))
 	c.ServeJSON()
 }
+
+// RotateCert
+// @Title RotateCert
+// @Tag Cert API
+// @Description rotate the signing key of cert
+// @Param   id     query    string  true        "The id ( owner/name ) of the cert"
+// @Success 200 {object} controllers.Response The Response object
+// @router /rotate-cert [post]
+func (c *ApiController) RotateCert() {
+	id := c.Input().Get("id")
+	cert, err := object.GetCert(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if cert == nil {
+		c.ResponseError(fmt.Sprintf(c.T("cert:The cert: %s does not exist"), id))
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.RotateCert(cert))
+	c.ServeJSON()
+}
+
+// GetCertRotations
+// @Title GetCertRotations
+// @Tag Cert API
+// @Description get the rotations of cert
+// @Param   id     query    string  true        "The id ( owner/name ) of the cert"
+// @Success 200 {array} object.CertRotation The Response object
+// @router /get-cert-rotations [get]
+func (c *ApiController) GetCertRotations() {
+	id := c.Input().Get("id")
+	owner, name := util.GetOwnerAndNameFromId(id)
+	rotations, err := object.GetCertRotations(owner, name)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(rotations)
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/casdoor/casdoor/object"
+)
+
+func (c *ApiController) getBearerToken() string {
+	authorization := c.Ctx.Request.Header.Get("Authorization")
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+func (c *ApiController) responseClientRegistrationError(status int, tokenError *object.TokenError) {
+	if status == 401 {
+		c.Ctx.Output.Header("WWW-Authenticate", "Bearer error=\"invalid_token\"")
+	}
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = tokenError
+	c.ServeJSON()
+}
+
+// getRegisteredClient authenticates the registration access token of RFC 7592 section 2
+func (c *ApiController) getRegisteredClient() (*object.Application, string, bool) {
+	registrationAccessToken := c.getBearerToken()
+	application, err := object.GetRegisteredClient(c.Ctx.Input.Param(":clientId"), registrationAccessToken)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, "", false
+	}
+
+	// an unknown client is reported the same way as an invalid token, per RFC 7592 section 2.1
+	if application == nil {
+		c.responseClientRegistrationError(401, &object.TokenError{
+			Error:            object.InvalidToken,
+			ErrorDescription: "the registration access token is invalid",
+		})
+		return nil, "", false
+	}
+	return application, registrationAccessToken, true
+}
+
+// RegisterClient
+// @Title RegisterClient
+// @Tag Client Registration API
+// @Description register an OAuth client dynamically (RFC 7591)
+// @Param   body    body   object.ClientMetadata  true        "The client metadata"
+// @Success 201 {object} object.ClientInformationResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/register [post]
+func (c *ApiController) RegisterClient() {
+	var metadata object.ClientMetadata
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &metadata)
+	if err != nil {
+		c.responseClientRegistrationError(400, &object.TokenError{
+			Error:            object.InvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	count, err := object.GetApplicationCount("", "", "")
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if err = checkQuotaForApplication(int(count)); err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	resp, tokenError, err := object.RegisterClient(&metadata, c.getBearerToken(), c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		status := 400
+		if tokenError.Error == object.InvalidToken {
+			status = 401
+		}
+		c.responseClientRegistrationError(status, tokenError)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(201)
+	c.Data["json"] = resp
+	c.ServeJSON()
+}
+
+// GetRegisteredClient
+// @Title GetRegisteredClient
+// @Tag Client Registration API
+// @Description read the registration of a dynamically registered client (RFC 7592)
+// @Param   clientId    path   string  true        "The client id"
+// @Success 200 {object} object.ClientInformationResponse The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/register/:clientId [get]
+func (c *ApiController) GetRegisteredClient() {
+	application, registrationAccessToken, ok := c.getRegisteredClient()
+	if !ok {
+		return
+	}
+
+	resp, err := object.GetClientInformation(application, registrationAccessToken, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = resp
+	c.ServeJSON()
+}
+
+// UpdateRegisteredClient
+// @Title UpdateRegisteredClient
+// @Tag Client Registration API
+// @Description update the registration of a dynamically registered client (RFC 7592)
+// @Param   clientId    path   string  true        "The client id"
+// @Param   body    body   object.ClientMetadata  true        "The client metadata, including client_id"
+// @Success 200 {object} object.ClientInformationResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/register/:clientId [put]
+func (c *ApiController) UpdateRegisteredClient() {
+	application, registrationAccessToken, ok := c.getRegisteredClient()
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ClientId     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		object.ClientMetadata
+	}
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &req)
+	if err != nil {
+		c.responseClientRegistrationError(400, &object.TokenError{
+			Error:            object.InvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	resp, tokenError, err := object.UpdateRegisteredClient(application, req.ClientId, req.ClientSecret, &req.ClientMetadata, registrationAccessToken, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.responseClientRegistrationError(400, tokenError)
+		return
+	}
+
+	c.Data["json"] = resp
+	c.ServeJSON()
+}
+
+// DeleteRegisteredClient
+// @Title DeleteRegisteredClient
+// @Tag Client Registration API
+// @Description delete a dynamically registered client (RFC 7592)
+// @Param   clientId    path   string  true        "The client id"
+// @Success 204 {string} string "No Content"
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/register/:clientId [delete]
+func (c *ApiController) DeleteRegisteredClient() {
+	application, _, ok := c.getRegisteredClient()
+	if !ok {
+		return
+	}
+
+	_, err := object.DeleteRegisteredClient(application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.SetStatus(204)
+	c.Ctx.Output.Body([]byte{})
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetUserConsents
+// @Title GetUserConsents
+// @Tag Consent API
+// @Description get the consents that the current user has granted to applications
+// @Success 200 {array} object.Consent The Response object
+// @router /get-user-consents [get]
+func (c *ApiController) GetUserConsents() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	consents, err := object.GetConsentsByUser(user.Owner, user.Name)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(consents)
+}
+
+// GrantConsent
+// @Title GrantConsent
+// @Tag Consent API
+// @Description grant the scope to a third-party application for the current user
+// @Param   application     query    string  true        "The name of the application"
+// @Param   scope     query    string  false        "The scope granted to the application"
+// @Success 200 {object} controllers.Response The Response object
+// @router /grant-consent [post]
+func (c *ApiController) GrantConsent() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	applicationName := c.Input().Get("application")
+	scope := c.Input().Get("scope")
+
+	application, err := object.GetApplication(util.GetId("admin", applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist"), applicationName))
+		return
+	}
+
+	_, err = object.GrantConsent(user, application, scope)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	// lets the authorization request with "prompt=consent" that is being answered go through once
+	c.SetSession("consentApplication", application.Name)
+
+	c.ResponseOk()
+}
+
+// RevokeConsent
+// @Title RevokeConsent
+// @Tag Consent API
+// @Description revoke a consent of the current user, the tokens that the application holds for the user are expired too
+// @Param   id     query    string  true        "The id ( owner/name ) of the consent"
+// @Success 200 {object} controllers.Response The Response object
+// @router /revoke-consent [post]
+func (c *ApiController) RevokeConsent() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	id := c.Input().Get("id")
+	consent, err := object.GetConsent(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if consent == nil || consent.Owner != user.Owner || consent.User != user.Name {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.RevokeConsent(consent))
+	c.ServeJSON()
+}
+
+// isConsentRequired checks the consent of the user, a "prompt=consent" request that the user has just answered is let through
+func (c *ApiController) isConsentRequired(application *object.Application, user *object.User, scope string, prompt string, authorizationDetails string) (bool, error) {
+	if c.GetSession("consentApplication") == application.Name {
+		c.DelSession("consentApplication")
+		prompt = ""
+		// the user has just approved the authorization details of this request
+		authorizationDetails = ""
+	}
+
+	return object.IsConsentRequired(application, user, scope, prompt, authorizationDetails)
+}
@@ -24,7 +24,13 @@ import "github.com/casdoor/casdoor/object"
 // @router /.well-known/openid-configuration [get]
 func (c *RootController) GetOidcDiscovery() {
 	host := c.Ctx.Request.Host
-	c.Data["json"] = object.GetOidcDiscovery(host)
+	oidcDiscovery, err := object.GetOidcDiscovery(host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = oidcDiscovery
 	c.ServeJSON()
 }
 
@@ -0,0 +1,168 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"html/template"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// frontchannelLogoutTemplate loads the front-channel logout URLs in hidden iframes, then goes on to the redirect URL,
+// or posts the form if there is one
+var frontchannelLogoutTemplate = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Logging out</title></head>
+<body>
+{{range .Urls}}<iframe src="{{.}}" style="display:none"></iframe>
+{{end}}{{if .Form}}<form method="post" action="{{.Form.Action}}">{{range $name, $value := .Form.Values}}<input type="hidden" name="{{$name}}" value="{{$value}}">{{end}}</form>
+{{end}}<script>
+var redirectUrl = {{.RedirectUrl}};
+var count = {{len .Urls}};
+var finished = false;
+var finish = function () {
+  if (finished) {
+    return;
+  }
+  finished = true;
+  if (document.forms.length > 0) {
+    document.forms[0].submit();
+  } else {
+    window.location.replace(redirectUrl);
+  }
+};
+var done = function () {
+  count--;
+  if (count <= 0) {
+    finish();
+  }
+};
+Array.prototype.forEach.call(document.getElementsByTagName("iframe"), function (iframe) {
+  iframe.onload = done;
+  iframe.onerror = done;
+});
+if (count <= 0) {
+  finish();
+}
+setTimeout(finish, 5000);
+</script>
+</body>
+</html>
+`))
+
+// logoutConfirmTemplate asks the user to confirm the logout requested by a client without a valid id_token_hint,
+// per OpenID Connect RP-Initiated Logout 1.0 section 2, the form carries a token kept in the session against CSRF
+var logoutConfirmTemplate = template.Must(template.New("logout-confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Log out</title></head>
+<body>
+<p>Do you want to log out of {{.Application}}?</p>
+<form method="post" action="{{.Form.Action}}">{{range $name, $value := .Form.Values}}<input type="hidden" name="{{$name}}" value="{{$value}}">{{end}}<button type="submit">Log out</button></form>
+</body>
+</html>
+`))
+
+// checkLogoutConfirmation reports whether the user has confirmed the logout by posting the confirmation form
+func (c *ApiController) checkLogoutConfirmation() bool {
+	confirmToken := c.Input().Get("logout_confirm_token")
+	sessionToken, _ := c.GetSession("logoutConfirmToken").(string)
+	if c.Ctx.Request.Method != "POST" || confirmToken == "" || sessionToken == "" {
+		return false
+	}
+
+	c.DelSession("logoutConfirmToken")
+	return subtle.ConstantTimeCompare([]byte(confirmToken), []byte(sessionToken)) == 1
+}
+
+// serveLogoutConfirmPage renders the confirmation page that posts the logout request back with the confirmation token
+func (c *ApiController) serveLogoutConfirmPage(application *object.Application, clientId string, redirectUri string, state string) {
+	confirmToken := util.GenerateClientSecret()
+	c.SetSession("logoutConfirmToken", confirmToken)
+
+	applicationName := application.DisplayName
+	if applicationName == "" {
+		applicationName = application.Name
+	}
+
+	var buf bytes.Buffer
+	err := logoutConfirmTemplate.Execute(&buf, map[string]interface{}{
+		"Application": applicationName,
+		"Form": &logoutForm{
+			Action: "/api/logout",
+			Values: map[string]string{
+				"client_id":                clientId,
+				"post_logout_redirect_uri": redirectUri,
+				"state":                    state,
+				"logout_confirm_token":     confirmToken,
+			},
+		},
+	})
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	c.Ctx.Output.Body(buf.Bytes())
+}
+
+// logoutSessionApplications ends the current browser session of the user in every application it has signed in to,
+// and returns the front-channel logout URLs to be loaded by the browser
+func (c *ApiController) logoutSessionApplications(userId string) ([]string, error) {
+	user, err := object.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return []string{}, nil
+	}
+
+	return object.LogoutSessionApplications(user, c.Ctx.Input.CruSession.SessionID(), c.Ctx.Request.Host, "")
+}
+
+// logoutForm is the form posted by the logout page after the front-channel logout
+type logoutForm struct {
+	Action string
+	Values map[string]string
+}
+
+// serveFrontchannelLogoutPage renders the page that performs the front-channel logout before redirecting
+func (c *ApiController) serveFrontchannelLogoutPage(frontchannelLogoutUrls []string, redirectUrl string) {
+	c.serveLogoutPage(frontchannelLogoutUrls, redirectUrl, nil)
+}
+
+// serveFrontchannelLogoutForm renders the page that performs the front-channel logout before posting the form
+func (c *ApiController) serveFrontchannelLogoutForm(frontchannelLogoutUrls []string, form *logoutForm) {
+	c.serveLogoutPage(frontchannelLogoutUrls, "", form)
+}
+
+func (c *ApiController) serveLogoutPage(frontchannelLogoutUrls []string, redirectUrl string, form *logoutForm) {
+	var buf bytes.Buffer
+	err := frontchannelLogoutTemplate.Execute(&buf, map[string]interface{}{
+		"Urls":        frontchannelLogoutUrls,
+		"RedirectUrl": redirectUrl,
+		"Form":        form,
+	})
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	c.Ctx.Output.Body(buf.Bytes())
+}
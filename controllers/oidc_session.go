@@ -0,0 +1,125 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// checkSessionTemplate is the OP iframe of OIDC Session Management 1.0 section 3.3, it recomputes the session_state
+// posted by the RP iframe from the browser state cookie and answers "unchanged", "changed" or "error"
+var checkSessionTemplate = template.Must(template.New("check-session").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Check session</title></head>
+<body>
+<script>
+var cookieName = {{.CookieName}};
+var getCookie = function (name) {
+  var cookies = document.cookie ? document.cookie.split("; ") : [];
+  for (var i = 0; i < cookies.length; i++) {
+    var index = cookies[i].indexOf("=");
+    if (cookies[i].substring(0, index) === name) {
+      return decodeURIComponent(cookies[i].substring(index + 1));
+    }
+  }
+  return "";
+};
+var sha256Hex = function (text) {
+  return window.crypto.subtle.digest("SHA-256", new TextEncoder().encode(text)).then(function (buffer) {
+    return Array.prototype.map.call(new Uint8Array(buffer), function (b) {
+      return ("0" + b.toString(16)).slice(-2);
+    }).join("");
+  });
+};
+window.addEventListener("message", function (e) {
+  if (e.source === null || typeof e.data !== "string") {
+    return;
+  }
+
+  var parts = e.data.split(" ");
+  var index = parts.length === 2 ? parts[1].lastIndexOf(".") : -1;
+  if (index === -1) {
+    e.source.postMessage("error", e.origin);
+    return;
+  }
+
+  var clientId = parts[0];
+  var sessionState = parts[1];
+  var salt = sessionState.substring(index + 1);
+  var opbs = getCookie(cookieName);
+  if (opbs === "") {
+    e.source.postMessage("changed", e.origin);
+    return;
+  }
+
+  sha256Hex([clientId, e.origin, opbs, salt].join(" ")).then(function (hash) {
+    e.source.postMessage(hash + "." + salt === sessionState ? "unchanged" : "changed", e.origin);
+  }, function () {
+    e.source.postMessage("error", e.origin);
+  });
+}, false);
+</script>
+</body>
+</html>
+`))
+
+// CheckSessionIframe
+// @Title CheckSessionIframe
+// @Tag OAuth API
+// @Description the check_session_iframe of OIDC Session Management, to be embedded by the RPs
+// @Success 200 {string} string The HTML page
+// @router /login/oauth/check-session [get]
+func (c *ApiController) CheckSessionIframe() {
+	var buf bytes.Buffer
+	err := checkSessionTemplate.Execute(&buf, map[string]interface{}{
+		"CookieName": object.OpbsCookieName,
+	})
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	c.Ctx.Output.Body(buf.Bytes())
+}
+
+// resetOpbs changes the OP browser state, so that the RPs checking the session see it as changed
+func (c *ApiController) resetOpbs() string {
+	opbs := util.GenerateId()
+	c.Ctx.SetCookie(object.OpbsCookieName, opbs, 0, "/")
+	c.Ctx.Input.SetData(object.OpbsCookieName, opbs)
+	return opbs
+}
+
+func (c *ApiController) getOpbs() string {
+	if opbs, ok := c.Ctx.Input.GetData(object.OpbsCookieName).(string); ok {
+		return opbs
+	}
+
+	opbs := c.Ctx.GetCookie(object.OpbsCookieName)
+	if opbs == "" {
+		opbs = c.resetOpbs()
+	}
+	return opbs
+}
+
+// getSessionState returns the session_state of the authorization response to the redirect URI
+func (c *ApiController) getSessionState(clientId string, redirectUri string) string {
+	return object.GetSessionState(clientId, redirectUri, c.getOpbs())
+}
@@ -19,6 +19,7 @@ import (
 	"net/http"
 
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 )
 
 func (c *ApiController) GetSamlMeta() {
@@ -64,3 +65,181 @@ func (c *ApiController) HandleSamlRedirect() {
 
 	c.Redirect(targetURL, http.StatusSeeOther)
 }
+
+// HandleSamlLogout
+// @Title HandleSamlLogout
+// @Tag Login API
+// @Description handle the SAML LogoutRequest of a service provider with the HTTP-Redirect or HTTP-POST binding, the user is logged out of the other applications of the session and a signed LogoutResponse is sent back
+// @Param   owner     path    string  true        "The owner of the application"
+// @Param   application     path    string  true        "The name of the application"
+// @Param   SAMLRequest     query    string  true        "The LogoutRequest"
+// @Param   RelayState     query    string  false        "The RelayState"
+// @router /saml/slo/:owner/:application [get]
+func (c *ApiController) HandleSamlLogout() {
+	owner := c.Ctx.Input.Param(":owner")
+	applicationName := c.Ctx.Input.Param(":application")
+	samlRequest := c.Input().Get("SAMLRequest")
+	relayState := c.Input().Get("RelayState")
+	host := c.Ctx.Request.Host
+	isPostBinding := c.Ctx.Request.Method == http.MethodPost
+
+	application, err := object.GetApplication(util.GetId(owner, applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), applicationName))
+		return
+	}
+
+	if samlRequest == "" {
+		c.ResponseError(c.T("general:Missing parameter") + ": SAMLRequest")
+		return
+	}
+
+	logoutRequest, err := object.ParseSamlLogoutRequest(application, samlRequest, c.Ctx.Request.URL.RawQuery, isPostBinding, host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	frontchannelLogoutUrls := []string{}
+	if userId := c.GetSessionUsername(); userId != "" {
+		user, err := object.GetUser(userId)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		sessionId := c.Ctx.Input.CruSession.SessionID()
+		if user != nil && logoutRequest.IsForSession(application, user, sessionId) {
+			c.ClearUserSession()
+			c.ClearTokenSession()
+			frontchannelLogoutUrls, err = object.LogoutSessionApplications(user, sessionId, host, application.Name)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			_, err = object.DeleteSessionId(util.GetSessionId(user.Owner, user.Name, object.CasdoorApplication), sessionId)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			util.LogInfo(c.Ctx, "API: [%s] logged out by the SAML application: [%s]", userId, application.GetId())
+		}
+	}
+
+	logoutResponse, err := object.GetSamlLogoutResponse(application, logoutRequest, host, relayState, object.SamlStatusSuccess, isPostBinding)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if isPostBinding {
+		values := map[string]string{"SAMLResponse": logoutResponse}
+		if relayState != "" {
+			values["RelayState"] = relayState
+		}
+		c.serveFrontchannelLogoutForm(frontchannelLogoutUrls, &logoutForm{Action: application.SamlLogoutUrl, Values: values})
+		return
+	}
+
+	if len(frontchannelLogoutUrls) != 0 {
+		c.serveFrontchannelLogoutPage(frontchannelLogoutUrls, logoutResponse)
+		return
+	}
+	c.Ctx.Redirect(http.StatusFound, logoutResponse)
+}
+
+// ImportSamlMetadata
+// @Title ImportSamlMetadata
+// @Tag Application API
+// @Description fill the SAML settings of the application from the metadata of the SP, which is the request body or fetched from the URL. The application is returned without being saved
+// @Param   id     query    string  true        "The id ( owner/name ) of the application"
+// @Param   metadataUrl     query    string  false        "The URL of the SP metadata"
+// @Param   body    body   string  false        "The XML of the SP metadata"
+// @Success 200 {object} object.Application The Response object
+// @router /import-saml-metadata [post]
+func (c *ApiController) ImportSamlMetadata() {
+	id := c.Input().Get("id")
+	metadataUrl := c.Input().Get("metadataUrl")
+
+	application, err := object.GetApplication(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), id))
+		return
+	}
+
+	err = object.ImportSamlSpMetadata(application, metadataUrl, string(c.Ctx.Input.RequestBody))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(object.GetMaskedApplication(application, c.GetSessionUsername()))
+}
+
+// HandleSamlIdpInitiatedLogin
+// @Title HandleSamlIdpInitiatedLogin
+// @Tag Login API
+// @Description start the IdP-initiated SSO of the application, the user is signed in and an unsolicited SAML response is sent to the SAML reply URL of the application
+// @Param   owner     path    string  true        "The owner of the application"
+// @Param   application     path    string  true        "The name of the application"
+// @Param   RelayState     query    string  false        "The RelayState, the one of the application is used if it's empty"
+// @router /saml/idp-initiated/:owner/:application [get]
+func (c *ApiController) HandleSamlIdpInitiatedLogin() {
+	owner := c.Ctx.Input.Param(":owner")
+	applicationName := c.Ctx.Input.Param(":application")
+
+	application, err := object.GetApplication(util.GetId(owner, applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), applicationName))
+		return
+	}
+
+	relayState := object.GetSamlIdpInitiatedRelayState(application, c.Input().Get("RelayState"))
+	c.Redirect(object.GetSamlIdpInitiatedAddress(owner, applicationName, relayState, c.Ctx.Request.Host), http.StatusFound)
+}
+
+// HandleSamlArtifactResolve
+// @Title HandleSamlArtifactResolve
+// @Tag Login API
+// @Description resolve the artifact of the SAML HTTP-Artifact binding with the SOAP binding, the artifact can only be resolved once before it expires
+// @Param   owner     path    string  true        "The owner of the application"
+// @Param   application     path    string  true        "The name of the application"
+// @Param   body    body   string  true        "The SOAP envelope of the ArtifactResolve"
+// @router /saml/artifact/:owner/:application [post]
+func (c *ApiController) HandleSamlArtifactResolve() {
+	owner := c.Ctx.Input.Param(":owner")
+	applicationName := c.Ctx.Input.Param(":application")
+
+	application, err := object.GetApplication(util.GetId(owner, applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), applicationName))
+		return
+	}
+
+	response, err := object.ResolveSamlArtifact(application, c.Ctx.Input.RequestBody, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/xml; charset=utf-8")
+	c.Ctx.Output.Body([]byte(response))
+}
@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -153,74 +154,95 @@ func (c *ApiController) DeleteToken() {
 // @Param   client_id     query    string  true        "OAuth client id"
 // @Param   client_secret     query    string  true        "OAuth client secret"
 // @Param   code     query    string  true        "OAuth code"
+// @Param   device_code     query    string  false        "OAuth device code"
+// @Param   subject_token     query    string  false        "OAuth token exchange subject token"
+// @Param   subject_token_type     query    string  false        "OAuth token exchange subject token type"
+// @Param   actor_token     query    string  false        "OAuth token exchange actor token"
+// @Param   actor_token_type     query    string  false        "OAuth token exchange actor token type"
+// @Param   audience     query    string  false        "OAuth token exchange audience"
+// @Param   assertion     query    string  false        "JWT bearer grant assertion"
+// @Param   client_assertion_type     query    string  false        "urn:ietf:params:oauth:client-assertion-type:jwt-bearer for private_key_jwt"
+// @Param   client_assertion     query    string  false        "the client assertion JWT for private_key_jwt"
+// @Param   authorization_details     query    string  false        "the authorization details (RFC 9396) requested for the token"
 // @Success 200 {object} object.TokenWrapper The Response object
 // @Success 400 {object} object.TokenError The Response object
 // @Success 401 {object} object.TokenError The Response object
 // @router /login/oauth/access_token [post]
 func (c *ApiController) GetOAuthToken() {
-	clientId := c.Input().Get("client_id")
-	clientSecret := c.Input().Get("client_secret")
-	grantType := c.Input().Get("grant_type")
-	code := c.Input().Get("code")
-	verifier := c.Input().Get("code_verifier")
-	scope := c.Input().Get("scope")
-	nonce := c.Input().Get("nonce")
-	username := c.Input().Get("username")
-	password := c.Input().Get("password")
-	tag := c.Input().Get("tag")
-	avatar := c.Input().Get("avatar")
-	refreshToken := c.Input().Get("refresh_token")
+	tokenRequest := &object.TokenRequest{
+		GrantType:            c.Input().Get("grant_type"),
+		ClientId:             c.Input().Get("client_id"),
+		ClientSecret:         c.Input().Get("client_secret"),
+		Code:                 c.Input().Get("code"),
+		Verifier:             c.Input().Get("code_verifier"),
+		Scope:                c.Input().Get("scope"),
+		Nonce:                c.Input().Get("nonce"),
+		Username:             c.Input().Get("username"),
+		Password:             c.Input().Get("password"),
+		Tag:                  c.Input().Get("tag"),
+		Avatar:               c.Input().Get("avatar"),
+		RefreshToken:         c.Input().Get("refresh_token"),
+		DeviceCode:           c.Input().Get("device_code"),
+		AuthReqId:            c.Input().Get("auth_req_id"),
+		SubjectToken:         c.Input().Get("subject_token"),
+		SubjectTokenType:     c.Input().Get("subject_token_type"),
+		ActorToken:           c.Input().Get("actor_token"),
+		ActorTokenType:       c.Input().Get("actor_token_type"),
+		Audience:             c.Input().Get("audience"),
+		Assertion:            c.Input().Get("assertion"),
+		AuthorizationDetails: c.Input().Get("authorization_details"),
+	}
+	clientAssertionType := c.Input().Get("client_assertion_type")
+	clientAssertion := c.Input().Get("client_assertion")
 
-	if clientId == "" && clientSecret == "" {
-		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
+	if tokenRequest.ClientId == "" && tokenRequest.ClientSecret == "" {
+		tokenRequest.ClientId, tokenRequest.ClientSecret, _ = c.Ctx.Request.BasicAuth()
 	}
 
 	if len(c.Ctx.Input.RequestBody) != 0 {
-		// If clientId is empty, try to read data from RequestBody
-		var tokenRequest TokenRequest
-		err := json.Unmarshal(c.Ctx.Input.RequestBody, &tokenRequest)
+		// the parameters that are not in the query or the form are read from the JSON body
+		var body TokenRequest
+		err := json.Unmarshal(c.Ctx.Input.RequestBody, &body)
 		if err == nil {
-			if clientId == "" {
-				clientId = tokenRequest.ClientId
-			}
-			if clientSecret == "" {
-				clientSecret = tokenRequest.ClientSecret
-			}
-			if grantType == "" {
-				grantType = tokenRequest.GrantType
-			}
-			if code == "" {
-				code = tokenRequest.Code
-			}
-			if verifier == "" {
-				verifier = tokenRequest.Verifier
+			setIfEmpty := func(value *string, bodyValue string) {
+				if *value == "" {
+					*value = bodyValue
+				}
 			}
-			if scope == "" {
-				scope = tokenRequest.Scope
-			}
-			if nonce == "" {
-				nonce = tokenRequest.Nonce
-			}
-			if username == "" {
-				username = tokenRequest.Username
-			}
-			if password == "" {
-				password = tokenRequest.Password
-			}
-			if tag == "" {
-				tag = tokenRequest.Tag
-			}
-			if avatar == "" {
-				avatar = tokenRequest.Avatar
-			}
-			if refreshToken == "" {
-				refreshToken = tokenRequest.RefreshToken
+
+			setIfEmpty(&tokenRequest.ClientId, body.ClientId)
+			setIfEmpty(&tokenRequest.ClientSecret, body.ClientSecret)
+			setIfEmpty(&tokenRequest.GrantType, body.GrantType)
+			setIfEmpty(&tokenRequest.Code, body.Code)
+			setIfEmpty(&tokenRequest.Verifier, body.Verifier)
+			setIfEmpty(&tokenRequest.Scope, body.Scope)
+			setIfEmpty(&tokenRequest.Nonce, body.Nonce)
+			setIfEmpty(&tokenRequest.Username, body.Username)
+			setIfEmpty(&tokenRequest.Password, body.Password)
+			setIfEmpty(&tokenRequest.Tag, body.Tag)
+			setIfEmpty(&tokenRequest.Avatar, body.Avatar)
+			setIfEmpty(&tokenRequest.RefreshToken, body.RefreshToken)
+			setIfEmpty(&tokenRequest.DeviceCode, body.DeviceCode)
+			setIfEmpty(&tokenRequest.AuthReqId, body.AuthReqId)
+			setIfEmpty(&tokenRequest.SubjectToken, body.SubjectToken)
+			setIfEmpty(&tokenRequest.SubjectTokenType, body.SubjectTokenType)
+			setIfEmpty(&tokenRequest.ActorToken, body.ActorToken)
+			setIfEmpty(&tokenRequest.ActorTokenType, body.ActorTokenType)
+			setIfEmpty(&tokenRequest.Audience, body.Audience)
+			setIfEmpty(&tokenRequest.Assertion, body.Assertion)
+			setIfEmpty(&clientAssertionType, body.ClientAssertionType)
+			setIfEmpty(&clientAssertion, body.ClientAssertion)
+			if len(body.AuthorizationDetails) != 0 {
+				setIfEmpty(&tokenRequest.AuthorizationDetails, string(body.AuthorizationDetails))
 			}
 		}
 	}
 
-	host := c.Ctx.Request.Host
-	token, err := object.GetOAuthToken(grantType, clientId, clientSecret, code, verifier, scope, nonce, username, password, host, refreshToken, tag, avatar, c.GetAcceptLanguage())
+	if !c.completeTokenRequest(tokenRequest, clientAssertionType, clientAssertion) {
+		return
+	}
+
+	token, err := object.GetOAuthToken(tokenRequest)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -231,6 +253,357 @@ func (c *ApiController) GetOAuthToken() {
 	c.ServeJSON()
 }
 
+// DeviceAuthorization
+// @Title DeviceAuthorization
+// @Tag Token API
+// @Description start the OAuth 2.0 device authorization grant (RFC 8628)
+// @Param   client_id     query    string  true        "OAuth client id"
+// @Param   client_secret     query    string  false        "OAuth client secret"
+// @Param   scope     query    string  false        "OAuth scope"
+// @Success 200 {object} object.DeviceAuthResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/device_authorization [post]
+func (c *ApiController) DeviceAuthorization() {
+	clientId := c.Input().Get("client_id")
+	clientSecret := c.Input().Get("client_secret")
+	scope := c.Input().Get("scope")
+
+	if clientId == "" && clientSecret == "" {
+		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
+	}
+
+	clientAuth, ok := c.authenticateClient(clientId, clientSecret, c.Input().Get("client_assertion_type"), c.Input().Get("client_assertion"))
+	if !ok {
+		return
+	}
+
+	resp, tokenError, err := object.GetDeviceAuthResponse(clientAuth.ClientId, clientAuth.ClientSecret, scope, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+	} else {
+		c.Data["json"] = resp
+	}
+	c.SetTokenErrorHttpStatus()
+	c.ServeJSON()
+}
+
+// BackchannelAuthentication
+// @Title BackchannelAuthentication
+// @Tag Token API
+// @Description start the OpenID Connect client-initiated backchannel authentication (CIBA)
+// @Param   client_id     query    string  true        "OAuth client id"
+// @Param   client_secret     query    string  false        "OAuth client secret"
+// @Param   scope     query    string  true        "OAuth scope, should contain openid"
+// @Param   login_hint     query    string  false        "The username, email or phone of the user"
+// @Param   id_token_hint     query    string  false        "An ID token previously issued to the client for the user"
+// @Param   binding_message     query    string  false        "The message displayed to the user on both devices"
+// @Param   requested_expiry     query    string  false        "The requested lifetime of the request in seconds"
+// @Param   client_notification_token     query    string  false        "The bearer token of the client notification endpoint, required in the ping mode"
+// @Success 200 {object} object.CibaAuthResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/bc-authorize [post]
+func (c *ApiController) BackchannelAuthentication() {
+	clientId := c.Input().Get("client_id")
+	clientSecret := c.Input().Get("client_secret")
+
+	if clientId == "" && clientSecret == "" {
+		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
+	}
+
+	clientAuth, ok := c.authenticateClient(clientId, clientSecret, c.Input().Get("client_assertion_type"), c.Input().Get("client_assertion"))
+	if !ok {
+		return
+	}
+
+	resp, tokenError, err := object.GetCibaAuthResponse(clientAuth.ClientId, clientAuth.ClientSecret, c.Input().Get("scope"), c.Input().Get("login_hint"), c.Input().Get("id_token_hint"), c.Input().Get("binding_message"), c.Input().Get("requested_expiry"), c.Input().Get("client_notification_token"), c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+	} else {
+		c.Data["json"] = resp
+	}
+	c.SetTokenErrorHttpStatus()
+	c.ServeJSON()
+}
+
+// PushAuthorizationRequest
+// @Title PushAuthorizationRequest
+// @Tag Token API
+// @Description push the authorization request parameters before redirecting the user (RFC 9126)
+// @Param   client_id     query    string  true        "OAuth client id"
+// @Param   client_secret     query    string  false        "OAuth client secret, not sent by public clients"
+// @Param   response_type     query    string  false        "OAuth response type"
+// @Param   redirect_uri     query    string  false        "OAuth redirect uri"
+// @Param   scope     query    string  false        "OAuth scope"
+// @Param   state     query    string  false        "OAuth state"
+// @Param   nonce     query    string  false        "OAuth nonce"
+// @Param   code_challenge     query    string  false        "OAuth code challenge"
+// @Param   code_challenge_method     query    string  false        "OAuth code challenge method"
+// @Param   request     query    string  false        "signed request object (RFC 9101)"
+// @Success 201 {object} object.ParResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/par [post]
+func (c *ApiController) PushAuthorizationRequest() {
+	clientId := c.Input().Get("client_id")
+	clientSecret := c.Input().Get("client_secret")
+	request := c.Input().Get("request")
+	authRequest := &object.AuthorizationRequest{
+		ResponseType:        c.Input().Get("response_type"),
+		RedirectUri:         c.Input().Get("redirect_uri"),
+		Scope:               c.Input().Get("scope"),
+		State:               c.Input().Get("state"),
+		Nonce:               c.Input().Get("nonce"),
+		CodeChallenge:       c.Input().Get("code_challenge"),
+		CodeChallengeMethod: c.Input().Get("code_challenge_method"),
+		AcrValues:           c.Input().Get("acr_values"),
+		MaxAge:              c.Input().Get("max_age"),
+		Claims:              c.Input().Get("claims"),
+
+		AuthorizationDetails: c.Input().Get("authorization_details"),
+	}
+
+	if clientId == "" && clientSecret == "" {
+		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
+	}
+
+	if c.Input().Get("request_uri") != "" {
+		c.Data["json"] = &object.TokenError{
+			Error:            object.InvalidRequest,
+			ErrorDescription: "request_uri is not allowed in a pushed authorization request",
+		}
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	clientAuth, ok := c.authenticateClient(clientId, clientSecret, c.Input().Get("client_assertion_type"), c.Input().Get("client_assertion"))
+	if !ok {
+		return
+	}
+
+	resp, tokenError, err := object.PushAuthorizationRequest(clientAuth.ClientId, clientAuth.ClientSecret, authRequest, request, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+	} else {
+		c.Ctx.Output.SetStatus(201)
+		c.Data["json"] = resp
+	}
+	c.SetTokenErrorHttpStatus()
+	c.ServeJSON()
+}
+
+// GetDeviceAuth
+// @Title GetDeviceAuth
+// @Tag Token API
+// @Description get the pending device authorization request by user code
+// @Param   userCode     query    string  true        "The user code displayed on the device"
+// @Success 200 {object} controllers.Response The Response object
+// @router /get-device-auth [get]
+func (c *ApiController) GetDeviceAuth() {
+	userId, ok := c.RequireSignedIn()
+	if !ok {
+		return
+	}
+
+	userCode := c.Input().Get("userCode")
+	deviceAuth, err := object.GetDeviceAuthByUserCode(userCode)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if deviceAuth == nil {
+		c.ResponseError(c.T("token:The user code is invalid or has expired"))
+		return
+	}
+
+	application, err := object.GetApplication(deviceAuth.Application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist"), deviceAuth.Application))
+		return
+	}
+
+	c.ResponseOk(deviceAuth, object.GetMaskedApplication(application, userId))
+}
+
+// ApproveDeviceAuth
+// @Title ApproveDeviceAuth
+// @Tag Token API
+// @Description approve or deny the pending device authorization request by user code
+// @Param   userCode     query    string  true        "The user code displayed on the device"
+// @Param   approved     query    string  true        "Whether the user approves the request, true or false"
+// @Success 200 {object} controllers.Response The Response object
+// @router /approve-device-auth [post]
+func (c *ApiController) ApproveDeviceAuth() {
+	userId, ok := c.RequireSignedIn()
+	if !ok {
+		return
+	}
+
+	userCode := c.Input().Get("userCode")
+	approved := c.Input().Get("approved") == "true"
+
+	deviceAuth, err := object.GetDeviceAuthByUserCode(userCode)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if deviceAuth == nil {
+		c.ResponseError(c.T("token:The user code is invalid or has expired"))
+		return
+	}
+
+	application, err := object.GetApplication(deviceAuth.Application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist"), deviceAuth.Application))
+		return
+	}
+
+	if approved {
+		allowed, err := object.CheckLoginPermission(userId, application)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		if !allowed {
+			c.ResponseError(c.T("auth:Unauthorized operation"))
+			return
+		}
+	}
+
+	decided, err := object.ApproveDeviceAuth(userCode, userId, approved)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if !decided {
+		c.ResponseError(c.T("token:The user code is invalid or has expired"))
+		return
+	}
+
+	c.ResponseOk()
+}
+
+// GetCibaAuths
+// @Title GetCibaAuths
+// @Tag Token API
+// @Description get the pending backchannel authentication requests of the current user
+// @Success 200 {object} controllers.Response The Response object
+// @router /get-ciba-auths [get]
+func (c *ApiController) GetCibaAuths() {
+	userId, ok := c.RequireSignedIn()
+	if !ok {
+		return
+	}
+
+	cibaAuths, err := object.GetCibaAuthsByUser(userId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	applications := map[string]*object.Application{}
+	for _, cibaAuth := range cibaAuths {
+		if _, ok := applications[cibaAuth.Application]; ok {
+			continue
+		}
+
+		application, err := object.GetApplication(cibaAuth.Application)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if application != nil {
+			applications[cibaAuth.Application] = object.GetMaskedApplication(application, userId)
+		}
+	}
+
+	c.ResponseOk(cibaAuths, applications)
+}
+
+// ApproveCibaAuth
+// @Title ApproveCibaAuth
+// @Tag Token API
+// @Description approve or deny the pending backchannel authentication request of the current user
+// @Param   id     query    string  true        "The id of the request"
+// @Param   approved     query    string  true        "Whether the user approves the request, true or false"
+// @Success 200 {object} controllers.Response The Response object
+// @router /approve-ciba-auth [post]
+func (c *ApiController) ApproveCibaAuth() {
+	userId, ok := c.RequireSignedIn()
+	if !ok {
+		return
+	}
+
+	id := c.Input().Get("id")
+	approved := c.Input().Get("approved") == "true"
+
+	cibaAuth, err := object.GetCibaAuth(id, userId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if cibaAuth == nil {
+		c.ResponseError(c.T("token:The authentication request is invalid or has expired"))
+		return
+	}
+
+	application, err := object.GetApplication(cibaAuth.Application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("auth:The application: %s does not exist"), cibaAuth.Application))
+		return
+	}
+
+	decided, err := object.ApproveCibaAuth(application, id, userId, approved)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if !decided {
+		c.ResponseError(c.T("token:The authentication request is invalid or has expired"))
+		return
+	}
+
+	c.ResponseOk()
+}
+
 // RefreshToken
 // @Title RefreshToken
 // @Tag Token API
@@ -245,26 +618,35 @@ func (c *ApiController) GetOAuthToken() {
 // @Success 401 {object} object.TokenError The Response object
 // @router /login/oauth/refresh_token [post]
 func (c *ApiController) RefreshToken() {
-	grantType := c.Input().Get("grant_type")
-	refreshToken := c.Input().Get("refresh_token")
-	scope := c.Input().Get("scope")
-	clientId := c.Input().Get("client_id")
-	clientSecret := c.Input().Get("client_secret")
-	host := c.Ctx.Request.Host
+	tokenRequest := &object.TokenRequest{
+		GrantType:            c.Input().Get("grant_type"),
+		RefreshToken:         c.Input().Get("refresh_token"),
+		Scope:                c.Input().Get("scope"),
+		ClientId:             c.Input().Get("client_id"),
+		ClientSecret:         c.Input().Get("client_secret"),
+		AuthorizationDetails: c.Input().Get("authorization_details"),
+	}
 
-	if clientId == "" {
+	if tokenRequest.ClientId == "" {
 		// If clientID is empty, try to read data from RequestBody
-		var tokenRequest TokenRequest
-		if err := json.Unmarshal(c.Ctx.Input.RequestBody, &tokenRequest); err == nil {
-			clientId = tokenRequest.ClientId
-			clientSecret = tokenRequest.ClientSecret
-			grantType = tokenRequest.GrantType
-			scope = tokenRequest.Scope
-			refreshToken = tokenRequest.RefreshToken
+		var body TokenRequest
+		if err := json.Unmarshal(c.Ctx.Input.RequestBody, &body); err == nil {
+			tokenRequest.ClientId = body.ClientId
+			tokenRequest.ClientSecret = body.ClientSecret
+			tokenRequest.GrantType = body.GrantType
+			tokenRequest.Scope = body.Scope
+			tokenRequest.RefreshToken = body.RefreshToken
+			if len(body.AuthorizationDetails) != 0 {
+				tokenRequest.AuthorizationDetails = string(body.AuthorizationDetails)
+			}
 		}
 	}
 
-	refreshToken2, err := object.RefreshToken(grantType, refreshToken, scope, clientId, clientSecret, host)
+	if !c.completeTokenRequest(tokenRequest, c.Input().Get("client_assertion_type"), c.Input().Get("client_assertion")) {
+		return
+	}
+
+	refreshToken2, err := object.RefreshToken(tokenRequest)
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -275,6 +657,78 @@ func (c *ApiController) RefreshToken() {
 	c.ServeJSON()
 }
 
+// authenticateClient authenticates the client by its client assertion or TLS certificate if the application
+// declares so, the returned client id and secret are the ones that the grants should check
+func (c *ApiController) authenticateClient(clientId string, clientSecret string, clientAssertionType string, clientAssertion string) (*object.ClientAuthentication, bool) {
+	certificates := object.GetClientCertificates(c.Ctx.Request)
+	clientAuth, tokenError, err := object.AuthenticateClient(clientId, clientSecret, clientAssertionType, clientAssertion, certificates, c.Ctx.Request.Host, c.Ctx.Request.URL.Path)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, false
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return nil, false
+	}
+
+	return clientAuth, true
+}
+
+// completeTokenRequest authenticates the client and validates the DPoP proof of the token request, then fills in
+// what the grants need to know about them and about the request itself
+func (c *ApiController) completeTokenRequest(tokenRequest *object.TokenRequest, clientAssertionType string, clientAssertion string) bool {
+	clientAuth, ok := c.authenticateClient(tokenRequest.ClientId, tokenRequest.ClientSecret, clientAssertionType, clientAssertion)
+	if !ok {
+		return false
+	}
+
+	dpopJkt, ok := c.getDpopJkt()
+	if !ok {
+		return false
+	}
+
+	tokenRequest.ClientId = clientAuth.ClientId
+	tokenRequest.ClientSecret = clientAuth.ClientSecret
+	tokenRequest.CertThumbprint = clientAuth.CertThumbprint
+	tokenRequest.DpopJkt = dpopJkt
+	tokenRequest.Host = c.Ctx.Request.Host
+	tokenRequest.Lang = c.GetAcceptLanguage()
+	return true
+}
+
+// getDpopJkt validates the DPoP proof sent to the token endpoint and returns the thumbprint of its key,
+// which is empty when no proof is sent. A fresh nonce is always returned for the next proof
+func (c *ApiController) getDpopJkt() (string, bool) {
+	nonce, err := object.GetDpopNonce()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return "", false
+	}
+	c.Ctx.Output.Header("DPoP-Nonce", nonce)
+
+	proof := c.Ctx.Request.Header.Get("DPoP")
+	if proof == "" {
+		return "", true
+	}
+
+	jkt, tokenError, err := object.ValidateDpopProof(proof, c.Ctx.Request.Method, c.Ctx.Request.URL.Path, c.Ctx.Request.Host, "")
+	if err != nil {
+		c.ResponseError(err.Error())
+		return "", false
+	}
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return "", false
+	}
+
+	return jkt, true
+}
+
 func (c *ApiController) ResponseTokenError(errorMsg string) {
 	c.Data["json"] = &object.TokenError{
 		Error: errorMsg,
@@ -300,23 +754,26 @@ func (c *ApiController) ResponseTokenError(errorMsg string) {
 // @router /login/oauth/introspect [post]
 func (c *ApiController) IntrospectToken() {
 	tokenValue := c.Input().Get("token")
+	clientAssertionType := c.Input().Get("client_assertion_type")
+	clientAssertion := c.Input().Get("client_assertion")
 	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
 	if !ok {
 		clientId = c.Input().Get("client_id")
 		clientSecret = c.Input().Get("client_secret")
-		if clientId == "" || clientSecret == "" {
+		// clients using a client assertion or a TLS certificate don't send their secret
+		if clientId == "" && clientAssertion == "" {
 			c.ResponseTokenError(object.InvalidRequest)
 			return
 		}
 	}
 
-	application, err := object.GetApplicationByClientId(clientId)
-	if err != nil {
-		c.ResponseTokenError(err.Error())
+	clientAuth, ok := c.authenticateClient(clientId, clientSecret, clientAssertionType, clientAssertion)
+	if !ok {
 		return
 	}
 
-	if application == nil || application.ClientSecret != clientSecret {
+	application := clientAuth.Application
+	if application == nil || application.ClientSecret != clientAuth.ClientSecret {
 		c.ResponseTokenError(c.T("token:Invalid application or wrong clientSecret"))
 		return
 	}
@@ -327,18 +784,22 @@ func (c *ApiController) IntrospectToken() {
 		c.ResponseTokenError(err.Error())
 		return
 	}
-	if token == nil {
+	// revoked tokens have expires_in set to 0
+	if token == nil || token.ExpiresIn <= 0 {
 		c.Data["json"] = &object.IntrospectionResponse{Active: false}
 		c.ServeJSON()
 		return
 	}
 
+	if token.IsOpaque {
+		c.Data["json"] = object.GetOpaqueTokenIntrospection(application, token, tokenValue, clientId)
+		c.ServeJSON()
+		return
+	}
+
 	if application.TokenFormat == "JWT-Standard" {
 		jwtToken, err := object.ParseStandardJwtTokenByApplication(tokenValue, application)
 		if err != nil || jwtToken.Valid() != nil {
-			// and token revoked case. but we not implement
-			// TODO: 2022-03-03 add token revoked check, when we implemented the Token Revocation(rfc7009) Specs.
-			// refs: https://tools.ietf.org/html/rfc7009
 			c.Data["json"] = &object.IntrospectionResponse{Active: false}
 			c.ServeJSON()
 			return
@@ -357,6 +818,9 @@ func (c *ApiController) IntrospectToken() {
 			Aud:       jwtToken.Audience,
 			Iss:       jwtToken.Issuer,
 			Jti:       jwtToken.ID,
+			Cnf:       jwtToken.Cnf,
+
+			AuthorizationDetails: token.GetAuthorizationDetails(),
 		}
 		c.ServeJSON()
 		return
@@ -364,9 +828,6 @@ func (c *ApiController) IntrospectToken() {
 
 	jwtToken, err := object.ParseJwtTokenByApplication(tokenValue, application)
 	if err != nil || jwtToken.Valid() != nil {
-		// and token revoked case. but we not implement
-		// TODO: 2022-03-03 add token revoked check, when we implemented the Token Revocation(rfc7009) Specs.
-		// refs: https://tools.ietf.org/html/rfc7009
 		c.Data["json"] = &object.IntrospectionResponse{Active: false}
 		c.ServeJSON()
 		return
@@ -385,6 +846,57 @@ func (c *ApiController) IntrospectToken() {
 		Aud:       jwtToken.Audience,
 		Iss:       jwtToken.Issuer,
 		Jti:       jwtToken.ID,
+		Cnf:       jwtToken.Cnf,
+
+		AuthorizationDetails: token.GetAuthorizationDetails(),
 	}
 	c.ServeJSON()
 }
+
+// RevokeToken
+// @Title RevokeToken
+// @Tag Login API
+// @Description revoke an access token or a refresh token (RFC 7009), revoking a refresh token also revokes
+// every access token issued with it
+// @Param   token    formData    string  true        "access_token's value or refresh_token's value"
+// @Param   token_type_hint    formData    string  false        "the token type access_token or refresh_token"
+// @Param   client_id    formData    string  false        "OAuth client id, if not sent with Basic Authorization"
+// @Param   client_secret    formData    string  false        "OAuth client secret, if not sent with Basic Authorization"
+// @Success 200 {string} string "The token is revoked or doesn't exist"
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/revoke [post]
+func (c *ApiController) RevokeToken() {
+	tokenValue := c.Input().Get("token")
+	tokenTypeHint := c.Input().Get("token_type_hint")
+	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
+	if !ok {
+		clientId = c.Input().Get("client_id")
+		clientSecret = c.Input().Get("client_secret")
+	}
+
+	clientAuth, ok := c.authenticateClient(clientId, clientSecret, c.Input().Get("client_assertion_type"), c.Input().Get("client_assertion"))
+	if !ok {
+		return
+	}
+
+	tokens, tokenError, err := object.RevokeToken(clientAuth.ClientId, clientAuth.ClientSecret, tokenValue, tokenTypeHint)
+	if err != nil {
+		c.ResponseTokenError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	if len(tokens) != 0 {
+		c.setRecordRevokeToken(tokens)
+	}
+
+	c.Ctx.Output.SetStatus(200)
+	c.Ctx.Output.Body([]byte{})
+}
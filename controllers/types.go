@@ -14,6 +14,8 @@
 
 package controllers
 
+import "encoding/json"
+
 type TokenRequest struct {
 	ClientId     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
@@ -27,4 +29,18 @@ type TokenRequest struct {
 	Tag          string `json:"tag"`
 	Avatar       string `json:"avatar"`
 	RefreshToken string `json:"refresh_token"`
+	DeviceCode   string `json:"device_code"`
+	AuthReqId    string `json:"auth_req_id"`
+
+	SubjectToken     string `json:"subject_token"`
+	SubjectTokenType string `json:"subject_token_type"`
+	ActorToken       string `json:"actor_token"`
+	ActorTokenType   string `json:"actor_token_type"`
+	Audience         string `json:"audience"`
+	Assertion        string `json:"assertion"`
+
+	ClientAssertionType string `json:"client_assertion_type"`
+	ClientAssertion     string `json:"client_assertion"`
+
+	AuthorizationDetails json.RawMessage `json:"authorization_details"`
 }
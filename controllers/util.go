@@ -86,6 +86,12 @@ func (c *ApiController) SetTokenErrorHttpStatus() {
 	}
 }
 
+// setRecordRevokeToken makes the record filter add a "revoke-token" record for the revoked tokens, so that webhooks can react
+func (c *ApiController) setRecordRevokeToken(tokens []*object.Token) {
+	c.Ctx.Input.SetParam("recordRevokeToken", util.StructToJson(object.GetMaskedTokens(tokens)))
+	c.Ctx.Input.SetParam("recordRevokeTokenUserId", util.GetId(tokens[0].Organization, tokens[0].User))
+}
+
 // RequireSignedIn ...
 func (c *ApiController) RequireSignedIn() (string, bool) {
 	userId := c.GetSessionUsername()
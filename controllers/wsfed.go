@@ -0,0 +1,139 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+func (c *ApiController) getWsFedApplication() (*object.Application, bool) {
+	owner := c.Ctx.Input.Param(":owner")
+	applicationName := c.Ctx.Input.Param(":application")
+
+	application, err := object.GetApplication(util.GetId(owner, applicationName))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, false
+	}
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), applicationName))
+		return nil, false
+	}
+	if !application.EnableWsFed {
+		c.ResponseError(fmt.Sprintf(c.T("wsfed:WS-Federation is not enabled for the application: %s"), applicationName))
+		return nil, false
+	}
+	return application, true
+}
+
+// HandleWsFed
+// @Title HandleWsFed
+// @Tag Login API
+// @Description the passive requestor endpoint of WS-Federation. For wsignin1.0, the user is signed in and the SAML token is posted to the reply URL of the relying party; for wsignout1.0 and wsignoutcleanup1.0, the user is logged out of the applications of the session
+// @Param   owner     path    string  true        "The owner of the application"
+// @Param   application     path    string  true        "The name of the application"
+// @Param   wa     query    string  true        "The action: wsignin1.0, wsignout1.0 or wsignoutcleanup1.0"
+// @Param   wtrealm     query    string  false        "The realm of the relying party, required for wsignin1.0"
+// @Param   wreply     query    string  false        "The reply URL"
+// @Param   wctx     query    string  false        "The context that is sent back to the relying party"
+// @router /wsfed/:owner/:application [get]
+func (c *ApiController) HandleWsFed() {
+	application, ok := c.getWsFedApplication()
+	if !ok {
+		return
+	}
+
+	action := c.Input().Get("wa")
+	reply := c.Input().Get("wreply")
+	host := c.Ctx.Request.Host
+
+	switch action {
+	case object.WsFedActionSignIn:
+		realm := c.Input().Get("wtrealm")
+		_, err := object.GetWsFedReply(application, realm, reply)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		targetUrl := object.GetWsFedSignInAddress(application.Owner, application.Name, realm, reply, c.Input().Get("wctx"), host)
+		c.Redirect(targetUrl, http.StatusFound)
+	case object.WsFedActionSignOut, object.WsFedActionSignOutCleanup:
+		reply, err := object.GetWsFedSignOutReply(application, reply, host)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		frontchannelLogoutUrls := []string{}
+		if userId := c.GetSessionUsername(); userId != "" {
+			user, err := object.GetUser(userId)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			if user != nil {
+				sessionId := c.Ctx.Input.CruSession.SessionID()
+				c.ClearUserSession()
+				c.ClearTokenSession()
+				frontchannelLogoutUrls, err = object.LogoutSessionApplications(user, sessionId, host, application.Name)
+				if err != nil {
+					c.ResponseError(err.Error())
+					return
+				}
+
+				_, err = object.DeleteSessionId(util.GetSessionId(user.Owner, user.Name, object.CasdoorApplication), sessionId)
+				if err != nil {
+					c.ResponseError(err.Error())
+					return
+				}
+
+				util.LogInfo(c.Ctx, "API: [%s] logged out by the WS-Federation application: [%s]", userId, application.GetId())
+			}
+		}
+
+		c.serveFrontchannelLogoutPage(frontchannelLogoutUrls, reply)
+	default:
+		c.ResponseError(fmt.Sprintf(c.T("wsfed:Unsupported WS-Federation action: %s"), action))
+	}
+}
+
+// GetWsFedMetadata
+// @Title GetWsFedMetadata
+// @Tag Login API
+// @Description get the signed federation metadata of the application for the WS-Federation relying parties
+// @Param   owner     path    string  true        "The owner of the application"
+// @Param   application     path    string  true        "The name of the application"
+// @router /wsfed/metadata/:owner/:application [get]
+func (c *ApiController) GetWsFedMetadata() {
+	application, ok := c.getWsFedApplication()
+	if !ok {
+		return
+	}
+
+	metadata, err := object.GetWsFedMetadata(application, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/xml; charset=utf-8")
+	c.Ctx.Output.Body([]byte(metadata))
+}
@@ -50,6 +50,12 @@ type AuthForm struct {
 	RelayState   string `json:"relayState"`
 	SamlRequest  string `json:"samlRequest"`
 	SamlResponse string `json:"samlResponse"`
+	SamlQuery    string `json:"samlQuery"`
+	IdpInitiated bool   `json:"idpInitiated"`
+
+	WsFedRealm   string `json:"wsFedRealm"`
+	WsFedReply   string `json:"wsFedReply"`
+	WsFedContext string `json:"wsFedContext"`
 
 	CaptchaType  string `json:"captchaType"`
 	CaptchaToken string `json:"captchaToken"`
@@ -92,30 +92,70 @@ type Application struct {
 	CertPublicKey         string          `xorm:"-" json:"certPublicKey"`
 	Tags                  []string        `xorm:"mediumtext" json:"tags"`
 	SamlAttributes        []*SamlItem     `xorm:"varchar(1000)" json:"samlAttributes"`
+	CustomScopes          []*CustomScope  `xorm:"mediumtext" json:"customScopes"`
 	IsShared              bool            `json:"isShared"`
-
-	ClientId             string     `xorm:"varchar(100)" json:"clientId"`
-	ClientSecret         string     `xorm:"varchar(100)" json:"clientSecret"`
-	RedirectUris         []string   `xorm:"varchar(1000)" json:"redirectUris"`
-	TokenFormat          string     `xorm:"varchar(100)" json:"tokenFormat"`
-	TokenSigningMethod   string     `xorm:"varchar(100)" json:"tokenSigningMethod"`
-	TokenFields          []string   `xorm:"varchar(1000)" json:"tokenFields"`
-	ExpireInHours        int        `json:"expireInHours"`
-	RefreshExpireInHours int        `json:"refreshExpireInHours"`
-	SignupUrl            string     `xorm:"varchar(200)" json:"signupUrl"`
-	SigninUrl            string     `xorm:"varchar(200)" json:"signinUrl"`
-	ForgetUrl            string     `xorm:"varchar(200)" json:"forgetUrl"`
-	AffiliationUrl       string     `xorm:"varchar(100)" json:"affiliationUrl"`
-	TermsOfUse           string     `xorm:"varchar(100)" json:"termsOfUse"`
-	SignupHtml           string     `xorm:"mediumtext" json:"signupHtml"`
-	SigninHtml           string     `xorm:"mediumtext" json:"signinHtml"`
-	ThemeData            *ThemeData `xorm:"json" json:"themeData"`
-	FooterHtml           string     `xorm:"mediumtext" json:"footerHtml"`
-	FormCss              string     `xorm:"text" json:"formCss"`
-	FormCssMobile        string     `xorm:"text" json:"formCssMobile"`
-	FormOffset           int        `json:"formOffset"`
-	FormSideHtml         string     `xorm:"mediumtext" json:"formSideHtml"`
-	FormBackgroundUrl    string     `xorm:"varchar(200)" json:"formBackgroundUrl"`
+	IsThirdParty          bool            `json:"isThirdParty"`
+
+	AuthorizationDetailsTypes []*AuthorizationDetailsType `xorm:"mediumtext" json:"authorizationDetailsTypes"`
+
+	SamlLogoutUrl                 string   `xorm:"varchar(200)" json:"samlLogoutUrl"`
+	SamlSpCertificate             string   `xorm:"mediumtext" json:"samlSpCertificate"`
+	SamlSpMetadataUrl             string   `xorm:"varchar(200)" json:"samlSpMetadataUrl"`
+	SamlSpEntityId                string   `xorm:"varchar(200)" json:"samlSpEntityId"`
+	SamlSpEncryptionCertificate   string   `xorm:"mediumtext" json:"samlSpEncryptionCertificate"`
+	SamlNameIdFormats             []string `xorm:"varchar(1000)" json:"samlNameIdFormats"`
+	EnableSamlAssertionEncryption bool     `json:"enableSamlAssertionEncryption"`
+	SamlAuthnRequestsSigned       bool     `json:"samlAuthnRequestsSigned"`
+	SamlIdpInitiatedRelayState    string   `xorm:"varchar(500)" json:"samlIdpInitiatedRelayState"`
+	EnableSamlArtifactBinding     bool     `json:"enableSamlArtifactBinding"`
+
+	EnableWsFed    bool   `json:"enableWsFed"`
+	WsFedTokenType string `xorm:"varchar(100)" json:"wsFedTokenType"`
+
+	ClientId                        string     `xorm:"varchar(100)" json:"clientId"`
+	ClientSecret                    string     `xorm:"varchar(100)" json:"clientSecret"`
+	RedirectUris                    []string   `xorm:"varchar(1000)" json:"redirectUris"`
+	TokenFormat                     string     `xorm:"varchar(100)" json:"tokenFormat"`
+	TokenSigningMethod              string     `xorm:"varchar(100)" json:"tokenSigningMethod"`
+	TokenFields                     []string   `xorm:"varchar(1000)" json:"tokenFields"`
+	JwtAudiences                    []string   `xorm:"varchar(1000)" json:"jwtAudiences"`
+	TokenExchangeAudiences          []string   `xorm:"varchar(1000)" json:"tokenExchangeAudiences"`
+	TokenExchangeClients            []string   `xorm:"varchar(1000)" json:"tokenExchangeClients"`
+	RequirePar                      bool       `json:"requirePar"`
+	EnableDpop                      bool       `json:"enableDpop"`
+	Jwks                            string     `xorm:"mediumtext" json:"jwks"`
+	JwksUri                         string     `xorm:"varchar(200)" json:"jwksUri"`
+	IdTokenEncryptedResponseAlg     string     `xorm:"varchar(100)" json:"idTokenEncryptedResponseAlg"`
+	IdTokenEncryptedResponseEnc     string     `xorm:"varchar(100)" json:"idTokenEncryptedResponseEnc"`
+	UserinfoSignedResponseAlg       string     `xorm:"varchar(100)" json:"userinfoSignedResponseAlg"`
+	UserinfoEncryptedResponseAlg    string     `xorm:"varchar(100)" json:"userinfoEncryptedResponseAlg"`
+	UserinfoEncryptedResponseEnc    string     `xorm:"varchar(100)" json:"userinfoEncryptedResponseEnc"`
+	TlsClientAuthSubjectDn          string     `xorm:"varchar(200)" json:"tlsClientAuthSubjectDn"`
+	BackchannelLogoutUri            string     `xorm:"varchar(200)" json:"backchannelLogoutUri"`
+	FrontchannelLogoutUri           string     `xorm:"varchar(200)" json:"frontchannelLogoutUri"`
+	PostLogoutRedirectUris          []string   `xorm:"varchar(1000)" json:"postLogoutRedirectUris"`
+	BackchannelTokenDeliveryMode    string     `xorm:"varchar(20)" json:"backchannelTokenDeliveryMode"`
+	ClientNotificationEndpoint      string     `xorm:"varchar(200)" json:"clientNotificationEndpoint"`
+	TokenEndpointAuthMethod         string     `xorm:"varchar(100)" json:"tokenEndpointAuthMethod"`
+	RegistrationAccessTokenHash     string     `xorm:"varchar(100)" json:"registrationAccessTokenHash"`
+	ExpireInHours                   int        `json:"expireInHours"`
+	RefreshExpireInHours            int        `json:"refreshExpireInHours"`
+	RotateRefreshToken              bool       `json:"rotateRefreshToken"`
+	RefreshTokenFamilyExpireInHours int        `json:"refreshTokenFamilyExpireInHours"`
+	SignupUrl                       string     `xorm:"varchar(200)" json:"signupUrl"`
+	SigninUrl                       string     `xorm:"varchar(200)" json:"signinUrl"`
+	ForgetUrl                       string     `xorm:"varchar(200)" json:"forgetUrl"`
+	AffiliationUrl                  string     `xorm:"varchar(100)" json:"affiliationUrl"`
+	TermsOfUse                      string     `xorm:"varchar(100)" json:"termsOfUse"`
+	SignupHtml                      string     `xorm:"mediumtext" json:"signupHtml"`
+	SigninHtml                      string     `xorm:"mediumtext" json:"signinHtml"`
+	ThemeData                       *ThemeData `xorm:"json" json:"themeData"`
+	FooterHtml                      string     `xorm:"mediumtext" json:"footerHtml"`
+	FormCss                         string     `xorm:"text" json:"formCss"`
+	FormCssMobile                   string     `xorm:"text" json:"formCssMobile"`
+	FormOffset                      int        `json:"formOffset"`
+	FormSideHtml                    string     `xorm:"mediumtext" json:"formSideHtml"`
+	FormBackgroundUrl               string     `xorm:"varchar(200)" json:"formBackgroundUrl"`
 
 	FailedSigninLimit      int `json:"failedSigninLimit"`
 	FailedSigninFrozenTime int `json:"failedSigninFrozenTime"`
@@ -543,8 +583,17 @@ func GetMaskedApplication(application *Application, userId string) *Application
 	application.RedirectUris = nil
 	application.TokenFormat = "***"
 	application.TokenFields = nil
+	application.TokenExchangeAudiences = nil
+	application.TokenExchangeClients = nil
+	application.JwtAudiences = nil
+	for _, customScope := range application.CustomScopes {
+		customScope.Claims = nil
+	}
+	application.Jwks = ""
+	application.RegistrationAccessTokenHash = ""
 	application.ExpireInHours = -1
 	application.RefreshExpireInHours = -1
+	application.RefreshTokenFamilyExpireInHours = -1
 	application.FailedSigninLimit = -1
 	application.FailedSigninFrozenTime = -1
 
@@ -646,6 +695,16 @@ func UpdateApplication(id string, application *Application) (bool, error) {
 		return false, fmt.Errorf("only applications belonging to built-in organization can be shared")
 	}
 
+	err = checkCustomScopes(application)
+	if err != nil {
+		return false, err
+	}
+
+	err = checkAuthorizationDetailsTypes(application)
+	if err != nil {
+		return false, err
+	}
+
 	for _, providerItem := range application.Providers {
 		providerItem.Provider = nil
 	}
@@ -685,6 +744,16 @@ func AddApplication(application *Application) (bool, error) {
 		return false, nil
 	}
 
+	err = checkCustomScopes(application)
+	if err != nil {
+		return false, err
+	}
+
+	err = checkAuthorizationDetailsTypes(application)
+	if err != nil {
+		return false, err
+	}
+
 	for _, providerItem := range application.Providers {
 		providerItem.Provider = nil
 	}
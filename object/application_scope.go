@@ -0,0 +1,239 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/casdoor/casdoor/util"
+)
+
+// ScopeClaim is a claim released by a custom scope, its value is computed by an expression over the user
+type ScopeClaim struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// CustomScope is a scope defined by an application in addition to the standard OIDC scopes
+type CustomScope struct {
+	Name        string        `json:"name"`
+	DisplayName string        `json:"displayName"`
+	Description string        `json:"description"`
+	Claims      []*ScopeClaim `json:"claims"`
+}
+
+// the registered claims of a JWT can't be overridden by the custom scopes
+var reservedClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "nonce", "scope", "cnf", "act", "sid", "tokenType", "acr", "amr", "auth_time", "authorization_details"}
+
+var dottedIdentifierRegex = regexp.MustCompile(`\b[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)+`)
+
+// claimParameters resolves the variables of a claim expression, "properties.<key>" is a property of the user and
+// the other variables are the JSON fields of the user
+type claimParameters map[string]interface{}
+
+func (p claimParameters) Get(name string) (interface{}, error) {
+	if strings.HasPrefix(name, "properties.") {
+		properties, _ := p["properties"].(map[string]interface{})
+		if value, ok := properties[strings.TrimPrefix(name, "properties.")]; ok {
+			return value, nil
+		}
+		return "", nil
+	}
+
+	if value, ok := p[name]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("the variable: %s is not defined", name)
+}
+
+// escapeClaimExpression brackets the dotted variables out of the string literals, as govaluate takes them as
+// accessors of structs otherwise
+func escapeClaimExpression(expression string) string {
+	var sb strings.Builder
+	start := 0
+	var quote rune
+	for i, c := range expression {
+		if quote != 0 {
+			if c == quote {
+				sb.WriteString(expression[start : i+1])
+				start = i + 1
+				quote = 0
+			}
+			continue
+		}
+
+		if c == '"' || c == '\'' || c == '`' {
+			sb.WriteString(dottedIdentifierRegex.ReplaceAllString(expression[start:i], "[$0]"))
+			start = i
+			quote = c
+		}
+	}
+
+	if quote != 0 {
+		sb.WriteString(expression[start:])
+	} else {
+		sb.WriteString(dottedIdentifierRegex.ReplaceAllString(expression[start:], "[$0]"))
+	}
+	return sb.String()
+}
+
+func parseClaimExpression(expression string) (*govaluate.EvaluableExpression, error) {
+	return govaluate.NewEvaluableExpression(escapeClaimExpression(expression))
+}
+
+func getNamesAndIds(ids []string) []interface{} {
+	res := []interface{}{}
+	for _, id := range ids {
+		res = append(res, id)
+		if i := strings.LastIndex(id, "/"); i != -1 {
+			res = append(res, id[i+1:])
+		}
+	}
+	return res
+}
+
+// getClaimParameters returns the variables of the claim expressions: the JSON fields of the user, and the groups,
+// roles and permissions as lists of names (the ids of the groups are listed as well)
+func getClaimParameters(user *User) (claimParameters, error) {
+	u := *user
+	err := ExtendUserWithRolesAndPermissions(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	res := claimParameters{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []interface{}{}
+	for _, role := range u.Roles {
+		roles = append(roles, role.Name)
+	}
+	permissions := []interface{}{}
+	for _, permission := range u.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+
+	res["groups"] = getNamesAndIds(u.Groups)
+	res["roles"] = roles
+	res["permissions"] = permissions
+	if res["properties"] == nil {
+		res["properties"] = map[string]interface{}{}
+	}
+	return res, nil
+}
+
+// getCustomScopes returns the custom scopes of the application that are requested
+func (application *Application) getCustomScopes(scope string) []*CustomScope {
+	res := []*CustomScope{}
+	scopes := strings.Fields(scope)
+	for _, customScope := range application.CustomScopes {
+		if util.InSlice(scopes, customScope.Name) {
+			res = append(res, customScope)
+		}
+	}
+	return res
+}
+
+// getCustomScopeClaims computes the claims released by the requested custom scopes of the application
+func getCustomScopeClaims(application *Application, user *User, scope string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	customScopes := application.getCustomScopes(scope)
+	if len(customScopes) == 0 || user == nil {
+		return res, nil
+	}
+
+	parameters, err := getClaimParameters(user)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, customScope := range customScopes {
+		for _, claim := range customScope.Claims {
+			if claim.Name == "" || util.InSlice(reservedClaims, claim.Name) {
+				continue
+			}
+
+			expression, err := parseClaimExpression(claim.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("the expression of the claim: %s of the scope: %s is invalid: %s", claim.Name, customScope.Name, err.Error())
+			}
+
+			value, err := expression.Eval(parameters)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate the claim: %s of the scope: %s: %s", claim.Name, customScope.Name, err.Error())
+			}
+			res[claim.Name] = value
+		}
+	}
+
+	return res, nil
+}
+
+// addCustomClaims adds the custom claims to the claims of a JWT
+func addCustomClaims(claims interface{}, customClaims map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]interface{}{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range customClaims {
+		res[name] = value
+	}
+	return res, nil
+}
+
+// checkCustomScopes validates the custom scopes of the application before it's saved
+func checkCustomScopes(application *Application) error {
+	names := map[string]bool{}
+	for _, customScope := range application.CustomScopes {
+		if customScope.Name == "" || strings.ContainsAny(customScope.Name, " \t\n\"\\") {
+			return fmt.Errorf("the custom scope name: \"%s\" is invalid", customScope.Name)
+		}
+		if names[customScope.Name] {
+			return fmt.Errorf("the custom scope: %s is duplicated", customScope.Name)
+		}
+		names[customScope.Name] = true
+
+		for _, claim := range customScope.Claims {
+			if util.InSlice(reservedClaims, claim.Name) {
+				return fmt.Errorf("the claim: %s of the scope: %s is reserved", claim.Name, customScope.Name)
+			}
+
+			_, err := parseClaimExpression(claim.Expression)
+			if err != nil {
+				return fmt.Errorf("the expression of the claim: %s of the scope: %s is invalid: %s", claim.Name, customScope.Name, err.Error())
+			}
+		}
+	}
+	return nil
+}
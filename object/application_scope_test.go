@@ -0,0 +1,54 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import "testing"
+
+func TestEvalClaimExpression(t *testing.T) {
+	parameters := claimParameters{
+		"email":      "alice@example.com",
+		"properties": map[string]interface{}{"dept": "finance"},
+		"groups":     getNamesAndIds([]string{"built-in/managers"}),
+		"roles":      []interface{}{},
+	}
+
+	scenarios := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{`properties.dept`, "finance"},
+		{`properties.missing`, ""},
+		{`"managers" in groups`, true},
+		{`"built-in/managers" in groups`, true},
+		{`"admins" in roles`, false},
+		{`email == "a.b@example.com"`, false},
+		{`properties.dept == "finance" && "managers" in groups`, true},
+	}
+
+	for _, scenario := range scenarios {
+		expression, err := parseClaimExpression(scenario.expression)
+		if err != nil {
+			t.Fatalf("%s: %s", scenario.expression, err.Error())
+		}
+
+		value, err := expression.Eval(parameters)
+		if err != nil {
+			t.Fatalf("%s: %s", scenario.expression, err.Error())
+		}
+		if value != scenario.expected {
+			t.Errorf("%s = %v, want %v", scenario.expression, value, scenario.expected)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	CasTicketTypeService       = "ST"
+	CasTicketTypeProxy         = "PT"
+	CasTicketTypeProxyGranting = "PGT"
+
+	CasTicketStoreDatabase = "Database"
+	CasTicketStoreRedis    = "Redis"
+
+	defaultCasTicketTimeoutSeconds    = 300
+	defaultCasPgtTicketTimeoutSeconds = 7200
+)
+
+// CasTicket is a ticket of the CAS protocol: a service ticket, a proxy ticket or a proxy-granting ticket. The
+// service and proxy tickets can only be validated once, the proxy-granting tickets can be used until they expire
+type CasTicket struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	ExpireTime  string `xorm:"varchar(100) index" json:"expireTime"`
+
+	Type           string                    `xorm:"varchar(10)" json:"type"`
+	Service        string                    `xorm:"varchar(1000)" json:"service"`
+	UserId         string                    `xorm:"varchar(100)" json:"userId"`
+	IsFromNewLogin bool                      `json:"isFromNewLogin"`
+	Response       *CasAuthenticationSuccess `xorm:"mediumtext json" json:"response"`
+}
+
+// CasTicketStore keeps the CAS tickets so that they can be validated by any Casdoor instance. ConsumeTicket must
+// return a ticket to only one of the concurrent callers
+type CasTicketStore interface {
+	AddTicket(ticket *CasTicket) error
+	GetTicket(name string) (*CasTicket, error)
+	ConsumeTicket(name string) (*CasTicket, error)
+	PurgeExpiredTickets(cutoff time.Time) (int64, error)
+}
+
+var (
+	casTicketStore     CasTicketStore
+	casTicketStoreOnce sync.Once
+)
+
+// getCasTicketStore returns the store configured by casTicketStore, the tickets are kept in the database by default
+func getCasTicketStore() CasTicketStore {
+	casTicketStoreOnce.Do(func() {
+		if casTicketStore != nil {
+			return
+		}
+
+		if conf.GetConfigString("casTicketStore") == CasTicketStoreRedis {
+			casTicketStore = newRedisCasTicketStore(conf.GetConfigString("redisEndpoint"))
+		} else {
+			casTicketStore = &databaseCasTicketStore{}
+		}
+	})
+	return casTicketStore
+}
+
+// getCasTicketTimeout returns the lifetime of the type of tickets, which is configured by
+// casServiceTicketTimeoutSeconds, casProxyTicketTimeoutSeconds and casProxyGrantingTicketTimeoutSeconds
+func getCasTicketTimeout(ticketType string) time.Duration {
+	key := "casServiceTicketTimeoutSeconds"
+	defaultSeconds := int64(defaultCasTicketTimeoutSeconds)
+	switch ticketType {
+	case CasTicketTypeProxy:
+		key = "casProxyTicketTimeoutSeconds"
+	case CasTicketTypeProxyGranting:
+		key = "casProxyGrantingTicketTimeoutSeconds"
+		defaultSeconds = defaultCasPgtTicketTimeoutSeconds
+	}
+
+	seconds, err := conf.GetConfigInt64(key)
+	if err != nil || seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func newCasTicket(ticketType string, name string, response *CasAuthenticationSuccess, service string, userId string, isFromNewLogin bool) *CasTicket {
+	// the tickets are owned by the organization of the user
+	owner := "admin"
+	if strings.Contains(userId, "/") {
+		owner, _ = util.GetOwnerAndNameFromIdNoCheck(userId)
+	}
+
+	now := time.Now()
+	return &CasTicket{
+		Owner:          owner,
+		Name:           name,
+		CreatedTime:    now.Format(time.RFC3339),
+		ExpireTime:     now.Add(getCasTicketTimeout(ticketType)).Format(time.RFC3339),
+		Type:           ticketType,
+		Service:        service,
+		UserId:         userId,
+		IsFromNewLogin: isFromNewLogin,
+		Response:       response,
+	}
+}
+
+func (ticket *CasTicket) isExpired() bool {
+	expireTime, err := time.Parse(time.RFC3339, ticket.ExpireTime)
+	return err != nil || time.Now().After(expireTime)
+}
+
+// databaseCasTicketStore keeps the tickets in the cas_ticket table, which is shared by the Casdoor instances
+type databaseCasTicketStore struct{}
+
+func (store *databaseCasTicketStore) AddTicket(ticket *CasTicket) error {
+	_, err := ormer.Engine.Insert(ticket)
+	return err
+}
+
+func (store *databaseCasTicketStore) GetTicket(name string) (*CasTicket, error) {
+	ticket := CasTicket{}
+	existed, err := ormer.Engine.Where("name = ?", name).Get(&ticket)
+	if err != nil {
+		return nil, err
+	}
+	if !existed {
+		return nil, nil
+	}
+	return &ticket, nil
+}
+
+// ConsumeTicket deletes the ticket and returns it only if this call is the one deleting it, so that the ticket
+// can't be validated twice by the concurrent requests to different instances
+func (store *databaseCasTicketStore) ConsumeTicket(name string) (*CasTicket, error) {
+	ticket, err := store.GetTicket(name)
+	if err != nil || ticket == nil {
+		return nil, err
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{ticket.Owner, ticket.Name}).Delete(&CasTicket{})
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+	return ticket, nil
+}
+
+func (store *databaseCasTicketStore) PurgeExpiredTickets(cutoff time.Time) (int64, error) {
+	return purgeRecords(&CasTicket{}, "cas_ticket", "expire_time < ?", cutoff.Format(time.RFC3339))
+}
+
+func addCasTicket(ticketType string, response *CasAuthenticationSuccess, service string, userId string, isFromNewLogin bool) (string, error) {
+	name := fmt.Sprintf("%s-%s", ticketType, util.GenerateId())
+	ticket := newCasTicket(ticketType, name, response, service, userId, isFromNewLogin)
+	err := getCasTicketStore().AddTicket(ticket)
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// consumeCasTicket returns the service or proxy ticket and invalidates it, nil is returned if it doesn't exist, has
+// been validated or has expired
+func consumeCasTicket(name string) (*CasTicket, error) {
+	// the proxy-granting tickets can't be validated, and they aren't invalidated by the attempts
+	if strings.HasPrefix(name, CasTicketTypeProxyGranting+"-") {
+		return nil, nil
+	}
+
+	ticket, err := getCasTicketStore().ConsumeTicket(name)
+	if err != nil || ticket == nil {
+		return nil, err
+	}
+	if ticket.isExpired() {
+		return nil, nil
+	}
+	return ticket, nil
+}
+
+// getCasPgt returns the proxy-granting ticket, it can be used to get proxy tickets until it expires
+func getCasPgt(name string) (*CasTicket, error) {
+	ticket, err := getCasTicketStore().GetTicket(name)
+	if err != nil || ticket == nil {
+		return nil, err
+	}
+	if ticket.Type != CasTicketTypeProxyGranting || ticket.isExpired() {
+		return nil, nil
+	}
+	return ticket, nil
+}
+
+// purgeExpiredCasTickets removes the tickets that have expired before the cutoff, the ones in Redis expire by
+// themselves
+func purgeExpiredCasTickets(cutoff time.Time) (int64, error) {
+	return getCasTicketStore().PurgeExpiredTickets(cutoff)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const casTicketRedisKeyPrefix = "cas_ticket:"
+
+// redisCasTicketStore keeps the tickets in Redis, they expire with the keys
+type redisCasTicketStore struct {
+	pool *redis.Pool
+}
+
+// newRedisCasTicketStore returns the store for the endpoint, which has the same format as the one of the Beego
+// session: "address,poolSize,password,dbNum"
+func newRedisCasTicketStore(endpoint string) *redisCasTicketStore {
+	return &redisCasTicketStore{pool: newRedisPool(endpoint)}
+}
+
+func (store *redisCasTicketStore) AddTicket(ticket *CasTicket) error {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return err
+	}
+
+	expireTime, err := time.Parse(time.RFC3339, ticket.ExpireTime)
+	if err != nil {
+		return err
+	}
+
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("SET", casTicketRedisKeyPrefix+ticket.Name, data, "EX", getRedisExpireSeconds(expireTime))
+	return err
+}
+
+func (store *redisCasTicketStore) GetTicket(name string) (*CasTicket, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", casTicketRedisKeyPrefix+name))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalCasTicket(data)
+}
+
+// ConsumeTicket gets and deletes the ticket with a script, so only one of the concurrent callers gets it
+func (store *redisCasTicketStore) ConsumeTicket(name string) (*CasTicket, error) {
+	data, err := consumeRedisKey(store.pool, casTicketRedisKeyPrefix+name)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return unmarshalCasTicket(data)
+}
+
+func (store *redisCasTicketStore) PurgeExpiredTickets(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func unmarshalCasTicket(data []byte) (*CasTicket, error) {
+	ticket := CasTicket{}
+	err := json.Unmarshal(data, &ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
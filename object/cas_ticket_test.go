@@ -0,0 +1,138 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+)
+
+type memoryCasTicketStore struct {
+	tickets sync.Map
+}
+
+func (store *memoryCasTicketStore) AddTicket(ticket *CasTicket) error {
+	store.tickets.Store(ticket.Name, ticket)
+	return nil
+}
+
+func (store *memoryCasTicketStore) GetTicket(name string) (*CasTicket, error) {
+	if value, ok := store.tickets.Load(name); ok {
+		return value.(*CasTicket), nil
+	}
+	return nil, nil
+}
+
+func (store *memoryCasTicketStore) ConsumeTicket(name string) (*CasTicket, error) {
+	if value, ok := store.tickets.LoadAndDelete(name); ok {
+		return value.(*CasTicket), nil
+	}
+	return nil, nil
+}
+
+func (store *memoryCasTicketStore) PurgeExpiredTickets(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+// testConcurrentConsume adds a ticket to the store and consumes it from many goroutines at the same time
+func testConcurrentConsume(t *testing.T, store CasTicketStore) {
+	ticket := newCasTicket(CasTicketTypeService, "ST-concurrent-"+t.Name(), &CasAuthenticationSuccess{User: "alice"}, "https://app.example.com", "built-in/alice", true)
+	err := store.AddTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testConsumeOnce(t, "the ticket", func() (bool, error) {
+		res, err := store.ConsumeTicket(ticket.Name)
+		return res != nil, err
+	})
+}
+
+func TestDatabaseCasTicketStore(t *testing.T) {
+	initTestOrmer(t, &CasTicket{})
+	store := &databaseCasTicketStore{}
+
+	response := &CasAuthenticationSuccess{User: "alice", Attributes: &CasAttributes{UserAttributes: &CasUserAttributes{Attributes: []*CasNamedAttribute{{Name: "email", Value: "alice@example.com"}}}}}
+	ticket := newCasTicket(CasTicketTypeService, "ST-round-trip", response, "https://app.example.com", "built-in/alice", true)
+	err := store.AddTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := store.GetTicket(ticket.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res == nil || res.Service != ticket.Service || res.UserId != ticket.UserId || !res.IsFromNewLogin || res.ExpireTime != ticket.ExpireTime {
+		t.Fatalf("the ticket should be read back: %v", res)
+	}
+	if res.Response == nil || res.Response.User != "alice" || len(res.Response.Attributes.UserAttributes.Attributes) != 1 {
+		t.Errorf("the response of the ticket should be read back: %v", res.Response)
+	}
+
+	testConcurrentConsume(t, store)
+}
+
+func TestRedisCasTicketStore(t *testing.T) {
+	endpoint := conf.GetConfigString("redisEndpoint")
+	if endpoint == "" {
+		t.Skip("redisEndpoint is not configured")
+	}
+
+	testConcurrentConsume(t, newRedisCasTicketStore(endpoint))
+}
+
+func TestCasTickets(t *testing.T) {
+	casTicketStore = &memoryCasTicketStore{}
+	response := &CasAuthenticationSuccess{User: "alice"}
+
+	st, err := addCasTicket(CasTicketTypeService, response, "https://app.example.com", "built-in/alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, res, service, userId, err := GetCasTokenByTicket(st, true)
+	if err != nil || !ok || res.User != "alice" || service != "https://app.example.com" || userId != "built-in/alice" {
+		t.Fatalf("the service ticket should be validated: %v", err)
+	}
+	if ok, _, _, _, _ = GetCasTokenByTicket(st, false); ok {
+		t.Errorf("the service ticket should only be validated once")
+	}
+
+	// renew requires the ticket to be issued from a new login
+	st, _ = addCasTicket(CasTicketTypeService, response, "https://app.example.com", "built-in/alice", false)
+	if ok, _, _, _, _ = GetCasTokenByTicket(st, true); ok {
+		t.Errorf("the service ticket from the single sign-on session should not be validated with renew")
+	}
+
+	pgt, _ := StoreCasTokenForPgt(response, "https://app.example.com", "built-in/alice")
+	if ok, _, _, _, _ = GetCasTokenByTicket(pgt, false); ok {
+		t.Errorf("the proxy-granting ticket should not be validated as a service ticket")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _, _, _, _ = GetCasTokenByPgt(pgt); !ok {
+			t.Errorf("the proxy-granting ticket should be usable until it expires")
+		}
+	}
+
+	pt, _ := StoreCasTokenForProxyTicket(response, "https://backend.example.com", "built-in/alice")
+	expiredTicket, _ := casTicketStore.GetTicket(pt)
+	expiredTicket.ExpireTime = time.Now().Add(-time.Second).Format(time.RFC3339)
+	if ok, _, _, _, _ = GetCasTokenByTicket(pt, false); ok {
+		t.Errorf("the expired proxy ticket should not be validated")
+	}
+}
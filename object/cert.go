@@ -35,6 +35,14 @@ type Cert struct {
 
 	Certificate string `xorm:"mediumtext" json:"certificate"`
 	PrivateKey  string `xorm:"mediumtext" json:"privateKey"`
+
+	EnableRotation   bool   `json:"enableRotation"`
+	RotationInterval int    `json:"rotationInterval"`
+	LastRotatedTime  string `xorm:"varchar(100)" json:"lastRotatedTime"`
+	KeyId            string `xorm:"varchar(100)" json:"keyId"`
+	NextKeyId        string `xorm:"varchar(100)" json:"nextKeyId"`
+	NextCertificate  string `xorm:"mediumtext" json:"nextCertificate"`
+	NextPrivateKey   string `xorm:"mediumtext" json:"nextPrivateKey"`
 }
 
 func GetMaskedCert(cert *Cert) *Cert {
@@ -169,6 +177,11 @@ func UpdateCert(id string, cert *Cert) (bool, error) {
 		return false, err
 	}
 
+	err = cert.populateNextKey()
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(cert)
 	if err != nil {
 		return false, err
@@ -183,6 +196,11 @@ func AddCert(cert *Cert) (bool, error) {
 		return false, err
 	}
 
+	err = cert.populateNextKey()
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(cert)
 	if err != nil {
 		return false, err
@@ -209,9 +227,25 @@ func (p *Cert) populateContent() error {
 		return nil
 	}
 
+	certificate, privateKey, err := p.generateKeys()
+	if err != nil {
+		return err
+	}
+
+	p.Certificate = certificate
+	p.PrivateKey = privateKey
+
+	// the successor must use the same algorithm as the regenerated key
+	p.NextKeyId = ""
+	p.NextCertificate = ""
+	p.NextPrivateKey = ""
+	return nil
+}
+
+func (p *Cert) generateKeys() (string, string, error) {
 	if len(p.CryptoAlgorithm) < 3 {
 		err := fmt.Errorf("populateContent() error, unsupported crypto algorithm: %s", p.CryptoAlgorithm)
-		return err
+		return "", "", err
 	}
 
 	if p.CryptoAlgorithm == "RSA" {
@@ -221,7 +255,7 @@ func (p *Cert) populateContent() error {
 	sigAlgorithm := p.CryptoAlgorithm[:2]
 	shaSize, err := util.ParseIntWithError(p.CryptoAlgorithm[2:])
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	var certificate, privateKey string
@@ -235,12 +269,10 @@ func (p *Cert) populateContent() error {
 		err = fmt.Errorf("populateContent() error, unsupported signature algorithm: %s", sigAlgorithm)
 	}
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	p.Certificate = certificate
-	p.PrivateKey = privateKey
-	return nil
+	return certificate, privateKey, nil
 }
 
 func getCertByApplication(application *Application) (*Cert, error) {
@@ -271,5 +303,12 @@ func certChangeTrigger(oldName string, newName string) error {
 		return err
 	}
 
+	certRotation := new(CertRotation)
+	certRotation.Cert = newName
+	_, err = session.Where("cert=?", oldName).Update(certRotation)
+	if err != nil {
+		return err
+	}
+
 	return session.Commit()
 }
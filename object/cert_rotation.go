@@ -0,0 +1,283 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+// a retired key is still published and accepted for a while, so that the tokens signed with it can expire
+const retiredKeyRetention = 30 * 24 * time.Hour
+
+const certRotationCheckInterval = time.Hour
+
+// CertRotation records a rotation of a cert, it keeps the certificate of the retired key for the verification
+type CertRotation struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100) index" json:"createdTime"`
+
+	Cert            string `xorm:"varchar(100) index" json:"cert"`
+	CryptoAlgorithm string `xorm:"varchar(100)" json:"cryptoAlgorithm"`
+	KeyId           string `xorm:"varchar(100)" json:"keyId"`
+	NextKeyId       string `xorm:"varchar(100)" json:"nextKeyId"`
+	Certificate     string `xorm:"mediumtext" json:"certificate"`
+}
+
+func GetCertRotations(owner string, cert string) ([]*CertRotation, error) {
+	rotations := []*CertRotation{}
+	err := ormer.Engine.Desc("created_time").Find(&rotations, &CertRotation{Owner: owner, Cert: cert})
+	if err != nil {
+		return rotations, err
+	}
+
+	return rotations, nil
+}
+
+// getRetiredCertRotations returns the rotations of the cert whose retired keys are still accepted
+func getRetiredCertRotations(cert *Cert) ([]*CertRotation, error) {
+	rotations := []*CertRotation{}
+	since := time.Now().Add(-retiredKeyRetention).Format(time.RFC3339)
+	err := ormer.Engine.Where("owner = ? and cert = ? and created_time > ?", cert.Owner, cert.Name, since).Desc("created_time").Find(&rotations)
+	if err != nil {
+		return rotations, err
+	}
+
+	return rotations, nil
+}
+
+// GetKeyId returns the kid of the current key of the cert, a cert that has never been rotated uses its name
+func (p *Cert) GetKeyId() string {
+	if p.KeyId == "" {
+		return p.Name
+	}
+	return p.KeyId
+}
+
+func (p *Cert) generateKeyId() string {
+	return fmt.Sprintf("%s-%s", p.Name, util.GenerateTimeId())
+}
+
+// populateNextKey generates the successor of the current key, it's published before the rotation so that
+// the relying parties have cached it by the time it signs the tokens
+func (p *Cert) populateNextKey() error {
+	if !p.EnableRotation || p.NextCertificate != "" {
+		return nil
+	}
+
+	certificate, privateKey, err := p.generateKeys()
+	if err != nil {
+		return err
+	}
+
+	p.NextKeyId = p.generateKeyId()
+	p.NextCertificate = certificate
+	p.NextPrivateKey = privateKey
+	return nil
+}
+
+// getPublicCertificate returns the certificate of the key with the kid, which can be the current key, the next key
+// or a recently retired key. The current key is used for the tokens without a known kid
+func (p *Cert) getPublicCertificate(kid string) (string, error) {
+	if kid == "" || kid == p.GetKeyId() {
+		return p.Certificate, nil
+	}
+	if kid == p.NextKeyId && p.NextCertificate != "" {
+		return p.NextCertificate, nil
+	}
+
+	rotations, err := getRetiredCertRotations(p)
+	if err != nil {
+		return "", err
+	}
+	for _, rotation := range rotations {
+		if rotation.KeyId == kid {
+			return rotation.Certificate, nil
+		}
+	}
+
+	return p.Certificate, nil
+}
+
+// getNextRotationTime returns when the cert is due for rotation, after the rotation interval (in days) since the
+// last rotation, or before the current certificate expires if no interval is configured
+func (p *Cert) getNextRotationTime() (time.Time, error) {
+	if p.RotationInterval > 0 {
+		lastRotatedTime := p.LastRotatedTime
+		if lastRotatedTime == "" {
+			lastRotatedTime = p.CreatedTime
+		}
+
+		t, err := time.Parse(time.RFC3339, lastRotatedTime)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.Add(time.Duration(p.RotationInterval) * 24 * time.Hour), nil
+	}
+
+	block, _ := pem.Decode([]byte(p.Certificate))
+	if block == nil {
+		return time.Time{}, fmt.Errorf("failed to decode the certificate of the cert: %s", p.GetId())
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// the retired key must stay valid while it's still accepted
+	return certificate.NotAfter.Add(-retiredKeyRetention), nil
+}
+
+// RotateCert promotes the next key of the cert to the current key and retires the current one
+func RotateCert(cert *Cert) (bool, error) {
+	if cert.Type != "x509" {
+		return false, fmt.Errorf("only the x509 certs can be rotated, the type of the cert: %s is %s", cert.GetId(), cert.Type)
+	}
+
+	if cert.NextCertificate == "" {
+		certificate, privateKey, err := cert.generateKeys()
+		if err != nil {
+			return false, err
+		}
+
+		cert.NextKeyId = cert.generateKeyId()
+		cert.NextCertificate = certificate
+		cert.NextPrivateKey = privateKey
+	}
+
+	oldKeyId := cert.KeyId
+	rotation := &CertRotation{
+		Owner:           cert.Owner,
+		Name:            util.GenerateId(),
+		CreatedTime:     util.GetCurrentTime(),
+		Cert:            cert.Name,
+		CryptoAlgorithm: cert.CryptoAlgorithm,
+		KeyId:           cert.GetKeyId(),
+		NextKeyId:       cert.NextKeyId,
+		Certificate:     cert.Certificate,
+	}
+
+	cert.KeyId = cert.NextKeyId
+	cert.Certificate = cert.NextCertificate
+	cert.PrivateKey = cert.NextPrivateKey
+	cert.LastRotatedTime = rotation.CreatedTime
+	cert.NextKeyId = ""
+	cert.NextCertificate = ""
+	cert.NextPrivateKey = ""
+
+	err := cert.populateNextKey()
+	if err != nil {
+		return false, err
+	}
+
+	session := ormer.Engine.NewSession()
+	defer session.Close()
+
+	err = session.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	// another instance may have rotated the cert in the meantime, the column is null for the certs added before
+	// the rotation was supported
+	query := session.ID(core.PK{cert.Owner, cert.Name})
+	if oldKeyId == "" {
+		query = query.Where("key_id = ? or key_id is null", "")
+	} else {
+		query = query.Where("key_id = ?", oldKeyId)
+	}
+	affected, err := query.Cols("key_id", "certificate", "private_key", "last_rotated_time", "next_key_id", "next_certificate", "next_private_key").Update(cert)
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, session.Rollback()
+	}
+
+	_, err = session.Insert(rotation)
+	if err != nil {
+		return false, err
+	}
+
+	return true, session.Commit()
+}
+
+// rotateCertIfDue publishes the next key of the cert if it's missing, or rotates the cert once it's due. The next
+// key is never promoted in the pass that generates it, as it hasn't been published yet
+func rotateCertIfDue(cert *Cert) error {
+	if cert.NextCertificate == "" {
+		err := cert.populateNextKey()
+		if err != nil {
+			return err
+		}
+
+		_, err = ormer.Engine.ID(core.PK{cert.Owner, cert.Name}).Where("next_key_id = ? or next_key_id is null", "").
+			Cols("next_key_id", "next_certificate", "next_private_key").Update(cert)
+		return err
+	}
+
+	nextRotationTime, err := cert.getNextRotationTime()
+	if err != nil {
+		return err
+	}
+	if time.Now().Before(nextRotationTime) {
+		return nil
+	}
+
+	_, err = RotateCert(cert)
+	return err
+}
+
+func rotateDueCerts() error {
+	certs, err := GetGlobalCerts()
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range certs {
+		if !cert.EnableRotation || cert.Type != "x509" || cert.Certificate == "" {
+			continue
+		}
+
+		err = rotateCertIfDue(cert)
+		if err != nil {
+			logs.Error(fmt.Sprintf("rotateCertIfDue() error for the cert: %s, %s", cert.GetId(), err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// RunCertRotationJob rotates the certs with the rotation enabled once they're due
+func RunCertRotationJob() {
+	ticker := time.NewTicker(certRotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		err := rotateDueCerts()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunCertRotationJob() error: %s", err.Error()))
+		}
+
+		<-ticker.C
+	}
+}
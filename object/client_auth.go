@@ -0,0 +1,501 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	TokenEndpointAuthMethodPrivateKeyJwt           = "private_key_jwt"
+	TokenEndpointAuthMethodTlsClientAuth           = "tls_client_auth"
+	TokenEndpointAuthMethodSelfSignedTlsClientAuth = "self_signed_tls_client_auth"
+
+	ClientAssertionTypeJwtBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	// the JWKS fetched from a jwks_uri is reused for this long
+	jwksUriCacheSeconds = 300
+	// a client assertion should not be valid for longer than this
+	clientAssertionMaxAgeSeconds = 600
+
+	clientAssertionJtiOwner = "client_assertion_jti"
+)
+
+// ClientAuthentication is the result of authenticating a client at the token endpoint. ClientSecret is the secret
+// that the grants compare against, so it is the application's own secret when the client has proved its identity
+// in another way. CertThumbprint is set when the client has authenticated with its TLS certificate
+type ClientAuthentication struct {
+	Application    *Application
+	ClientId       string
+	ClientSecret   string
+	CertThumbprint string
+}
+
+type jwksUriCacheItem struct {
+	jwks      *jose.JSONWebKeySet
+	fetchTime time.Time
+}
+
+var (
+	jwksUriCacheLock sync.Mutex
+	jwksUriCache     = map[string]*jwksUriCacheItem{}
+
+	clientCertCaPool     *x509.CertPool
+	clientCertCaPoolErr  error
+	clientCertCaPoolOnce sync.Once
+)
+
+func isClientAssertionSigningMethod(method jwt.SigningMethod) bool {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		return true
+	default:
+		return false
+	}
+}
+
+func getTokenEndpointAuthSigningAlgs() []string {
+	return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+}
+
+func fetchJwks(jwksUri string) (*jose.JSONWebKeySet, error) {
+	jwksUriCacheLock.Lock()
+	item, ok := jwksUriCache[jwksUri]
+	jwksUriCacheLock.Unlock()
+	if ok && time.Since(item.fetchTime) < jwksUriCacheSeconds*time.Second {
+		return item.jwks, nil
+	}
+
+	resp, err := proxy.DefaultHttpClient.Get(jwksUri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the JWKS from: %s, status: %s", jwksUri, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &jose.JSONWebKeySet{}
+	err = json.Unmarshal(data, jwks)
+	if err != nil {
+		return nil, fmt.Errorf("the JWKS from: %s is invalid: %s", jwksUri, err.Error())
+	}
+
+	jwksUriCacheLock.Lock()
+	jwksUriCache[jwksUri] = &jwksUriCacheItem{jwks: jwks, fetchTime: time.Now()}
+	jwksUriCacheLock.Unlock()
+	return jwks, nil
+}
+
+// getApplicationJwks returns the registered JWKS of the application, or the one published at its JWKS URI
+func getApplicationJwks(application *Application) (*jose.JSONWebKeySet, error) {
+	if application.Jwks != "" {
+		jwks := &jose.JSONWebKeySet{}
+		err := json.Unmarshal([]byte(application.Jwks), jwks)
+		if err != nil {
+			return nil, fmt.Errorf("the JWKS of the application: %s is invalid: %s", application.GetId(), err.Error())
+		}
+		return jwks, nil
+	}
+
+	if application.JwksUri != "" {
+		return fetchJwks(application.JwksUri)
+	}
+
+	return nil, fmt.Errorf("the application: %s has no registered JWKS", application.GetId())
+}
+
+// getJwksSigningKey returns the public signing key with the given key id, or the first one if kid is empty
+func getJwksSigningKey(jwks *jose.JSONWebKeySet, kid string) interface{} {
+	keys := jwks.Keys
+	if kid != "" {
+		keys = jwks.Key(kid)
+	}
+
+	for _, key := range keys {
+		if key.Use == "" || key.Use == "sig" {
+			return key.Key
+		}
+	}
+	return nil
+}
+
+// isClientCertProxy reports whether the request comes from one of the proxies configured by
+// "clientCertTrustedProxies", a comma-separated list of IP addresses and CIDR ranges
+func isClientCertProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, trustedProxy := range strings.Split(conf.GetConfigString("clientCertTrustedProxies"), ",") {
+		trustedProxy = strings.TrimSpace(trustedProxy)
+		if trustedProxy == "" {
+			continue
+		}
+
+		if _, ipNet, err := net.ParseCIDR(trustedProxy); err == nil {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		} else if proxyIp := net.ParseIP(trustedProxy); proxyIp != nil && proxyIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientCertificates returns the TLS client certificate of the request followed by its intermediate certificates.
+// When Casdoor runs behind a TLS-terminating proxy, the certificates are read from the header configured by
+// "clientCertHeader", which should hold the URL-encoded PEM certificates. The header is only read from the proxies
+// configured by "clientCertTrustedProxies", anyone else could send it
+func GetClientCertificates(req *http.Request) []*x509.Certificate {
+	if req.TLS != nil && len(req.TLS.PeerCertificates) != 0 {
+		return req.TLS.PeerCertificates
+	}
+
+	header := conf.GetConfigString("clientCertHeader")
+	if header == "" || !isClientCertProxy(req.RemoteAddr) {
+		return nil
+	}
+
+	value := req.Header.Get(header)
+	if value == "" {
+		return nil
+	}
+
+	value, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil
+	}
+
+	certificates := []*x509.Certificate{}
+	rest := []byte(value)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil
+	}
+	return certificates
+}
+
+// GetClientCertificate returns the TLS client certificate of the request
+func GetClientCertificate(req *http.Request) *x509.Certificate {
+	certificates := GetClientCertificates(req)
+	if len(certificates) == 0 {
+		return nil
+	}
+	return certificates[0]
+}
+
+// getClientCertCaPool returns the CA certificates configured by "clientCertCaFile", which the certificates of the
+// tls_client_auth clients must chain to. Nil is returned when it's not configured
+func getClientCertCaPool() (*x509.CertPool, error) {
+	clientCertCaPoolOnce.Do(func() {
+		caFile := conf.GetConfigString("clientCertCaFile")
+		if caFile == "" {
+			return
+		}
+
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			clientCertCaPoolErr = err
+			return
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			clientCertCaPoolErr = fmt.Errorf("clientCertCaFile: %s contains no PEM certificate", caFile)
+			return
+		}
+		clientCertCaPool = pool
+	})
+	return clientCertCaPool, clientCertCaPoolErr
+}
+
+// GetCertThumbprint returns the x5t#S256 value of a certificate (RFC 8705 section 3.1)
+func GetCertThumbprint(certificate *x509.Certificate) string {
+	if certificate == nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// checkClientAssertionJtiReplay records the jti until the expire time and reports whether it has already been used by
+// the client, the jti is kept in the shared store so an assertion can't be replayed on another instance
+func checkClientAssertionJtiReplay(clientId string, jti string, expireTime time.Time) (bool, error) {
+	sum := sha256.Sum256([]byte(clientId + "/" + jti))
+	added, err := addSharedEntry(clientAssertionJtiOwner, hex.EncodeToString(sum[:]), "", expireTime)
+	if err != nil {
+		return false, err
+	}
+	return !added, nil
+}
+
+// verifyClientAssertion checks a client assertion JWT (RFC 7523 section 3) against the JWKS of the application,
+// the audience can be the issuer, the token endpoint or the endpoint the assertion is sent to
+func verifyClientAssertion(application *Application, clientAssertion string, host string, path string) (*jwt.RegisteredClaims, error) {
+	claims := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(clientAssertion, &claims, func(token *jwt.Token) (interface{}, error) {
+		if !isClientAssertionSigningMethod(token.Method) {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return getApplicationJwk(application, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != application.ClientId || claims.Subject != application.ClientId {
+		return nil, fmt.Errorf("the iss and sub of the client assertion should be the client id")
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("the exp of the client assertion should not be empty")
+	}
+	if time.Until(claims.ExpiresAt.Time) > clientAssertionMaxAgeSeconds*time.Second {
+		return nil, fmt.Errorf("the exp of the client assertion is too far in the future")
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	audiences := []string{originBackend, fmt.Sprintf("%s/api/login/oauth/access_token", originBackend), originBackend + path}
+	isAudienceValid := false
+	for _, audience := range audiences {
+		if claims.VerifyAudience(audience, true) {
+			isAudienceValid = true
+			break
+		}
+	}
+	if !isAudienceValid {
+		return nil, fmt.Errorf("the aud of the client assertion should be: %s", audiences[1])
+	}
+
+	if claims.ID == "" {
+		return nil, fmt.Errorf("the jti of the client assertion should not be empty")
+	}
+
+	return &claims, nil
+}
+
+// verifyClientCertificate checks the TLS client certificate of the application (RFC 8705 section 2): by its chain to
+// the configured CA certificates and its subject DN for tls_client_auth, or by its public key being one of the
+// registered JWKS for self_signed_tls_client_auth. The certificates are the client certificate followed by its
+// intermediate certificates
+func verifyClientCertificate(application *Application, certificates []*x509.Certificate) error {
+	if len(certificates) == 0 {
+		return fmt.Errorf("the client certificate is required")
+	}
+	certificate := certificates[0]
+
+	if application.TokenEndpointAuthMethod == TokenEndpointAuthMethodTlsClientAuth {
+		pool, err := getClientCertCaPool()
+		if err != nil {
+			return err
+		}
+		if pool == nil {
+			return fmt.Errorf("tls_client_auth is not available, clientCertCaFile is not configured")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, intermediate := range certificates[1:] {
+			intermediates.AddCert(intermediate)
+		}
+		_, err = certificate.Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		if err != nil {
+			return fmt.Errorf("the client certificate is not trusted: %s", err.Error())
+		}
+
+		if application.TlsClientAuthSubjectDn == "" || certificate.Subject.String() != application.TlsClientAuthSubjectDn {
+			return fmt.Errorf("the subject DN of the client certificate doesn't match the application")
+		}
+		return nil
+	}
+
+	jwks, err := getApplicationJwks(application)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := certificate.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return fmt.Errorf("the public key of the client certificate is not supported")
+	}
+
+	for _, key := range jwks.Keys {
+		if publicKey.Equal(key.Key) {
+			return nil
+		}
+		for _, x5c := range key.Certificates {
+			if x5c.Equal(certificate) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the client certificate doesn't match any key in the JWKS of the application")
+}
+
+// AuthenticateClient authenticates the client of a request to the token endpoint (or the introspection, revocation and
+// PAR endpoints) by its client assertion or TLS certificate. Clients that use a client secret are checked by the
+// grants themselves, but an application that declares a stronger method cannot fall back to its secret
+func AuthenticateClient(clientId string, clientSecret string, clientAssertionType string, clientAssertion string, certificates []*x509.Certificate, host string, path string) (*ClientAuthentication, *TokenError, error) {
+	if clientAssertion != "" || clientAssertionType != "" {
+		if clientAssertionType != ClientAssertionTypeJwtBearer {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: fmt.Sprintf("client_assertion_type should be: %s", ClientAssertionTypeJwtBearer),
+			}, nil
+		}
+
+		// the client id is taken from the assertion when it is not sent along
+		claims := jwt.RegisteredClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(clientAssertion, &claims)
+		if err != nil {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: fmt.Sprintf("client_assertion is invalid: %s", err.Error()),
+			}, nil
+		}
+		if clientId == "" {
+			clientId = claims.Subject
+		}
+		if clientId != claims.Subject {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: "the sub of the client assertion doesn't match client_id",
+			}, nil
+		}
+	}
+
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		// the grants respond to an unknown client
+		return &ClientAuthentication{ClientId: clientId, ClientSecret: clientSecret}, nil, nil
+	}
+
+	auth := &ClientAuthentication{
+		Application:  application,
+		ClientId:     clientId,
+		ClientSecret: application.ClientSecret,
+	}
+
+	switch application.TokenEndpointAuthMethod {
+	case TokenEndpointAuthMethodPrivateKeyJwt:
+		if clientAssertion == "" {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: "the application should authenticate with client_assertion",
+			}, nil
+		}
+
+		claims, err := verifyClientAssertion(application, clientAssertion, host, path)
+		if err != nil {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: fmt.Sprintf("client_assertion is invalid: %s", err.Error()),
+			}, nil
+		}
+
+		isReplayed, err := checkClientAssertionJtiReplay(application.ClientId, claims.ID, claims.ExpiresAt.Time)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isReplayed {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: "client_assertion has already been used",
+			}, nil
+		}
+	case TokenEndpointAuthMethodTlsClientAuth, TokenEndpointAuthMethodSelfSignedTlsClientAuth:
+		if clientAssertion != "" {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: "the application should authenticate with its TLS client certificate",
+			}, nil
+		}
+
+		err = verifyClientCertificate(application, certificates)
+		if err != nil {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: err.Error(),
+			}, nil
+		}
+		auth.CertThumbprint = GetCertThumbprint(certificates[0])
+	default:
+		if clientAssertion != "" {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: "the application doesn't authenticate with client_assertion",
+			}, nil
+		}
+		auth.ClientSecret = clientSecret
+	}
+
+	return auth, nil, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, subject string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: subject},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	data, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
+
+func TestVerifyClientCertificate(t *testing.T) {
+	ca, caKey := newTestCertificate(t, "Test CA", nil, nil)
+	certificate, _ := newTestCertificate(t, "client-mtls", ca, caKey)
+	selfSigned, _ := newTestCertificate(t, "client-mtls", nil, nil)
+	application := &Application{Owner: "admin", Name: "app-mtls", TokenEndpointAuthMethod: TokenEndpointAuthMethodTlsClientAuth, TlsClientAuthSubjectDn: "CN=client-mtls"}
+
+	if err := verifyClientCertificate(application, []*x509.Certificate{certificate}); err == nil {
+		t.Errorf("tls_client_auth should be refused without the CA certificates")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	clientCertCaPool = pool
+	t.Cleanup(func() {
+		clientCertCaPool = nil
+	})
+
+	if err := verifyClientCertificate(application, []*x509.Certificate{certificate}); err != nil {
+		t.Errorf("the certificate issued by the CA should be accepted: %v", err)
+	}
+	if err := verifyClientCertificate(application, []*x509.Certificate{selfSigned}); err == nil {
+		t.Errorf("a certificate not issued by the CA should be refused even with the same subject DN")
+	}
+}
+
+func TestGetClientCertificates(t *testing.T) {
+	certificate, _ := newTestCertificate(t, "client-mtls", nil, nil)
+	value := url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})))
+	t.Setenv("clientCertHeader", "X-Client-Cert")
+	t.Setenv("clientCertTrustedProxies", "10.0.0.1, 192.168.0.0/16")
+
+	for remoteAddr, trusted := range map[string]bool{"10.0.0.1:1234": true, "192.168.3.4:1234": true, "10.0.0.2:1234": false} {
+		req := httptest.NewRequest("POST", "/api/login/oauth/access_token", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Client-Cert", value)
+
+		certificates := GetClientCertificates(req)
+		if (len(certificates) == 1) != trusted {
+			t.Errorf("the certificate header from: %s should be read: %v, got: %d certificates", remoteAddr, trusted, len(certificates))
+		}
+	}
+}
@@ -0,0 +1,712 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	InvalidRedirectUri    = "invalid_redirect_uri"
+	InvalidClientMetadata = "invalid_client_metadata"
+	InvalidToken          = "invalid_token"
+
+	ImplicitGrantType = "implicit"
+
+	TokenEndpointAuthMethodNone              = "none"
+	TokenEndpointAuthMethodClientSecretBasic = "client_secret_basic"
+	TokenEndpointAuthMethodClientSecretPost  = "client_secret_post"
+)
+
+// ClientMetadata is the client metadata of RFC 7591 section 2
+type ClientMetadata struct {
+	RedirectUris            []string            `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string              `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string            `json:"grant_types,omitempty"`
+	ResponseTypes           []string            `json:"response_types,omitempty"`
+	ClientName              string              `json:"client_name,omitempty"`
+	ClientUri               string              `json:"client_uri,omitempty"`
+	LogoUri                 string              `json:"logo_uri,omitempty"`
+	TosUri                  string              `json:"tos_uri,omitempty"`
+	Jwks                    *jose.JSONWebKeySet `json:"jwks,omitempty"`
+	JwksUri                 string              `json:"jwks_uri,omitempty"`
+	TlsClientAuthSubjectDn  string              `json:"tls_client_auth_subject_dn,omitempty"`
+	BackchannelLogoutUri    string              `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutUri   string              `json:"frontchannel_logout_uri,omitempty"`
+	PostLogoutRedirectUris  []string            `json:"post_logout_redirect_uris,omitempty"`
+
+	BackchannelTokenDeliveryMode          string `json:"backchannel_token_delivery_mode,omitempty"`
+	BackchannelClientNotificationEndpoint string `json:"backchannel_client_notification_endpoint,omitempty"`
+	IdTokenEncryptedResponseAlg           string `json:"id_token_encrypted_response_alg,omitempty"`
+	IdTokenEncryptedResponseEnc           string `json:"id_token_encrypted_response_enc,omitempty"`
+	UserinfoSignedResponseAlg             string `json:"userinfo_signed_response_alg,omitempty"`
+	UserinfoEncryptedResponseAlg          string `json:"userinfo_encrypted_response_alg,omitempty"`
+	UserinfoEncryptedResponseEnc          string `json:"userinfo_encrypted_response_enc,omitempty"`
+}
+
+// ClientInformationResponse is the response of RFC 7591 section 3.2.1 and RFC 7592 section 3
+type ClientInformationResponse struct {
+	ClientId                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIdIssuedAt        int64  `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientUri   string `json:"registration_client_uri"`
+	ClientMetadata
+}
+
+func getTokenEndpointAuthMethods() []string {
+	return []string{TokenEndpointAuthMethodClientSecretBasic, TokenEndpointAuthMethodClientSecretPost, TokenEndpointAuthMethodNone, TokenEndpointAuthMethodPrivateKeyJwt, TokenEndpointAuthMethodTlsClientAuth, TokenEndpointAuthMethodSelfSignedTlsClientAuth}
+}
+
+// getApplicationGrantTypes maps the grant types of RFC 7591 to the ones stored in Application.GrantTypes
+func getApplicationGrantTypes(grantTypes []string) ([]string, error) {
+	res := []string{}
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case ImplicitGrantType:
+			res = append(res, "token", "id_token")
+		case "authorization_code", "password", "client_credentials", "refresh_token", DeviceCodeGrantType, TokenExchangeGrantType, JwtBearerGrantType, CibaGrantType:
+			res = append(res, grantType)
+		default:
+			return nil, fmt.Errorf("grant_type: %s is not supported", grantType)
+		}
+	}
+	return res, nil
+}
+
+// getPrivilegedGrantTypes returns the grant types that issue tokens without the user's interaction at Casdoor,
+// a self-registered client can only use them when an admin grants them
+func getPrivilegedGrantTypes() []string {
+	return []string{"password", "client_credentials", TokenExchangeGrantType, JwtBearerGrantType, CibaGrantType}
+}
+
+func getRegisteredGrantTypes(application *Application) []string {
+	res := []string{}
+	for _, grantType := range application.GrantTypes {
+		if grantType == "token" || grantType == "id_token" {
+			grantType = ImplicitGrantType
+		}
+		if !util.InSlice(res, grantType) {
+			res = append(res, grantType)
+		}
+	}
+	return res
+}
+
+func getRegisteredResponseTypes(application *Application) []string {
+	res := []string{}
+	if util.InSlice(application.GrantTypes, "authorization_code") {
+		res = append(res, "code")
+	}
+	if util.InSlice(application.GrantTypes, "token") {
+		res = append(res, "token")
+	}
+	if util.InSlice(application.GrantTypes, "id_token") {
+		res = append(res, "id_token")
+	}
+	return res
+}
+
+func isAbsoluteUri(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// checkRedirectUri allows https, http for the loopback interface and the private-use URI schemes of native apps,
+// per RFC 8252 section 7, so a client can't register a javascript: or data: URI as its redirect_uri
+func checkRedirectUri(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || strings.Contains(uri, "#") {
+		return fmt.Errorf("%s should be an absolute URI without fragment", uri)
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("%s should be an absolute URI without fragment", uri)
+		}
+	case "http":
+		hostname := u.Hostname()
+		ip := net.ParseIP(hostname)
+		if hostname != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("%s should use https, http is only allowed for the loopback interface", uri)
+		}
+	default:
+		// the private-use URI schemes are reverse domain names, per RFC 8252 section 7.1
+		if !strings.Contains(u.Scheme, ".") {
+			return fmt.Errorf("%s should use https or the reverse domain name scheme of a native app", uri)
+		}
+	}
+	return nil
+}
+
+func isInternalIp(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// checkOutboundUri checks a registered URI that Casdoor itself sends requests to, it must use https and must not
+// resolve to a loopback, link-local or private address, so a client can't make Casdoor reach its internal network
+func checkOutboundUri(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%s should be an absolute https URI", uri)
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("the host of %s can't be resolved: %s", uri, err.Error())
+	}
+	for _, ip := range ips {
+		if isInternalIp(ip) {
+			return fmt.Errorf("%s should not point to a loopback, link-local or private address", uri)
+		}
+	}
+	return nil
+}
+
+// checkClientMetadata validates the metadata and fills in the defaults of RFC 7591 section 2, the privileged grant
+// types are only allowed when they are in allowedGrantTypes
+func checkClientMetadata(metadata *ClientMetadata, allowedGrantTypes []string) *TokenError {
+	if len(metadata.GrantTypes) == 0 {
+		metadata.GrantTypes = []string{"authorization_code"}
+	}
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = TokenEndpointAuthMethodClientSecretBasic
+	}
+
+	if !util.InSlice(getTokenEndpointAuthMethods(), metadata.TokenEndpointAuthMethod) {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("token_endpoint_auth_method: %s is not supported", metadata.TokenEndpointAuthMethod),
+		}
+	}
+
+	if _, err := getApplicationGrantTypes(metadata.GrantTypes); err != nil {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}
+	}
+
+	for _, grantType := range metadata.GrantTypes {
+		if util.InSlice(getPrivilegedGrantTypes(), grantType) && !util.InSlice(allowedGrantTypes, grantType) {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: fmt.Sprintf("grant_type: %s requires the registration with an initial access token of an organization admin", grantType),
+			}
+		}
+	}
+
+	// response_types must be consistent with grant_types, per RFC 7591 section 2.1
+	for _, responseType := range metadata.ResponseTypes {
+		for _, item := range strings.Fields(responseType) {
+			grantType := ImplicitGrantType
+			if item == "code" {
+				grantType = "authorization_code"
+			} else if item != "token" && item != "id_token" {
+				return &TokenError{
+					Error:            InvalidClientMetadata,
+					ErrorDescription: fmt.Sprintf("response_type: %s is not supported", item),
+				}
+			}
+
+			if !util.InSlice(metadata.GrantTypes, grantType) {
+				return &TokenError{
+					Error:            InvalidClientMetadata,
+					ErrorDescription: fmt.Sprintf("response_type: %s requires the grant_type: %s", item, grantType),
+				}
+			}
+		}
+	}
+
+	needRedirectUri := util.InSlice(metadata.GrantTypes, "authorization_code") || util.InSlice(metadata.GrantTypes, ImplicitGrantType)
+	if needRedirectUri && len(metadata.RedirectUris) == 0 {
+		return &TokenError{
+			Error:            InvalidRedirectUri,
+			ErrorDescription: "redirect_uris should not be empty for the authorization_code and implicit grants",
+		}
+	}
+	for _, redirectUri := range metadata.RedirectUris {
+		if err := checkRedirectUri(redirectUri); err != nil {
+			return &TokenError{
+				Error:            InvalidRedirectUri,
+				ErrorDescription: fmt.Sprintf("redirect_uri: %s", err.Error()),
+			}
+		}
+	}
+
+	for _, redirectUri := range metadata.PostLogoutRedirectUris {
+		if err := checkRedirectUri(redirectUri); err != nil {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: fmt.Sprintf("post_logout_redirect_uri: %s", err.Error()),
+			}
+		}
+	}
+
+	for _, uri := range []string{metadata.ClientUri, metadata.LogoUri, metadata.TosUri, metadata.JwksUri, metadata.BackchannelLogoutUri, metadata.FrontchannelLogoutUri, metadata.BackchannelClientNotificationEndpoint} {
+		if uri != "" && !isAbsoluteUri(uri) {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: fmt.Sprintf("%s should be an absolute URI", uri),
+			}
+		}
+	}
+
+	for _, uri := range []string{metadata.JwksUri, metadata.BackchannelLogoutUri, metadata.BackchannelClientNotificationEndpoint} {
+		if uri == "" {
+			continue
+		}
+		if err := checkOutboundUri(uri); err != nil {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: err.Error(),
+			}
+		}
+	}
+
+	if metadata.Jwks != nil {
+		for _, key := range metadata.Jwks.Keys {
+			if !key.Valid() || !key.IsPublic() {
+				return &TokenError{
+					Error:            InvalidClientMetadata,
+					ErrorDescription: "jwks should only contain valid public keys",
+				}
+			}
+		}
+	}
+
+	if metadata.Jwks != nil && metadata.JwksUri != "" {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: "jwks and jwks_uri should not be both present",
+		}
+	}
+
+	switch metadata.BackchannelTokenDeliveryMode {
+	case "", CibaDeliveryModePoll:
+	case CibaDeliveryModePing:
+		if metadata.BackchannelClientNotificationEndpoint == "" {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: "backchannel_token_delivery_mode: ping requires backchannel_client_notification_endpoint",
+			}
+		}
+	default:
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("backchannel_token_delivery_mode: %s is not supported", metadata.BackchannelTokenDeliveryMode),
+		}
+	}
+
+	if tokenError := validateEncryptionMetadata(metadata, "id_token", metadata.IdTokenEncryptedResponseAlg, metadata.IdTokenEncryptedResponseEnc); tokenError != nil {
+		return tokenError
+	}
+	if tokenError := validateEncryptionMetadata(metadata, "userinfo", metadata.UserinfoEncryptedResponseAlg, metadata.UserinfoEncryptedResponseEnc); tokenError != nil {
+		return tokenError
+	}
+	if metadata.UserinfoSignedResponseAlg != "" && !util.InSlice(getUserinfoSigningAlgValuesSupported(), metadata.UserinfoSignedResponseAlg) {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("userinfo_signed_response_alg: %s is not supported", metadata.UserinfoSignedResponseAlg),
+		}
+	}
+
+	switch metadata.TokenEndpointAuthMethod {
+	case TokenEndpointAuthMethodPrivateKeyJwt, TokenEndpointAuthMethodSelfSignedTlsClientAuth:
+		if metadata.Jwks == nil && metadata.JwksUri == "" {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: fmt.Sprintf("token_endpoint_auth_method: %s requires jwks or jwks_uri", metadata.TokenEndpointAuthMethod),
+			}
+		}
+	case TokenEndpointAuthMethodTlsClientAuth:
+		if metadata.TlsClientAuthSubjectDn == "" {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: "token_endpoint_auth_method: tls_client_auth requires tls_client_auth_subject_dn",
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateEncryptionMetadata checks the *_encrypted_response_alg and *_encrypted_response_enc of a response,
+// the enc requires the alg and the encryption key is taken from the registered JWKS
+func validateEncryptionMetadata(metadata *ClientMetadata, response string, alg string, enc string) *TokenError {
+	if alg == "" {
+		if enc != "" {
+			return &TokenError{
+				Error:            InvalidClientMetadata,
+				ErrorDescription: fmt.Sprintf("%s_encrypted_response_enc requires %s_encrypted_response_alg", response, response),
+			}
+		}
+		return nil
+	}
+
+	if !util.InSlice(getEncryptionAlgValuesSupported(), alg) {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("%s_encrypted_response_alg: %s is not supported", response, alg),
+		}
+	}
+	if enc != "" && !util.InSlice(getEncryptionEncValuesSupported(), enc) {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("%s_encrypted_response_enc: %s is not supported", response, enc),
+		}
+	}
+	if metadata.Jwks == nil && metadata.JwksUri == "" {
+		return &TokenError{
+			Error:            InvalidClientMetadata,
+			ErrorDescription: fmt.Sprintf("%s_encrypted_response_alg requires jwks or jwks_uri", response),
+		}
+	}
+
+	return nil
+}
+
+// applyClientMetadata replaces the registered metadata of the application
+func applyClientMetadata(application *Application, metadata *ClientMetadata) error {
+	grantTypes, err := getApplicationGrantTypes(metadata.GrantTypes)
+	if err != nil {
+		return err
+	}
+
+	jwks := ""
+	if metadata.Jwks != nil {
+		jwks = util.StructToJson(metadata.Jwks)
+	}
+
+	application.RedirectUris = metadata.RedirectUris
+	application.GrantTypes = grantTypes
+	application.TokenEndpointAuthMethod = metadata.TokenEndpointAuthMethod
+	application.DisplayName = metadata.ClientName
+	if application.DisplayName == "" {
+		application.DisplayName = application.Name
+	}
+	application.HomepageUrl = metadata.ClientUri
+	application.Logo = metadata.LogoUri
+	application.TermsOfUse = metadata.TosUri
+	application.Jwks = jwks
+	application.JwksUri = metadata.JwksUri
+	application.TlsClientAuthSubjectDn = metadata.TlsClientAuthSubjectDn
+	application.BackchannelLogoutUri = metadata.BackchannelLogoutUri
+	application.FrontchannelLogoutUri = metadata.FrontchannelLogoutUri
+	application.PostLogoutRedirectUris = metadata.PostLogoutRedirectUris
+	application.BackchannelTokenDeliveryMode = metadata.BackchannelTokenDeliveryMode
+	application.ClientNotificationEndpoint = metadata.BackchannelClientNotificationEndpoint
+	application.IdTokenEncryptedResponseAlg = metadata.IdTokenEncryptedResponseAlg
+	application.IdTokenEncryptedResponseEnc = metadata.IdTokenEncryptedResponseEnc
+	application.UserinfoSignedResponseAlg = metadata.UserinfoSignedResponseAlg
+	application.UserinfoEncryptedResponseAlg = metadata.UserinfoEncryptedResponseAlg
+	application.UserinfoEncryptedResponseEnc = metadata.UserinfoEncryptedResponseEnc
+	return nil
+}
+
+func getClientInformationResponse(application *Application, registrationAccessToken string, host string) (*ClientInformationResponse, error) {
+	_, originBackend := getOriginFromHost(host)
+
+	metadata := ClientMetadata{
+		RedirectUris:            application.RedirectUris,
+		TokenEndpointAuthMethod: application.TokenEndpointAuthMethod,
+		GrantTypes:              getRegisteredGrantTypes(application),
+		ResponseTypes:           getRegisteredResponseTypes(application),
+		ClientName:              application.DisplayName,
+		ClientUri:               application.HomepageUrl,
+		LogoUri:                 application.Logo,
+		TosUri:                  application.TermsOfUse,
+		JwksUri:                 application.JwksUri,
+		TlsClientAuthSubjectDn:  application.TlsClientAuthSubjectDn,
+		BackchannelLogoutUri:    application.BackchannelLogoutUri,
+		FrontchannelLogoutUri:   application.FrontchannelLogoutUri,
+		PostLogoutRedirectUris:  application.PostLogoutRedirectUris,
+
+		BackchannelTokenDeliveryMode:          application.BackchannelTokenDeliveryMode,
+		BackchannelClientNotificationEndpoint: application.ClientNotificationEndpoint,
+		IdTokenEncryptedResponseAlg:           application.IdTokenEncryptedResponseAlg,
+		IdTokenEncryptedResponseEnc:           application.IdTokenEncryptedResponseEnc,
+		UserinfoSignedResponseAlg:             application.UserinfoSignedResponseAlg,
+		UserinfoEncryptedResponseAlg:          application.UserinfoEncryptedResponseAlg,
+		UserinfoEncryptedResponseEnc:          application.UserinfoEncryptedResponseEnc,
+	}
+	if application.Jwks != "" {
+		metadata.Jwks = &jose.JSONWebKeySet{}
+		err := util.JsonToStruct(application.Jwks, metadata.Jwks)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &ClientInformationResponse{
+		ClientId:                application.ClientId,
+		ClientSecretExpiresAt:   0,
+		RegistrationAccessToken: registrationAccessToken,
+		RegistrationClientUri:   fmt.Sprintf("%s/api/login/oauth/register/%s", originBackend, application.ClientId),
+		ClientMetadata:          metadata,
+	}
+	if application.TokenEndpointAuthMethod != TokenEndpointAuthMethodNone {
+		resp.ClientSecret = application.ClientSecret
+	}
+	if createdTime, err := time.Parse(time.RFC3339, application.CreatedTime); err == nil {
+		resp.ClientIdIssuedAt = createdTime.Unix()
+	}
+	return resp, nil
+}
+
+// getClientRegistrationOrganization returns the organization that the new client will belong to. With an
+// initial access token, it must be an access token of an organization admin, per RFC 7591 section 3.1. Without
+// one, open registration is only allowed into the organization configured by "clientRegistrationOrganization"
+// in app.conf
+func getClientRegistrationOrganization(initialAccessToken string) (string, *TokenError, error) {
+	if initialAccessToken == "" {
+		organization := conf.GetConfigString("clientRegistrationOrganization")
+		if organization == "" {
+			return "", &TokenError{
+				Error:            InvalidToken,
+				ErrorDescription: "an initial access token is required for client registration",
+			}, nil
+		}
+		return organization, nil, nil
+	}
+
+	token, err := GetTokenByAccessToken(initialAccessToken)
+	if err != nil {
+		return "", nil, err
+	}
+	if token == nil || token.ExpiresIn <= 0 {
+		return "", &TokenError{
+			Error:            InvalidToken,
+			ErrorDescription: "the initial access token is invalid or has been revoked",
+		}, nil
+	}
+
+	application, err := getApplication(token.Owner, token.Application)
+	if err != nil {
+		return "", nil, err
+	}
+	if application == nil {
+		return "", &TokenError{
+			Error:            InvalidToken,
+			ErrorDescription: "the application of the initial access token does not exist",
+		}, nil
+	}
+
+	_, err = ParseAccessToken(initialAccessToken, token, application)
+	if err != nil {
+		return "", &TokenError{
+			Error:            InvalidToken,
+			ErrorDescription: fmt.Sprintf("the initial access token is invalid: %s", err.Error()),
+		}, nil
+	}
+
+	// the client credentials grant issues tokens to the application itself, they are not issued by an admin
+	if token.User == application.Name && token.Organization == application.Organization {
+		return "", &TokenError{
+			Error:            InvalidToken,
+			ErrorDescription: "the initial access token should belong to an organization admin",
+		}, nil
+	}
+
+	user, err := getUser(token.Organization, token.User)
+	if err != nil {
+		return "", nil, err
+	}
+	if user == nil || !(user.IsAdmin || user.IsGlobalAdmin()) {
+		return "", &TokenError{
+			Error:            InvalidToken,
+			ErrorDescription: "the initial access token should belong to an organization admin",
+		}, nil
+	}
+
+	return user.Owner, nil, nil
+}
+
+// newRegisteredApplication creates an application in the organization with the sign-in settings of its default application
+func newRegisteredApplication(organization string) (*Application, error) {
+	application := &Application{
+		Owner:                "admin",
+		Name:                 fmt.Sprintf("application_%s", util.GetRandomName()),
+		CreatedTime:          util.GetCurrentTime(),
+		Organization:         organization,
+		Cert:                 "cert-built-in",
+		EnablePassword:       true,
+		EnableSignUp:         true,
+		Providers:            []*ProviderItem{},
+		SigninMethods:        []*SigninMethod{{Name: "Password", DisplayName: "Password", Rule: "All"}},
+		SignupItems:          []*SignupItem{},
+		SigninItems:          []*SigninItem{},
+		TokenFormat:          "JWT",
+		TokenFields:          []string{},
+		ExpireInHours:        168,
+		FormOffset:           2,
+		ClientId:             util.GenerateClientId(),
+		ClientSecret:         util.GenerateClientSecret(),
+		IsShared:             false,
+		RefreshExpireInHours: 168,
+	}
+
+	defaultApplication, err := GetDefaultApplication(util.GetId("admin", organization))
+	if err == nil && defaultApplication != nil {
+		application.Cert = defaultApplication.Cert
+		application.EnablePassword = defaultApplication.EnablePassword
+		application.EnableSignUp = defaultApplication.EnableSignUp
+		application.Providers = defaultApplication.Providers
+		application.SigninMethods = defaultApplication.SigninMethods
+		application.SignupItems = defaultApplication.SignupItems
+		application.SigninItems = defaultApplication.SigninItems
+		application.TokenFormat = defaultApplication.TokenFormat
+		application.TokenSigningMethod = defaultApplication.TokenSigningMethod
+		application.TokenFields = defaultApplication.TokenFields
+		application.ExpireInHours = defaultApplication.ExpireInHours
+		application.RefreshExpireInHours = defaultApplication.RefreshExpireInHours
+		application.ThemeData = defaultApplication.ThemeData
+		application.FormOffset = defaultApplication.FormOffset
+	}
+
+	return application, nil
+}
+
+// RegisterClient
+// Dynamic Client Registration, per RFC 7591 section 3
+func RegisterClient(metadata *ClientMetadata, initialAccessToken string, host string) (*ClientInformationResponse, *TokenError, error) {
+	organization, tokenError, err := getClientRegistrationOrganization(initialAccessToken)
+	if err != nil || tokenError != nil {
+		return nil, tokenError, err
+	}
+
+	organizationObj, err := getOrganization("admin", organization)
+	if err != nil {
+		return nil, nil, err
+	}
+	if organizationObj == nil {
+		return nil, nil, fmt.Errorf("the organization: %s for client registration does not exist", organization)
+	}
+
+	// the initial access token belongs to an organization admin, the open registration can't get the privileged grants
+	allowedGrantTypes := []string{}
+	if initialAccessToken != "" {
+		allowedGrantTypes = getPrivilegedGrantTypes()
+	}
+
+	tokenError = checkClientMetadata(metadata, allowedGrantTypes)
+	if tokenError != nil {
+		return nil, tokenError, nil
+	}
+
+	application, err := newRegisteredApplication(organization)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = applyClientMetadata(application, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	registrationAccessToken := util.GenerateClientSecret()
+	application.RegistrationAccessTokenHash = getTokenHash(registrationAccessToken)
+
+	affected, err := AddApplication(application)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !affected {
+		return nil, nil, fmt.Errorf("failed to add the application: %s", application.GetId())
+	}
+
+	resp, err := getClientInformationResponse(application, registrationAccessToken, host)
+	return resp, nil, err
+}
+
+// GetRegisteredClient returns the application registered with the client id if the registration access token matches it
+func GetRegisteredClient(clientId string, registrationAccessToken string) (*Application, error) {
+	if clientId == "" || registrationAccessToken == "" {
+		return nil, nil
+	}
+
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, err
+	}
+	if application == nil || application.RegistrationAccessTokenHash == "" {
+		return nil, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(application.RegistrationAccessTokenHash), []byte(getTokenHash(registrationAccessToken))) != 1 {
+		return nil, nil
+	}
+	return application, nil
+}
+
+// GetClientInformation
+// Client Read Request, per RFC 7592 section 2.1
+func GetClientInformation(application *Application, registrationAccessToken string, host string) (*ClientInformationResponse, error) {
+	return getClientInformationResponse(application, registrationAccessToken, host)
+}
+
+// UpdateRegisteredClient
+// Client Update Request, per RFC 7592 section 2.2. The metadata replaces all of the registered values
+func UpdateRegisteredClient(application *Application, clientId string, clientSecret string, metadata *ClientMetadata, registrationAccessToken string, host string) (*ClientInformationResponse, *TokenError, error) {
+	if clientId != application.ClientId {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "client_id doesn't match the registered client",
+		}, nil
+	}
+	if clientSecret != "" && clientSecret != application.ClientSecret {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "client_secret doesn't match the registered client",
+		}, nil
+	}
+
+	// the client keeps the privileged grants that it has been registered with or that an admin has granted to it,
+	// but the registration access token can't add new ones
+	tokenError := checkClientMetadata(metadata, application.GrantTypes)
+	if tokenError != nil {
+		return nil, tokenError, nil
+	}
+
+	err := applyClientMetadata(application, metadata)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = UpdateApplication(application.GetId(), application)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := getClientInformationResponse(application, registrationAccessToken, host)
+	return resp, nil, err
+}
+
+// DeleteRegisteredClient
+// Client Delete Request, per RFC 7592 section 2.3
+func DeleteRegisteredClient(application *Application) (bool, error) {
+	return DeleteApplication(application)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import "testing"
+
+func TestCheckOutboundUri(t *testing.T) {
+	scenarios := []struct {
+		uri   string
+		valid bool
+	}{
+		{"https://8.8.8.8/jwks", true},
+		{"http://8.8.8.8/jwks", false},
+		{"https://127.0.0.1/jwks", false},
+		{"https://[::1]/jwks", false},
+		{"https://10.0.0.1/logout", false},
+		{"https://192.168.1.1/logout", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://0.0.0.0/ciba", false},
+		{"/relative", false},
+	}
+
+	for _, scenario := range scenarios {
+		err := checkOutboundUri(scenario.uri)
+		if (err == nil) != scenario.valid {
+			t.Errorf("checkOutboundUri(%s) = %v, want valid: %v", scenario.uri, err, scenario.valid)
+		}
+	}
+}
+
+func TestCheckRedirectUri(t *testing.T) {
+	scenarios := []struct {
+		uri   string
+		valid bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"http://localhost:8080/callback", true},
+		{"http://127.0.0.1:8080/callback", true},
+		{"http://[::1]/callback", true},
+		{"com.example.app:/oauth2redirect", true},
+		{"http://app.example.com/callback", false},
+		{"https://app.example.com/callback#token", false},
+		{"javascript://alert(document.cookie)", false},
+		{"data:text/html,callback", false},
+		{"file:///etc/passwd", false},
+		{"/relative", false},
+	}
+
+	for _, scenario := range scenarios {
+		err := checkRedirectUri(scenario.uri)
+		if (err == nil) != scenario.valid {
+			t.Errorf("checkRedirectUri(%s) = %v, want valid: %v", scenario.uri, err, scenario.valid)
+		}
+	}
+}
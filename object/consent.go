@@ -0,0 +1,223 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+// Consent is the scopes that a user has granted to a third-party application
+type Consent struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	UpdatedTime string `xorm:"varchar(100)" json:"updatedTime"`
+
+	User        string   `xorm:"varchar(100) index" json:"user"`
+	Application string   `xorm:"varchar(100) index" json:"application"`
+	Scopes      []string `xorm:"mediumtext" json:"scopes"`
+}
+
+// ConsentRequest is what the consent screen shows to the user
+type ConsentRequest struct {
+	Application string   `json:"application"`
+	DisplayName string   `json:"displayName"`
+	Logo        string   `json:"logo"`
+	HomepageUrl string   `json:"homepageUrl"`
+	Scopes      []string `json:"scopes"`
+
+	ScopeDescriptions map[string]string `json:"scopeDescriptions"`
+
+	AuthorizationDetails      []AuthorizationDetail `json:"authorizationDetails"`
+	AuthorizationDetailsTypes map[string]string     `json:"authorizationDetailsTypes"`
+}
+
+func getScopeList(scope string) []string {
+	return strings.Fields(strings.ReplaceAll(scope, ",", " "))
+}
+
+func GetConsentsByUser(owner string, user string) ([]*Consent, error) {
+	consents := []*Consent{}
+	err := ormer.Engine.Desc("updated_time").Find(&consents, &Consent{Owner: owner, User: user})
+	if err != nil {
+		return consents, err
+	}
+
+	return consents, nil
+}
+
+func getConsent(owner string, name string) (*Consent, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	consent := Consent{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&consent)
+	if err != nil {
+		return &consent, err
+	}
+
+	if existed {
+		return &consent, nil
+	}
+
+	return nil, nil
+}
+
+func GetConsent(id string) (*Consent, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	return getConsent(owner, name)
+}
+
+func getConsentByUserAndApplication(user *User, application *Application) (*Consent, error) {
+	consent := Consent{Owner: user.Owner, User: user.Name, Application: application.Name}
+	existed, err := ormer.Engine.Get(&consent)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &consent, nil
+	}
+
+	return nil, nil
+}
+
+// IsConsentRequired reports whether the user should be asked before the application gets the scope. Only
+// third-party applications ask for consent, and "prompt=consent" asks again even if the scope has been granted
+func IsConsentRequired(application *Application, user *User, scope string, prompt string, authorizationDetails string) (bool, error) {
+	if !application.IsThirdParty {
+		return false, nil
+	}
+
+	// the authorization details are specific to the request, so the user approves them every time
+	if util.InSlice(strings.Fields(prompt), "consent") || authorizationDetails != "" {
+		return true, nil
+	}
+
+	consent, err := getConsentByUserAndApplication(user, application)
+	if err != nil {
+		return false, err
+	}
+	if consent == nil {
+		return true, nil
+	}
+
+	for _, item := range getScopeList(scope) {
+		if !util.InSlice(consent.Scopes, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func GetConsentRequest(application *Application, scope string, authorizationDetails string) *ConsentRequest {
+	// the custom scopes are described by the application
+	scopeDescriptions := map[string]string{}
+	for _, customScope := range application.getCustomScopes(strings.Join(getScopeList(scope), " ")) {
+		if customScope.Description != "" {
+			scopeDescriptions[customScope.Name] = customScope.Description
+		} else if customScope.DisplayName != "" {
+			scopeDescriptions[customScope.Name] = customScope.DisplayName
+		}
+	}
+
+	// the authorization details are checked again when the code or token is issued
+	details, _ := ParseAuthorizationDetails(application, authorizationDetails)
+	detailsTypes := map[string]string{}
+	for _, detailsType := range application.AuthorizationDetailsTypes {
+		detailsTypes[detailsType.Type] = detailsType.Description
+	}
+
+	return &ConsentRequest{
+		Application:       application.Name,
+		DisplayName:       application.DisplayName,
+		Logo:              application.Logo,
+		HomepageUrl:       application.HomepageUrl,
+		Scopes:            getScopeList(scope),
+		ScopeDescriptions: scopeDescriptions,
+
+		AuthorizationDetails:      details,
+		AuthorizationDetailsTypes: detailsTypes,
+	}
+}
+
+// GrantConsent adds the scope to the scopes that the user has granted to the application
+func GrantConsent(user *User, application *Application, scope string) (bool, error) {
+	consent, err := getConsentByUserAndApplication(user, application)
+	if err != nil {
+		return false, err
+	}
+
+	if consent == nil {
+		consent = &Consent{
+			Owner:       user.Owner,
+			Name:        util.GenerateId(),
+			CreatedTime: util.GetCurrentTime(),
+			UpdatedTime: util.GetCurrentTime(),
+			User:        user.Name,
+			Application: application.Name,
+			Scopes:      getScopeList(scope),
+		}
+
+		affected, err := ormer.Engine.Insert(consent)
+		if err != nil {
+			return false, err
+		}
+		return affected != 0, nil
+	}
+
+	for _, item := range getScopeList(scope) {
+		if !util.InSlice(consent.Scopes, item) {
+			consent.Scopes = append(consent.Scopes, item)
+		}
+	}
+	consent.UpdatedTime = util.GetCurrentTime()
+
+	affected, err := ormer.Engine.ID(core.PK{consent.Owner, consent.Name}).Cols("updated_time", "scopes").Update(consent)
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
+
+// RevokeConsent deletes the consent and expires the tokens that the application holds for the user
+func RevokeConsent(consent *Consent) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{consent.Owner, consent.Name}).Delete(&Consent{})
+	if err != nil {
+		return false, err
+	}
+
+	tokens := []*Token{}
+	err = ormer.Engine.Where("expires_in > 0").Find(&tokens, &Token{Organization: consent.Owner, User: consent.User, Application: consent.Application})
+	if err != nil {
+		return false, err
+	}
+
+	err = expireTokens(tokens)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (consent *Consent) GetId() string {
+	return fmt.Sprintf("%s/%s", consent.Owner, consent.Name)
+}
@@ -0,0 +1,258 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+// the authentication context classes, "2" requires a second factor
+const (
+	AcrSingleFactor = "1"
+	AcrMultiFactor  = "2"
+)
+
+// the authentication method references of RFC 8176, "fed" is the sign-in via a third-party identity provider
+const (
+	AmrPassword    = "pwd"
+	AmrOtp         = "otp"
+	AmrSms         = "sms"
+	AmrFace        = "face"
+	AmrHardwareKey = "hwk"
+	AmrFederated   = "fed"
+	AmrMfa         = "mfa"
+)
+
+// UnmetAuthenticationRequirements is the error of OpenID Connect Core Unmet Authentication Requirements 1.0
+const UnmetAuthenticationRequirements = "unmet_authentication_requirements"
+
+// AuthContext is how and when the user authenticated to Casdoor, and the claims requested by the "claims"
+// parameter (OIDC Core 1.0 section 5.5), it's reported to the applications in the acr, amr and auth_time claims
+type AuthContext struct {
+	AuthTime int64
+	Amr      []string
+	Claims   string
+}
+
+// ClaimRequest is an individual claim request of the "claims" parameter
+type ClaimRequest struct {
+	Essential bool          `json:"essential,omitempty"`
+	Value     interface{}   `json:"value,omitempty"`
+	Values    []interface{} `json:"values,omitempty"`
+}
+
+// ClaimsRequest is the "claims" parameter, the claims of "id_token" are added to the token and the claims of
+// "userinfo" are returned by the userinfo endpoint
+type ClaimsRequest struct {
+	Userinfo map[string]*ClaimRequest `json:"userinfo,omitempty"`
+	IdToken  map[string]*ClaimRequest `json:"id_token,omitempty"`
+}
+
+func GetAcrValuesSupported() []string {
+	return []string{AcrSingleFactor, AcrMultiFactor}
+}
+
+func getAuthClaimsSupported() []string {
+	return []string{"acr", "amr", "auth_time"}
+}
+
+// GetMfaAmr returns the authentication method reference of the MFA type
+func GetMfaAmr(mfaType string) string {
+	if mfaType == SmsType {
+		return AmrSms
+	}
+	return AmrOtp
+}
+
+// GetAcr returns the authentication context class of the authentication methods
+func GetAcr(amr []string) string {
+	if util.InSlice(amr, AmrMfa) {
+		return AcrMultiFactor
+	}
+	return AcrSingleFactor
+}
+
+// IsAcrSatisfied returns whether the authentication context class is one of the requested ones, a multi-factor
+// authentication satisfies a request for a single-factor one
+func IsAcrSatisfied(acr string, acrValues []string) bool {
+	if len(acrValues) == 0 {
+		return true
+	}
+
+	for _, acrValue := range acrValues {
+		if acrValue == acr || (acrValue == AcrSingleFactor && acr == AcrMultiFactor) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClaimsRequest parses the "claims" parameter, an empty parameter requests no claims
+func ParseClaimsRequest(claims string) (*ClaimsRequest, error) {
+	res := &ClaimsRequest{}
+	if claims == "" {
+		return res, nil
+	}
+
+	err := json.Unmarshal([]byte(claims), res)
+	if err != nil {
+		return nil, fmt.Errorf("the claims parameter is invalid: %s", err.Error())
+	}
+	return res, nil
+}
+
+// GetAcrValues returns the authentication context classes requested by acr_values and by the acr claim of the
+// "claims" parameter, and whether one of them is essential
+func (r *ClaimsRequest) GetAcrValues(acrValues string) ([]string, bool) {
+	res := strings.Fields(acrValues)
+	isEssential := false
+	for _, claimRequests := range []map[string]*ClaimRequest{r.IdToken, r.Userinfo} {
+		claimRequest := claimRequests["acr"]
+		if claimRequest == nil {
+			continue
+		}
+
+		values := claimRequest.Values
+		if claimRequest.Value != nil {
+			values = append(values, claimRequest.Value)
+		}
+		for _, value := range values {
+			if s, ok := value.(string); ok && !util.InSlice(res, s) {
+				res = append(res, s)
+			}
+		}
+		isEssential = isEssential || claimRequest.Essential
+	}
+	return res, isEssential
+}
+
+// getUserClaim returns the standard OIDC claim of the user, or nil if the user has no value for it
+func getUserClaim(user *User, name string) interface{} {
+	var res interface{}
+	switch name {
+	case "preferred_username":
+		res = user.Name
+	case "name":
+		res = user.DisplayName
+	case "given_name":
+		res = user.FirstName
+	case "family_name":
+		res = user.LastName
+	case "email":
+		res = user.Email
+	case "email_verified":
+		return user.EmailVerified
+	case "phone_number":
+		res = user.Phone
+	case "picture":
+		res = user.Avatar
+	case "address":
+		res = user.Location
+	case "gender":
+		res = user.Gender
+	case "birthdate":
+		res = user.Birthday
+	case "locale":
+		res = user.Language
+	case "website":
+		res = user.Homepage
+	case "groups":
+		if len(user.Groups) == 0 {
+			return nil
+		}
+		return user.Groups
+	}
+
+	if res == "" {
+		return nil
+	}
+	return res
+}
+
+// getClaimScope returns the scope that releases the standard OIDC claim, per OpenID Connect Core 1.0 section 5.4
+func getClaimScope(name string) string {
+	switch name {
+	case "email", "email_verified":
+		return "email"
+	case "phone_number":
+		return "phone"
+	case "address":
+		return "address"
+	default:
+		return "profile"
+	}
+}
+
+// getRequestedClaims returns the values of the requested claims that the user has, a claim is only released when
+// the user has consented to the scope that covers it
+func getRequestedClaims(user *User, claimRequests map[string]*ClaimRequest, scope string) map[string]interface{} {
+	res := map[string]interface{}{}
+	scopes := strings.Fields(scope)
+	for name := range claimRequests {
+		if !util.InSlice(scopes, getClaimScope(name)) {
+			continue
+		}
+		if value := getUserClaim(user, name); value != nil {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+// getClaims returns the acr, amr and auth_time claims of the authentication and the claims requested for the
+// ID token, it's empty for the tokens not issued to a signed-in user
+func (authContext *AuthContext) getClaims(user *User, scope string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	if authContext == nil {
+		return res, nil
+	}
+
+	claimsRequest, err := ParseClaimsRequest(authContext.Claims)
+	if err != nil {
+		return nil, err
+	}
+	res = getRequestedClaims(user, claimsRequest.IdToken, scope)
+
+	if authContext.AuthTime != 0 {
+		res["auth_time"] = authContext.AuthTime
+	}
+	if len(authContext.Amr) != 0 {
+		res["amr"] = authContext.Amr
+		res["acr"] = GetAcr(authContext.Amr)
+	}
+	return res, nil
+}
+
+func (token *Token) getAuthContext() *AuthContext {
+	return &AuthContext{
+		AuthTime: token.AuthTime,
+		Amr:      token.Amr,
+		Claims:   token.Claims,
+	}
+}
+
+func (token *Token) setAuthContext(authContext *AuthContext) {
+	if authContext == nil {
+		return
+	}
+
+	token.AuthTime = authContext.AuthTime
+	token.Amr = authContext.Amr
+	token.Claims = authContext.Claims
+}
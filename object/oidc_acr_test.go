@@ -0,0 +1,56 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetAcrValues(t *testing.T) {
+	claimsRequest, err := ParseClaimsRequest(`{"id_token": {"acr": {"essential": true, "values": ["2"]}, "email": null}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acrValues, isEssential := claimsRequest.GetAcrValues("1 2")
+	if !reflect.DeepEqual(acrValues, []string{"1", "2"}) || !isEssential {
+		t.Errorf("GetAcrValues() = %v, %v, want [1 2], true", acrValues, isEssential)
+	}
+
+	if IsAcrSatisfied(GetAcr([]string{AmrPassword}), []string{AcrMultiFactor}) {
+		t.Errorf("a password authentication should not satisfy acr: %s", AcrMultiFactor)
+	}
+	if !IsAcrSatisfied(GetAcr([]string{AmrPassword, AmrOtp, AmrMfa}), []string{AcrSingleFactor}) {
+		t.Errorf("a multi-factor authentication should satisfy acr: %s", AcrSingleFactor)
+	}
+
+	authContext := &AuthContext{AuthTime: 1700000000, Amr: []string{AmrPassword}, Claims: `{"id_token": {"email": null}}`}
+	claims, err := authContext.getClaims(&User{Email: "admin@example.com"}, "openid email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["email"] != "admin@example.com" || claims["acr"] != AcrSingleFactor || claims["auth_time"] != int64(1700000000) {
+		t.Errorf("getClaims() = %v", claims)
+	}
+
+	claims, err = authContext.getClaims(&User{Email: "admin@example.com"}, "openid profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := claims["email"]; ok {
+		t.Errorf("the email claim should not be released without the email scope: %v", claims)
+	}
+}
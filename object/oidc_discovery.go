@@ -22,26 +22,52 @@ import (
 	"strings"
 
 	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
 	"gopkg.in/square/go-jose.v2"
 )
 
 type OidcDiscovery struct {
-	Issuer                                 string   `json:"issuer"`
-	AuthorizationEndpoint                  string   `json:"authorization_endpoint"`
-	TokenEndpoint                          string   `json:"token_endpoint"`
-	UserinfoEndpoint                       string   `json:"userinfo_endpoint"`
-	JwksUri                                string   `json:"jwks_uri"`
-	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
-	ResponseTypesSupported                 []string `json:"response_types_supported"`
-	ResponseModesSupported                 []string `json:"response_modes_supported"`
-	GrantTypesSupported                    []string `json:"grant_types_supported"`
-	SubjectTypesSupported                  []string `json:"subject_types_supported"`
-	IdTokenSigningAlgValuesSupported       []string `json:"id_token_signing_alg_values_supported"`
-	ScopesSupported                        []string `json:"scopes_supported"`
-	ClaimsSupported                        []string `json:"claims_supported"`
-	RequestParameterSupported              bool     `json:"request_parameter_supported"`
-	RequestObjectSigningAlgValuesSupported []string `json:"request_object_signing_alg_values_supported"`
-	EndSessionEndpoint                     string   `json:"end_session_endpoint"`
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint"`
+	JwksUri                                    string   `json:"jwks_uri"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	IdTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported"`
+	IdTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported"`
+	UserinfoSigningAlgValuesSupported          []string `json:"userinfo_signing_alg_values_supported"`
+	UserinfoEncryptionAlgValuesSupported       []string `json:"userinfo_encryption_alg_values_supported"`
+	UserinfoEncryptionEncValuesSupported       []string `json:"userinfo_encryption_enc_values_supported"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
+	ClaimsParameterSupported                   bool     `json:"claims_parameter_supported"`
+	AcrValuesSupported                         []string `json:"acr_values_supported"`
+	RequestParameterSupported                  bool     `json:"request_parameter_supported"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint"`
+	CheckSessionIframe                         string   `json:"check_session_iframe"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests         bool     `json:"require_pushed_authorization_requests"`
+	DpopSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	TlsClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens"`
+	FrontchannelLogoutSupported                bool     `json:"frontchannel_logout_supported"`
+	FrontchannelLogoutSessionSupported         bool     `json:"frontchannel_logout_session_supported"`
+	BackchannelLogoutSupported                 bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported          bool     `json:"backchannel_logout_session_supported"`
+	BackchannelAuthenticationEndpoint          string   `json:"backchannel_authentication_endpoint"`
+	BackchannelTokenDeliveryModesSupported     []string `json:"backchannel_token_delivery_modes_supported"`
+	BackchannelUserCodeParameterSupported      bool     `json:"backchannel_user_code_parameter_supported"`
+	AuthorizationDetailsTypesSupported         []string `json:"authorization_details_types_supported"`
 }
 
 func isIpAddress(host string) bool {
@@ -93,7 +119,39 @@ func getOriginFromHost(host string) (string, string) {
 	return originF, originB
 }
 
-func GetOidcDiscovery(host string) OidcDiscovery {
+// getApplicationDiscoveryMetadata returns the names of the custom scopes, of the claims they release and of the
+// authorization details types of all applications, which are read in a single query
+func getApplicationDiscoveryMetadata() ([]string, []string, []string, error) {
+	applications := []*Application{}
+	err := ormer.Engine.Cols("custom_scopes", "authorization_details_types").Find(&applications)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	scopes := []string{}
+	claims := []string{}
+	detailsTypes := []string{}
+	for _, application := range applications {
+		for _, customScope := range application.CustomScopes {
+			if !util.InSlice(scopes, customScope.Name) {
+				scopes = append(scopes, customScope.Name)
+			}
+			for _, claim := range customScope.Claims {
+				if !util.InSlice(claims, claim.Name) {
+					claims = append(claims, claim.Name)
+				}
+			}
+		}
+		for _, detailsType := range application.AuthorizationDetailsTypes {
+			if !util.InSlice(detailsTypes, detailsType.Type) {
+				detailsTypes = append(detailsTypes, detailsType.Type)
+			}
+		}
+	}
+	return scopes, claims, detailsTypes, nil
+}
+
+func GetOidcDiscovery(host string) (OidcDiscovery, error) {
 	originFrontend, originBackend := getOriginFromHost(host)
 
 	// Examples:
@@ -102,25 +160,86 @@ func GetOidcDiscovery(host string) OidcDiscovery {
 	// https://accounts.google.com/.well-known/openid-configuration
 	// https://access.line.me/.well-known/openid-configuration
 	oidcDiscovery := OidcDiscovery{
-		Issuer:                                 originBackend,
-		AuthorizationEndpoint:                  fmt.Sprintf("%s/login/oauth/authorize", originFrontend),
-		TokenEndpoint:                          fmt.Sprintf("%s/api/login/oauth/access_token", originBackend),
-		UserinfoEndpoint:                       fmt.Sprintf("%s/api/userinfo", originBackend),
-		JwksUri:                                fmt.Sprintf("%s/.well-known/jwks", originBackend),
-		IntrospectionEndpoint:                  fmt.Sprintf("%s/api/login/oauth/introspect", originBackend),
-		ResponseTypesSupported:                 []string{"code", "token", "id_token", "code token", "code id_token", "token id_token", "code token id_token", "none"},
-		ResponseModesSupported:                 []string{"query", "fragment", "login", "code", "link"},
-		GrantTypesSupported:                    []string{"password", "authorization_code"},
-		SubjectTypesSupported:                  []string{"public"},
-		IdTokenSigningAlgValuesSupported:       []string{"RS256", "RS512", "ES256", "ES384", "ES512"},
-		ScopesSupported:                        []string{"openid", "email", "profile", "address", "phone", "offline_access"},
-		ClaimsSupported:                        []string{"iss", "ver", "sub", "aud", "iat", "exp", "id", "type", "displayName", "avatar", "permanentAvatar", "email", "phone", "location", "affiliation", "title", "homepage", "bio", "tag", "region", "language", "score", "ranking", "isOnline", "isAdmin", "isForbidden", "signupApplication", "ldap"},
-		RequestParameterSupported:              true,
-		RequestObjectSigningAlgValuesSupported: []string{"HS256", "HS384", "HS512"},
-		EndSessionEndpoint:                     fmt.Sprintf("%s/api/logout", originBackend),
+		Issuer:                                     originBackend,
+		AuthorizationEndpoint:                      fmt.Sprintf("%s/login/oauth/authorize", originFrontend),
+		TokenEndpoint:                              fmt.Sprintf("%s/api/login/oauth/access_token", originBackend),
+		UserinfoEndpoint:                           fmt.Sprintf("%s/api/userinfo", originBackend),
+		JwksUri:                                    fmt.Sprintf("%s/.well-known/jwks", originBackend),
+		IntrospectionEndpoint:                      fmt.Sprintf("%s/api/login/oauth/introspect", originBackend),
+		RevocationEndpoint:                         fmt.Sprintf("%s/api/login/oauth/revoke", originBackend),
+		ResponseTypesSupported:                     []string{"code", "token", "id_token", "code token", "code id_token", "token id_token", "code token id_token", "none"},
+		ResponseModesSupported:                     []string{"query", "fragment", "login", "code", "link"},
+		GrantTypesSupported:                        []string{"password", "authorization_code", DeviceCodeGrantType, TokenExchangeGrantType, JwtBearerGrantType, CibaGrantType},
+		SubjectTypesSupported:                      []string{"public"},
+		IdTokenSigningAlgValuesSupported:           []string{"RS256", "RS512", "ES256", "ES384", "ES512"},
+		IdTokenEncryptionAlgValuesSupported:        getEncryptionAlgValuesSupported(),
+		IdTokenEncryptionEncValuesSupported:        getEncryptionEncValuesSupported(),
+		UserinfoSigningAlgValuesSupported:          getUserinfoSigningAlgValuesSupported(),
+		UserinfoEncryptionAlgValuesSupported:       getEncryptionAlgValuesSupported(),
+		UserinfoEncryptionEncValuesSupported:       getEncryptionEncValuesSupported(),
+		ScopesSupported:                            []string{"openid", "email", "profile", "address", "phone", "offline_access"},
+		ClaimsSupported:                            []string{"iss", "ver", "sub", "aud", "iat", "exp", "id", "type", "displayName", "avatar", "permanentAvatar", "email", "phone", "location", "affiliation", "title", "homepage", "bio", "tag", "region", "language", "score", "ranking", "isOnline", "isAdmin", "isForbidden", "signupApplication", "ldap"},
+		ClaimsParameterSupported:                   true,
+		AcrValuesSupported:                         GetAcrValuesSupported(),
+		RequestParameterSupported:                  true,
+		RequestObjectSigningAlgValuesSupported:     []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+		EndSessionEndpoint:                         fmt.Sprintf("%s/api/logout", originBackend),
+		CheckSessionIframe:                         fmt.Sprintf("%s/api/login/oauth/check-session", originBackend),
+		DeviceAuthorizationEndpoint:                fmt.Sprintf("%s/api/login/oauth/device_authorization", originBackend),
+		PushedAuthorizationRequestEndpoint:         fmt.Sprintf("%s/api/login/oauth/par", originBackend),
+		RequirePushedAuthorizationRequests:         false,
+		DpopSigningAlgValuesSupported:              []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"},
+		RegistrationEndpoint:                       fmt.Sprintf("%s/api/login/oauth/register", originBackend),
+		TokenEndpointAuthMethodsSupported:          getTokenEndpointAuthMethods(),
+		TokenEndpointAuthSigningAlgValuesSupported: getTokenEndpointAuthSigningAlgs(),
+		TlsClientCertificateBoundAccessTokens:      true,
+		FrontchannelLogoutSupported:                true,
+		FrontchannelLogoutSessionSupported:         true,
+		BackchannelLogoutSupported:                 true,
+		BackchannelLogoutSessionSupported:          true,
+		BackchannelAuthenticationEndpoint:          fmt.Sprintf("%s/api/login/oauth/bc-authorize", originBackend),
+		BackchannelTokenDeliveryModesSupported:     []string{CibaDeliveryModePoll, CibaDeliveryModePing},
+		BackchannelUserCodeParameterSupported:      false,
+	}
+
+	// the claims of the authentication, the custom scopes of the applications and the claims they release
+	customScopes, customClaims, detailsTypes, err := getApplicationDiscoveryMetadata()
+	if err != nil {
+		return oidcDiscovery, err
+	}
+	for _, scope := range customScopes {
+		if !util.InSlice(oidcDiscovery.ScopesSupported, scope) {
+			oidcDiscovery.ScopesSupported = append(oidcDiscovery.ScopesSupported, scope)
+		}
+	}
+	for _, claim := range append(getAuthClaimsSupported(), customClaims...) {
+		if !util.InSlice(oidcDiscovery.ClaimsSupported, claim) {
+			oidcDiscovery.ClaimsSupported = append(oidcDiscovery.ClaimsSupported, claim)
+		}
 	}
+	oidcDiscovery.AuthorizationDetailsTypesSupported = detailsTypes
 
-	return oidcDiscovery
+	return oidcDiscovery, nil
+}
+
+func getJsonWebKey(certificate string, kid string, algorithm string) (jose.JSONWebKey, error) {
+	var jwk jose.JSONWebKey
+	certPemBlock := []byte(certificate)
+	certDerBlock, _ := pem.Decode(certPemBlock)
+	if certDerBlock == nil {
+		return jwk, fmt.Errorf("failed to decode the certificate of the key: %s", kid)
+	}
+	x509Cert, err := x509.ParseCertificate(certDerBlock.Bytes)
+	if err != nil {
+		return jwk, err
+	}
+
+	jwk.Key = x509Cert.PublicKey
+	jwk.Certificates = []*x509.Certificate{x509Cert}
+	jwk.KeyID = kid
+	jwk.Algorithm = algorithm
+	jwk.Use = "sig"
+	return jwk, nil
 }
 
 func GetJsonWebKeySet() (jose.JSONWebKeySet, error) {
@@ -142,20 +261,32 @@ func GetJsonWebKeySet() (jose.JSONWebKeySet, error) {
 			return jwks, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 		}
 
-		certPemBlock := []byte(cert.Certificate)
-		certDerBlock, _ := pem.Decode(certPemBlock)
-		x509Cert, err := x509.ParseCertificate(certDerBlock.Bytes)
+		jwk, err := getJsonWebKey(cert.Certificate, cert.GetKeyId(), cert.CryptoAlgorithm)
 		if err != nil {
 			return jwks, err
 		}
-
-		var jwk jose.JSONWebKey
-		jwk.Key = x509Cert.PublicKey
-		jwk.Certificates = []*x509.Certificate{x509Cert}
-		jwk.KeyID = cert.Name
-		jwk.Algorithm = cert.CryptoAlgorithm
-		jwk.Use = "sig"
 		jwks.Keys = append(jwks.Keys, jwk)
+
+		// the next key is published ahead of the rotation and the retired keys until their tokens have expired
+		if cert.NextCertificate != "" {
+			jwk, err = getJsonWebKey(cert.NextCertificate, cert.NextKeyId, cert.CryptoAlgorithm)
+			if err != nil {
+				return jwks, err
+			}
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+
+		rotations, err := getRetiredCertRotations(cert)
+		if err != nil {
+			return jwks, err
+		}
+		for _, rotation := range rotations {
+			jwk, err = getJsonWebKey(rotation.Certificate, rotation.KeyId, rotation.CryptoAlgorithm)
+			if err != nil {
+				return jwks, err
+			}
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
 	}
 
 	return jwks, nil
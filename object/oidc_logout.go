@@ -0,0 +1,193 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	logoutTokenExpireInSeconds = 120
+)
+
+// LogoutTokenClaims is the logout token of OpenID Connect Back-Channel Logout 1.0 section 2.4
+type LogoutTokenClaims struct {
+	Events map[string]interface{} `json:"events"`
+	Sid    string                 `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GetOidcSid returns the "sid" of a browser session, the session id itself is not exposed to the RPs
+func GetOidcSid(sessionId string) string {
+	if sessionId == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(sessionId))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// getSessionApplications returns the applications that the user has signed in to with the browser session
+func getSessionApplications(owner string, name string, sessionId string) ([]*Application, error) {
+	sessions := []*Session{}
+	err := ormer.Engine.Find(&sessions, &Session{Owner: owner, Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	applications := []*Application{}
+	for _, session := range sessions {
+		if !util.InSlice(session.SessionId, sessionId) {
+			continue
+		}
+
+		application, err := getApplication("admin", session.Application)
+		if err != nil {
+			return nil, err
+		}
+		if application != nil {
+			applications = append(applications, application)
+		}
+	}
+
+	return applications, nil
+}
+
+func generateLogoutToken(application *Application, user *User, sid string, host string) (string, error) {
+	_, originBackend := getOriginFromHost(host)
+
+	nowTime := time.Now()
+	claims := LogoutTokenClaims{
+		Events: map[string]interface{}{BackchannelLogoutEvent: map[string]interface{}{}},
+		Sid:    sid,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    originBackend,
+			Subject:   user.Id,
+			Audience:  []string{application.ClientId},
+			ExpiresAt: jwt.NewNumericDate(nowTime.Add(logoutTokenExpireInSeconds * time.Second)),
+			IssuedAt:  jwt.NewNumericDate(nowTime),
+			ID:        util.GenerateId(),
+		},
+	}
+
+	cert, key, err := getApplicationSigningKey(application)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(getJwtSigningMethod(application), claims)
+	token.Header["kid"] = cert.GetKeyId()
+	token.Header["typ"] = "logout+jwt"
+	return token.SignedString(key)
+}
+
+func sendBackchannelLogout(application *Application, logoutToken string) error {
+	resp, err := proxy.DefaultHttpClient.PostForm(application.BackchannelLogoutUri, url.Values{"logout_token": {logoutToken}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("the back-channel logout of the application: %s returns status: %s", application.GetId(), resp.Status)
+	}
+	return nil
+}
+
+func getFrontchannelLogoutUrl(application *Application, sid string, host string) string {
+	_, originBackend := getOriginFromHost(host)
+
+	sep := "?"
+	if strings.Contains(application.FrontchannelLogoutUri, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%siss=%s&sid=%s", application.FrontchannelLogoutUri, sep, url.QueryEscape(originBackend), url.QueryEscape(sid))
+}
+
+// LogoutSessionApplications ends the browser session of the user in the applications that the user has signed in to
+// with it: the logout tokens are posted to their back-channel logout URIs, and the front-channel logout URLs and SAML
+// LogoutRequest URLs are returned for the logout page to load in iframes. The initiator application, which has
+// requested the logout itself, isn't notified
+func LogoutSessionApplications(user *User, sessionId string, host string, initiator string) ([]string, error) {
+	applications, err := getSessionApplications(user.Owner, user.Name, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	sid := GetOidcSid(sessionId)
+	frontchannelLogoutUrls := []string{}
+	for _, application := range applications {
+		if application.Name == initiator {
+			_, err = DeleteSessionId(util.GetSessionId(user.Owner, user.Name, application.Name), sessionId)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if application.BackchannelLogoutUri != "" {
+			logoutToken, err := generateLogoutToken(application, user, sid, host)
+			if err != nil {
+				return nil, err
+			}
+
+			application := application
+			util.SafeGoroutine(func() {
+				err := sendBackchannelLogout(application, logoutToken)
+				if err != nil {
+					logs.Warning(fmt.Sprintf("back-channel logout failed for the application: %s, error: %s", application.GetId(), err.Error()))
+				}
+			})
+		}
+
+		if application.FrontchannelLogoutUri != "" {
+			frontchannelLogoutUrls = append(frontchannelLogoutUrls, getFrontchannelLogoutUrl(application, sid, host))
+		}
+
+		if application.SamlLogoutUrl != "" {
+			samlLogoutUrl, err := getSamlLogoutRequestUrl(application, user, sid, host)
+			if err != nil {
+				return nil, err
+			}
+			frontchannelLogoutUrls = append(frontchannelLogoutUrls, samlLogoutUrl)
+		}
+
+		if wsFedCleanupUrl := getWsFedSignOutCleanupUrl(application); wsFedCleanupUrl != "" {
+			frontchannelLogoutUrls = append(frontchannelLogoutUrls, wsFedCleanupUrl)
+		}
+
+		if application.Name != CasdoorApplication {
+			_, err = DeleteSessionId(util.GetSessionId(user.Owner, user.Name, application.Name), sessionId)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return frontchannelLogoutUrls, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OpbsCookieName is the cookie of the OP browser state (OIDC Session Management 1.0 section 3), it is readable
+// by the check_session_iframe and changes whenever the user signs in or out
+const OpbsCookieName = "casdoor_opbs"
+
+func getUriOrigin(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+}
+
+// GetSessionState returns the session_state of an authorization response, the salted hash of the client id,
+// the origin of the redirect URI and the browser state, so that the check_session_iframe can recompute it
+func GetSessionState(clientId string, redirectUri string, opbs string) string {
+	if opbs == "" {
+		return ""
+	}
+
+	salt := util.GenerateClientId()
+	sum := sha256.Sum256([]byte(strings.Join([]string{clientId, getUriOrigin(redirectUri), opbs, salt}, " ")))
+	return fmt.Sprintf("%s.%s", hex.EncodeToString(sum[:]), salt)
+}
+
+// getApplicationByAudience returns the application an ID token is issued to, the audience of a shared
+// application is suffixed with the organization of the user
+func getApplicationByAudience(audience []string) (*Application, error) {
+	for _, item := range audience {
+		clientId := item
+		if i := strings.Index(item, "-org-"); i != -1 {
+			clientId = item[:i]
+		}
+
+		application, err := GetApplicationByClientId(clientId)
+		if err != nil {
+			return nil, err
+		}
+		if application != nil {
+			return application, nil
+		}
+	}
+	return nil, nil
+}
+
+// ParseIdTokenHint verifies the id_token_hint of an RP-initiated logout (OIDC RP-Initiated Logout 1.0 section 2),
+// the ID token must have been issued by Casdoor to the client (if client_id is given) but may have expired
+func ParseIdTokenHint(idTokenHint string, clientId string) (*Application, *Claims, error) {
+	unverifiedClaims := Claims{}
+	_, _, err := jwt.NewParser().ParseUnverified(idTokenHint, &unverifiedClaims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	application, err := getApplicationByAudience(unverifiedClaims.Audience)
+	if err != nil {
+		return nil, nil, err
+	}
+	if application == nil {
+		return nil, nil, fmt.Errorf("the audience of the ID token doesn't match any application")
+	}
+	if clientId != "" && application.ClientId != clientId {
+		return nil, nil, fmt.Errorf("the ID token is not issued to the client: %s", clientId)
+	}
+
+	claims, err := parseJwtTokenWithoutClaimsValidation(idTokenHint, application)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return application, claims, nil
+}
+
+// GetIdTokenHintUser returns the user that an ID token is issued for
+func GetIdTokenHintUser(application *Application, idTokenHint string, claims *Claims) (*User, error) {
+	token, err := GetTokenByAccessToken(idTokenHint)
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		return getUser(token.Organization, token.User)
+	}
+
+	return GetUserByUserId(application.Organization, claims.Subject)
+}
+
+// IsPostLogoutRedirectUriValid checks the post_logout_redirect_uri against the registered ones, the redirect URIs
+// are used instead if none is registered
+func (application *Application) IsPostLogoutRedirectUriValid(redirectUri string) bool {
+	if len(application.PostLogoutRedirectUris) == 0 {
+		return application.IsRedirectUriValid(redirectUri)
+	}
+
+	return util.InSlice(application.PostLogoutRedirectUris, redirectUri)
+}
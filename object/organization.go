@@ -77,6 +77,8 @@ type Organization struct {
 
 	MfaItems     []*MfaItem     `xorm:"varchar(300)" json:"mfaItems"`
 	AccountItems []*AccountItem `xorm:"varchar(5000)" json:"accountItems"`
+
+	TrustedIssuers []*TrustedIssuer `xorm:"mediumtext" json:"trustedIssuers"`
 }
 
 func GetOrganizationCount(owner, field, value string) (int64, error) {
@@ -0,0 +1,73 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/xorm-io/xorm"
+)
+
+// initTestOrmer replaces the ormer with an in-memory SQLite database with the tables of the beans during the test
+func initTestOrmer(t *testing.T, beans ...interface{}) {
+	engine, err := xorm.NewEngine("sqlite", fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the in-memory database lives as long as its connection
+	engine.SetMaxOpenConns(1)
+
+	err = engine.Sync2(beans...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := ormer
+	ormer = &Ormer{Engine: engine}
+	t.Cleanup(func() {
+		ormer = previous
+		engine.Close()
+	})
+}
+
+// testConsumeOnce calls consume from many goroutines at the same time, only one of them should get the value
+func testConsumeOnce(t *testing.T, name string, consume func() (bool, error)) {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	count := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := consume()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				lock.Lock()
+				count++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count != 1 {
+		t.Errorf("%s should be consumed once, got: %d", name, count)
+	}
+}
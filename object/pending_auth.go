@@ -0,0 +1,230 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xorm-io/core"
+)
+
+const (
+	PendingAuthTypeDevice = "device"
+	PendingAuthTypeCiba   = "ciba"
+
+	// RFC 8628 section 3.5: the interval is increased by 5 seconds on every slow_down error
+	slowDownIntervalSeconds = 5
+)
+
+// PendingAuth is an authorization request waiting for the decision of the user on another device, for the device
+// authorization grant and CIBA. The owner is the type of the request and the name is the device_code or auth_req_id,
+// which is only known to the client. The code is the one shown to the user: the user code of the device or the id
+// of the CIBA request
+type PendingAuth struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"-"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"-"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	ExpireTime  string `xorm:"varchar(100) index" json:"expireTime"`
+
+	Code                    string `xorm:"varchar(100) index" json:"id"`
+	Application             string `xorm:"varchar(100)" json:"application"`
+	Scope                   string `xorm:"varchar(100)" json:"scope"`
+	BindingMessage          string `xorm:"varchar(200)" json:"bindingMessage"`
+	User                    string `xorm:"varchar(100) index" json:"user"`
+	IsApproved              bool   `json:"isApproved"`
+	IsDenied                bool   `json:"isDenied"`
+	ClientNotificationToken string `xorm:"varchar(1024)" json:"-"`
+	LastPollTime            string `xorm:"varchar(100)" json:"-"`
+	PollInterval            int    `json:"-"`
+}
+
+func (auth *PendingAuth) isExpired() bool {
+	expireTime, err := time.Parse(time.RFC3339, auth.ExpireTime)
+	return err != nil || time.Now().After(expireTime)
+}
+
+// getPendingAuthName returns the name of the parameter carrying the name of the request in the token request
+func getPendingAuthName(authType string) string {
+	if authType == PendingAuthTypeCiba {
+		return "auth_req_id"
+	}
+	return "device_code"
+}
+
+func addPendingAuth(auth *PendingAuth) error {
+	_, err := ormer.Engine.Insert(auth)
+	return err
+}
+
+func getPendingAuth(authType string, name string) (*PendingAuth, error) {
+	auth := PendingAuth{Owner: authType, Name: name}
+	existed, err := ormer.Engine.Get(&auth)
+	if err != nil {
+		return nil, err
+	}
+	if !existed {
+		return nil, nil
+	}
+	return &auth, nil
+}
+
+// getUndecidedPendingAuths returns the unexpired requests matching the condition that the user has not decided yet,
+// newest first
+func getUndecidedPendingAuths(cond *PendingAuth) ([]*PendingAuth, error) {
+	auths := []*PendingAuth{}
+	err := ormer.Engine.Where("is_approved = ? and is_denied = ? and expire_time > ?", false, false, time.Now().Format(time.RFC3339)).
+		Desc("created_time").Find(&auths, cond)
+	if err != nil {
+		return nil, err
+	}
+	return auths, nil
+}
+
+// decidePendingAuth records the decision of the user, it returns false if the request has expired or been decided,
+// so only one of the concurrent decisions wins
+func decidePendingAuth(auth *PendingAuth, userId string, approved bool) (bool, error) {
+	decision := PendingAuth{User: userId, IsApproved: approved, IsDenied: !approved}
+	affected, err := ormer.Engine.ID(core.PK{auth.Owner, auth.Name}).
+		Where("is_approved = ? and is_denied = ? and expire_time > ?", false, false, time.Now().Format(time.RFC3339)).
+		Cols("user", "is_approved", "is_denied").Update(&decision)
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
+
+func deletePendingAuth(auth *PendingAuth) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{auth.Owner, auth.Name}).Delete(&PendingAuth{})
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
+
+func purgeExpiredPendingAuths(cutoff time.Time) (int64, error) {
+	return purgeRecords(&PendingAuth{}, "pending_auth", "expire_time < ?", cutoff.Format(time.RFC3339))
+}
+
+// pollPendingAuth handles a poll of the client on the token endpoint, per RFC 8628 section 3.5 and CIBA section 11.
+// The request is returned once the user has approved it, and it is consumed by the call returning it
+func pollPendingAuth(application *Application, authType string, name string) (*PendingAuth, *TokenError, error) {
+	nameParam := getPendingAuthName(authType)
+
+	auth, err := getPendingAuth(authType, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if auth == nil || auth.Application != application.GetId() {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid", nameParam),
+		}, nil
+	}
+
+	if auth.isExpired() {
+		_, err = deletePendingAuth(auth)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &TokenError{
+			Error:            ExpiredToken,
+			ErrorDescription: fmt.Sprintf("%s has expired", nameParam),
+		}, nil
+	}
+
+	now := time.Now()
+	lastPollTime, err := time.Parse(time.RFC3339, auth.LastPollTime)
+	tooFast := err == nil && now.Sub(lastPollTime) < time.Duration(auth.PollInterval)*time.Second
+	if tooFast {
+		auth.PollInterval += slowDownIntervalSeconds
+	}
+	auth.LastPollTime = now.Format(time.RFC3339)
+
+	// concurrent polls may overwrite each other here, which at worst loses an increase of the interval
+	_, err = ormer.Engine.ID(core.PK{auth.Owner, auth.Name}).Cols("last_poll_time", "poll_interval").Update(auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tooFast {
+		return nil, &TokenError{
+			Error:            SlowDown,
+			ErrorDescription: fmt.Sprintf("polling too fast, the interval has been increased to %d seconds", auth.PollInterval),
+		}, nil
+	}
+
+	if auth.IsDenied {
+		_, err = deletePendingAuth(auth)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &TokenError{
+			Error:            AccessDenied,
+			ErrorDescription: "the end user denied the authorization request",
+		}, nil
+	}
+
+	if !auth.IsApproved {
+		return nil, &TokenError{
+			Error:            AuthorizationPending,
+			ErrorDescription: "the end user has not yet completed the authorization",
+		}, nil
+	}
+
+	// the request can only be exchanged once, only the poll deleting it gets the token
+	deleted, err := deletePendingAuth(auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !deleted {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid", nameParam),
+		}, nil
+	}
+	return auth, nil, nil
+}
+
+// getPendingAuthToken polls the request and issues the token to the user who has approved it
+func getPendingAuthToken(application *Application, authType string, name string, host string) (*Token, *TokenError, error) {
+	auth, tokenError, err := pollPendingAuth(application, authType, name)
+	if err != nil || tokenError != nil {
+		return nil, tokenError, err
+	}
+
+	user, err := GetUser(auth.User)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user does not exist",
+		}, nil
+	}
+	if user.IsForbidden {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user is forbidden to sign in, please contact the administrator",
+		}, nil
+	}
+
+	token, err := GetTokenByUser(application, user, auth.Scope, "", host, "", nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, nil, nil
+}
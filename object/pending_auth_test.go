@@ -0,0 +1,113 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xorm-io/core"
+)
+
+func TestPollPendingAuth(t *testing.T) {
+	initTestOrmer(t, &PendingAuth{})
+	application := &Application{Owner: "admin", Name: "app-device"}
+	now := time.Now()
+	auth := &PendingAuth{
+		Owner:        PendingAuthTypeDevice,
+		Name:         "device-code",
+		CreatedTime:  now.Format(time.RFC3339),
+		ExpireTime:   now.Add(time.Minute).Format(time.RFC3339),
+		Code:         "BCDFGHJK",
+		Application:  application.GetId(),
+		PollInterval: deviceCodeIntervalSeconds,
+	}
+	if err := addPendingAuth(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, tokenError, _ := pollPendingAuth(&Application{Owner: "admin", Name: "another-app"}, auth.Owner, auth.Name); tokenError == nil || tokenError.Error != InvalidGrant {
+		t.Errorf("another application should not poll the request: %v", tokenError)
+	}
+	if _, tokenError, _ := pollPendingAuth(application, auth.Owner, auth.Name); tokenError == nil || tokenError.Error != AuthorizationPending {
+		t.Errorf("the request should be pending: %v", tokenError)
+	}
+	if _, tokenError, _ := pollPendingAuth(application, auth.Owner, auth.Name); tokenError == nil || tokenError.Error != SlowDown {
+		t.Errorf("polling too fast should slow down: %v", tokenError)
+	}
+
+	if res, _ := GetDeviceAuthByUserCode(""); res != nil {
+		t.Errorf("an empty user code should not match any request")
+	}
+	res, err := GetDeviceAuthByUserCode("bcdf-ghjk")
+	if err != nil || res == nil || res.Code != "BCDF-GHJK" {
+		t.Fatalf("the request should be found by the user code: %v, %v", res, err)
+	}
+	if decided, err := ApproveDeviceAuth("BCDF-GHJK", "built-in/alice", true); err != nil || !decided {
+		t.Fatalf("the request should be approved: %v", err)
+	}
+	if decided, _ := ApproveDeviceAuth("BCDF-GHJK", "built-in/bob", true); decided {
+		t.Errorf("the user code should only be used once")
+	}
+
+	// let the concurrent polls pass the interval check
+	_, err = ormer.Engine.ID(core.PK{auth.Owner, auth.Name}).Cols("last_poll_time").Update(&PendingAuth{LastPollTime: ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testConsumeOnce(t, "the approved request", func() (bool, error) {
+		res, _, err := pollPendingAuth(application, auth.Owner, auth.Name)
+		return res != nil, err
+	})
+}
+
+func TestApproveCibaAuth(t *testing.T) {
+	initTestOrmer(t, &PendingAuth{})
+	application := &Application{Owner: "admin", Name: "app-ciba", BackchannelTokenDeliveryMode: CibaDeliveryModePoll}
+	now := time.Now()
+	for i, userId := range []string{"built-in/alice", "built-in/bob"} {
+		auth := &PendingAuth{
+			Owner:       PendingAuthTypeCiba,
+			Name:        "auth-req-id-" + userId,
+			CreatedTime: now.Add(time.Duration(i) * time.Second).Format(time.RFC3339),
+			ExpireTime:  now.Add(time.Minute).Format(time.RFC3339),
+			Code:        "id-" + userId,
+			Application: application.GetId(),
+			User:        userId,
+		}
+		if err := addPendingAuth(auth); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auths, err := GetCibaAuthsByUser("built-in/alice")
+	if err != nil || len(auths) != 1 || auths[0].Code != "id-built-in/alice" {
+		t.Fatalf("only the requests of the user should be listed: %v, %v", auths, err)
+	}
+
+	if decided, _ := ApproveCibaAuth(application, "id-built-in/alice", "built-in/bob", true); decided {
+		t.Errorf("another user should not approve the request")
+	}
+	if decided, _ := ApproveCibaAuth(&Application{Owner: "admin", Name: "another-app"}, "id-built-in/alice", "built-in/alice", true); decided {
+		t.Errorf("the request should not be approved through another application")
+	}
+	if decided, err := ApproveCibaAuth(application, "id-built-in/alice", "built-in/alice", true); err != nil || !decided {
+		t.Fatalf("the request should be approved: %v", err)
+	}
+	if auths, _ = GetCibaAuthsByUser("built-in/alice"); len(auths) != 0 {
+		t.Errorf("the approved request should not be listed: %v", auths)
+	}
+}
@@ -67,6 +67,16 @@ var (
 		Name: "casdoor_total_throughput",
 		Help: "The total throughput of casdoor",
 	})
+
+	PurgedRecords = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "casdoor_purged_records_total",
+		Help: "The number of expired records purged from each table",
+	}, []string{"table"})
+
+	PurgeTime = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "casdoor_purge_last_success_timestamp_seconds",
+		Help: "The time of the last successful purge of the expired data",
+	})
 )
 
 func ClearThroughputPerSecond() {
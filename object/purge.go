@@ -0,0 +1,312 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego"
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+)
+
+const (
+	defaultPurgeRetentionHours = 24
+	defaultPurgeBatchSize      = 1000
+)
+
+// PurgeResult is the number of the expired records purged from each table in a purge run
+type PurgeResult map[string]int64
+
+func (result PurgeResult) add(table string, count int64) {
+	if count == 0 {
+		return
+	}
+
+	result[table] += count
+	PurgedRecords.WithLabelValues(table).Add(float64(count))
+}
+
+// getPurgeInterval returns how often the expired data is purged, the purge is disabled when it's not configured
+func getPurgeInterval() time.Duration {
+	minutes, err := conf.GetConfigInt64("purgeIntervalMinutes")
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// getPurgeRetention returns how long the expired data is kept before it's purged
+func getPurgeRetention() time.Duration {
+	hours, err := conf.GetConfigInt64("purgeRetentionHours")
+	if err != nil || hours < 0 {
+		hours = defaultPurgeRetentionHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func getPurgeBatchSize() int {
+	res, err := strconv.Atoi(conf.GetConfigString("purgeBatchSize"))
+	if err != nil || res <= 0 {
+		res = defaultPurgeBatchSize
+	}
+	return res
+}
+
+func RunPurgeJob() {
+	interval := getPurgeInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := PurgeExpiredData()
+		if err != nil {
+			logs.Error(fmt.Sprintf("RunPurgeJob() error: %s", err.Error()))
+		}
+
+		<-ticker.C
+	}
+}
+
+// PurgeExpiredData deletes the tokens, verification records, session ids, CAS tickets and shared entries (such as
+// the SAML artifacts) that have expired for longer than the retention. The rows are deleted in batches, and archived
+// as JSON lines into purgeArchiveDir before the deletion if it's configured
+func PurgeExpiredData() (PurgeResult, error) {
+	result := PurgeResult{}
+	cutoff := time.Now().Add(-getPurgeRetention())
+
+	count, err := purgeExpiredTokens(cutoff)
+	result.add("token", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeExpiredCodes(cutoff)
+	result.add("token", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeExpiredVerificationRecords(cutoff)
+	result.add("verification_record", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeStaleSessionIds()
+	result.add("session", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeExpiredCasTickets(cutoff)
+	result.add("cas_ticket", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeExpiredSharedEntries(cutoff)
+	result.add("shared_entry", count)
+	if err != nil {
+		return result, err
+	}
+
+	count, err = purgeExpiredPendingAuths(cutoff)
+	result.add("pending_auth", count)
+	if err != nil {
+		return result, err
+	}
+
+	PurgeTime.SetToCurrentTime()
+	return result, nil
+}
+
+// getTokenPurgeCutoff returns the created time before which the tokens of the application are purged, both the
+// access token and the refresh token of them have expired by then
+func getTokenPurgeCutoff(application *Application, cutoff time.Time) time.Time {
+	expireInHours := application.ExpireInHours
+	if application.RefreshExpireInHours > expireInHours {
+		expireInHours = application.RefreshExpireInHours
+	}
+	return cutoff.Add(-time.Duration(expireInHours) * time.Hour)
+}
+
+func purgeExpiredTokens(cutoff time.Time) (int64, error) {
+	applications := []*Application{}
+	err := ormer.Engine.Cols("owner", "name", "expire_in_hours", "refresh_expire_in_hours").Find(&applications)
+	if err != nil {
+		return 0, err
+	}
+
+	var res int64
+	for _, application := range applications {
+		if application.ExpireInHours <= 0 {
+			continue
+		}
+
+		createdTime := getTokenPurgeCutoff(application, cutoff).Format(time.RFC3339)
+		count, err := purgeRecords(&Token{}, "token", "application = ? and created_time < ?", application.Name, createdTime)
+		res += count
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// purgeExpiredCodes purges the tokens whose authorization code has never been redeemed, they can't be redeemed any more
+func purgeExpiredCodes(cutoff time.Time) (int64, error) {
+	return purgeRecords(&Token{}, "token", "code_is_used = ? and code_expire_in < ?", false, cutoff.Unix())
+}
+
+func purgeExpiredVerificationRecords(cutoff time.Time) (int64, error) {
+	timeoutInMinutes, err := conf.GetConfigInt64("verificationCodeTimeout")
+	if err != nil {
+		timeoutInMinutes = 10
+	}
+
+	createdTime := cutoff.Add(-time.Duration(timeoutInMinutes) * time.Minute).Format(time.RFC3339)
+	return purgeRecords(&VerificationRecord{}, "verification_record", "created_time < ?", createdTime)
+}
+
+// purgeRecords deletes the rows of the table matching the condition in batches, the owner and name of the table
+// are its primary key
+func purgeRecords(bean interface{}, table string, query string, args ...interface{}) (int64, error) {
+	batchSize := getPurgeBatchSize()
+	archiveDir := conf.GetConfigString("purgeArchiveDir")
+
+	var res int64
+	for {
+		session := ormer.Engine.Table(bean).Where(query, args...).Limit(batchSize)
+		if archiveDir == "" {
+			session = session.Cols("owner", "name")
+		}
+		rows, err := session.QueryString()
+		if err != nil {
+			return res, err
+		}
+		if len(rows) == 0 {
+			return res, nil
+		}
+
+		if archiveDir != "" {
+			err = archiveRecords(archiveDir, table, rows)
+			if err != nil {
+				return res, err
+			}
+		}
+
+		names := map[string][]string{}
+		for _, row := range rows {
+			names[row["owner"]] = append(names[row["owner"]], row["name"])
+		}
+
+		var affected int64
+		for owner, ownerNames := range names {
+			count, err := ormer.Engine.Where(query, args...).And("owner = ?", owner).In("name", ownerNames).Delete(bean)
+			affected += count
+			if err != nil {
+				return res + affected, err
+			}
+		}
+
+		res += affected
+		if affected == 0 || len(rows) < batchSize {
+			return res, nil
+		}
+	}
+}
+
+// archiveRecords appends the rows to the archive file of the table for the day, one JSON object per line
+func archiveRecords(archiveDir string, table string, rows []map[string]string) error {
+	err := os.MkdirAll(archiveDir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(archiveDir, fmt.Sprintf("%s-%s.jsonl", table, time.Now().Format("20060102")))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, row := range rows {
+		err = encoder.Encode(row)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeStaleSessionIds removes the session ids whose Beego sessions no longer exist, and the sessions left with no ids
+func purgeStaleSessionIds() (int64, error) {
+	if beego.GlobalSessions == nil {
+		return 0, nil
+	}
+
+	provider := beego.GlobalSessions.GetProvider()
+	batchSize := getPurgeBatchSize()
+
+	var res int64
+	offset := 0
+	for {
+		sessions := []*Session{}
+		err := ormer.Engine.Asc("owner", "name", "application").Limit(batchSize, offset).Find(&sessions)
+		if err != nil {
+			return res, err
+		}
+
+		for _, session := range sessions {
+			sessionIds := []string{}
+			for _, sessionId := range session.SessionId {
+				if provider.SessionExist(sessionId) {
+					sessionIds = append(sessionIds, sessionId)
+				}
+			}
+			if len(sessionIds) == len(session.SessionId) {
+				offset++
+				continue
+			}
+
+			res += int64(len(session.SessionId) - len(sessionIds))
+			session.SessionId = sessionIds
+			if len(sessionIds) == 0 {
+				_, err = DeleteSession(session.GetId())
+			} else {
+				offset++
+				_, err = UpdateSession(session.GetId(), session)
+			}
+			if err != nil {
+				return res, err
+			}
+		}
+
+		if len(sessions) < batchSize {
+			return res, nil
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTokenPurgeCutoff(t *testing.T) {
+	cutoff := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	res := getTokenPurgeCutoff(&Application{ExpireInHours: 24, RefreshExpireInHours: 72}, cutoff)
+	if !res.Equal(cutoff.Add(-72 * time.Hour)) {
+		t.Errorf("getTokenPurgeCutoff() = %s, want the refresh token expiration", res)
+	}
+
+	res = getTokenPurgeCutoff(&Application{ExpireInHours: 24}, cutoff)
+	if !res.Equal(cutoff.Add(-24 * time.Hour)) {
+		t.Errorf("getTokenPurgeCutoff() = %s, want the access token expiration", res)
+	}
+}
+
+func TestPurgeExpiredCasTickets(t *testing.T) {
+	initTestOrmer(t, &CasTicket{})
+	store := &databaseCasTicketStore{}
+
+	expiredTicket := newCasTicket(CasTicketTypeService, "ST-expired", &CasAuthenticationSuccess{}, "https://app.example.com", "built-in/alice", true)
+	expiredTicket.ExpireTime = time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	validTicket := newCasTicket(CasTicketTypeService, "ST-valid", &CasAuthenticationSuccess{}, "https://app.example.com", "built-in/alice", true)
+	for _, ticket := range []*CasTicket{expiredTicket, validTicket} {
+		err := store.AddTicket(ticket)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := store.PurgeExpiredTickets(time.Now().Add(-time.Hour))
+	if err != nil || count != 1 {
+		t.Errorf("PurgeExpiredTickets() = %d, %v, want 1", count, err)
+	}
+	if ticket, _ := store.GetTicket("ST-valid"); ticket == nil {
+		t.Errorf("the ticket expiring after the cutoff should be kept")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisConsumeScript gets and deletes a key in one step, GETDEL does the same but needs Redis 6.2
+var redisConsumeScript = redis.NewScript(1, `
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value
+`)
+
+// newRedisPool returns the pool for the endpoint, which has the same format as the one of the Beego session:
+// "address,poolSize,password,dbNum"
+func newRedisPool(endpoint string) *redis.Pool {
+	tokens := strings.Split(endpoint, ",")
+	address := tokens[0]
+	poolSize := 100
+	options := []redis.DialOption{}
+	if len(tokens) > 1 {
+		if size, err := strconv.Atoi(tokens[1]); err == nil && size > 0 {
+			poolSize = size
+		}
+	}
+	if len(tokens) > 2 && tokens[2] != "" {
+		options = append(options, redis.DialPassword(tokens[2]))
+	}
+	if len(tokens) > 3 {
+		if db, err := strconv.Atoi(tokens[3]); err == nil {
+			options = append(options, redis.DialDatabase(db))
+		}
+	}
+
+	return &redis.Pool{
+		MaxIdle:     poolSize,
+		IdleTimeout: 180 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", address, options...)
+		},
+	}
+}
+
+// getRedisExpireSeconds returns the TTL of a key expiring at the given time, at least one second
+func getRedisExpireSeconds(expireTime time.Time) int64 {
+	seconds := int64(time.Until(expireTime).Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// consumeRedisKey returns the value of the key and deletes it atomically, nil is returned if the key doesn't exist
+// or another caller has consumed it
+func consumeRedisKey(pool *redis.Pool, key string) ([]byte, error) {
+	conn := pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(redisConsumeScript.Do(conn, key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return data, err
+}
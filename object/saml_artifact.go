@@ -0,0 +1,223 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+const (
+	SamlMethodArtifact  = "Artifact"
+	SamlBindingSoap     = "urn:oasis:names:tc:SAML:2.0:bindings:SOAP"
+	soapEnvelopeSpace   = "http://schemas.xmlsoap.org/soap/envelope/"
+	samlArtifactTimeout = 5 * time.Minute
+)
+
+// SamlArtifactResolve is the ArtifactResolve of SAML 2.0 core section 3.5.1
+type SamlArtifactResolve struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol ArtifactResolve"`
+	ID       string   `xml:"ID,attr"`
+	Version  string   `xml:"Version,attr"`
+	Issuer   string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Artifact string   `xml:"urn:oasis:names:tc:SAML:2.0:protocol Artifact"`
+}
+
+const samlArtifactOwner = "saml_artifact"
+
+// samlArtifactItem is the response an artifact refers to, it's kept as a shared entry and resolved only once
+type samlArtifactItem struct {
+	Application string `json:"application"`
+	Issuer      string `json:"issuer"`
+	Response    []byte `json:"response"`
+}
+
+func getSamlArtifactResolutionLocation(application *Application, host string) string {
+	_, originBackend := getOriginFromHost(host)
+	return fmt.Sprintf("%s/api/saml/artifact/%s/%s", originBackend, application.Owner, application.Name)
+}
+
+// newSamlArtifact stores the response and returns the type 0x0004 artifact referring to it, per SAML 2.0 bindings
+// section 3.6.4. The SourceID is the SHA-1 of the entity ID of the IdP
+func newSamlArtifact(application *Application, host string, issuer string, response []byte) (string, error) {
+	messageHandle := make([]byte, 20)
+	_, err := rand.Read(messageHandle)
+	if err != nil {
+		return "", err
+	}
+
+	sourceId := sha1.Sum([]byte(host))
+	data := []byte{0x00, 0x04, 0x00, 0x00}
+	data = append(data, sourceId[:]...)
+	data = append(data, messageHandle...)
+	artifact := base64.StdEncoding.EncodeToString(data)
+
+	item := &samlArtifactItem{
+		Application: application.GetId(),
+		Issuer:      issuer,
+		Response:    response,
+	}
+	_, err = addSharedEntry(samlArtifactOwner, artifact, item, time.Now().Add(samlArtifactTimeout))
+	if err != nil {
+		return "", err
+	}
+	return artifact, nil
+}
+
+// loadSamlArtifact returns the response the artifact refers to and removes it, so that the artifact can't be
+// resolved again by any instance
+func loadSamlArtifact(application *Application, issuer string, artifact string) ([]byte, error) {
+	item := samlArtifactItem{}
+	ok, err := consumeSharedEntry(samlArtifactOwner, artifact, &item)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if item.Application != application.GetId() {
+		return nil, nil
+	}
+	if item.Issuer != "" && item.Issuer != issuer {
+		return nil, nil
+	}
+	return item.Response, nil
+}
+
+// parseSamlArtifactResolve reads the ArtifactResolve from the SOAP body, its signature is verified with the SP
+// certificate if it's signed, and required if the SP signs its requests
+func parseSamlArtifactResolve(application *Application, body *etree.Element) (*SamlArtifactResolve, error) {
+	children := body.ChildElements()
+	if len(children) == 0 {
+		return nil, errors.New("the SOAP body is empty")
+	}
+
+	// the namespaces declared by the SOAP envelope are declared on the ArtifactResolve, so it can be verified alone
+	nsContext, err := etreeutils.NSBuildParentContext(children[0])
+	if err != nil {
+		return nil, err
+	}
+	element, err := etreeutils.NSDetatch(nsContext, children[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if element.FindElement("./Signature") != nil || application.SamlAuthnRequestsSigned {
+		if application.SamlSpCertificate == "" {
+			return nil, errors.New("the SAML SP certificate should be set to verify the signed ArtifactResolve")
+		}
+
+		certificate, err := getSamlSpCertificate(application)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+		element, err = ctx.Validate(element)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate the signature of the SAML ArtifactResolve: %s", err.Error())
+		}
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(element)
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var artifactResolve SamlArtifactResolve
+	err = xml.Unmarshal(data, &artifactResolve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the SAML ArtifactResolve: %s", err.Error())
+	}
+
+	if artifactResolve.Version != "2.0" {
+		return nil, fmt.Errorf("the SAML version: %s is not supported", artifactResolve.Version)
+	}
+	if !isSamlIssuerValid(application, artifactResolve.Issuer) {
+		return nil, fmt.Errorf("the issuer: %s doesn't exist in the allowed Redirect URI list", artifactResolve.Issuer)
+	}
+	return &artifactResolve, nil
+}
+
+// ResolveSamlArtifact handles the ArtifactResolve sent with the SOAP binding and returns the SOAP envelope of the
+// ArtifactResponse. Per SAML 2.0 core section 3.5.3, the response is left out if the artifact can't be resolved
+func ResolveSamlArtifact(application *Application, data []byte, host string) (string, error) {
+	doc := etree.NewDocument()
+	err := doc.ReadFromBytes(data)
+	if err != nil {
+		return "", err
+	}
+	if doc.Root() == nil || doc.Root().Tag != "Envelope" {
+		return "", errors.New("the request should be a SOAP envelope")
+	}
+
+	body := doc.Root().FindElement("./Body")
+	if body == nil {
+		return "", errors.New("the SOAP envelope doesn't have a body")
+	}
+
+	inResponseTo := ""
+	status := SamlStatusSuccess
+	var response []byte
+	artifactResolve, err := parseSamlArtifactResolve(application, body)
+	if err != nil {
+		status = SamlStatusRequester
+	} else {
+		inResponseTo = artifactResolve.ID
+		response, err = loadSamlArtifact(application, artifactResolve.Issuer, artifactResolve.Artifact)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	envelope := &etree.Element{Space: "soap", Tag: "Envelope"}
+	envelope.CreateAttr("xmlns:soap", soapEnvelopeSpace)
+	artifactResponse := envelope.CreateElement("soap:Body").CreateElement("samlp:ArtifactResponse")
+	artifactResponse.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	artifactResponse.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	artifactResponse.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	artifactResponse.CreateAttr("Version", "2.0")
+	artifactResponse.CreateAttr("IssueInstant", time.Now().UTC().Format(time.RFC3339))
+	if inResponseTo != "" {
+		artifactResponse.CreateAttr("InResponseTo", inResponseTo)
+	}
+	artifactResponse.CreateElement("saml:Issuer").SetText(originBackend)
+	artifactResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", status)
+
+	if response != nil {
+		responseDoc := etree.NewDocument()
+		err = responseDoc.ReadFromBytes(response)
+		if err != nil {
+			return "", err
+		}
+		artifactResponse.AddChild(responseDoc.Root())
+	}
+
+	envelopeDoc := etree.NewDocument()
+	envelopeDoc.SetRoot(envelope)
+	return envelopeDoc.WriteToString()
+}
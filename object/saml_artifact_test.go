@@ -0,0 +1,75 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testSamlArtifactResolve = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">
+  <soap:Body>
+    <samlp:ArtifactResolve ID="_resolve" Version="2.0" IssueInstant="2024-01-01T00:00:00Z">
+      <saml:Issuer>%s</saml:Issuer>
+      <samlp:Artifact>%s</samlp:Artifact>
+    </samlp:ArtifactResolve>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestResolveSamlArtifact(t *testing.T) {
+	initTestOrmer(t, &SharedEntry{})
+
+	application := &Application{Owner: "admin", Name: "app-saml", RedirectUris: []string{"https://sp.example.com"}}
+	host := "door.example.com"
+	response := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_response"/>`
+
+	artifact, err := newSamlArtifact(application, host, "https://sp.example.com", []byte(response))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(artifact)
+	if len(data) != 44 || data[1] != 0x04 {
+		t.Fatalf("unexpected artifact: %v", data)
+	}
+
+	// an unknown SP can't resolve the artifact
+	res, err := ResolveSamlArtifact(application, []byte(fmt.Sprintf(testSamlArtifactResolve, "https://other.example.com", artifact)), host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res, SamlStatusRequester) || strings.Contains(res, "_response") {
+		t.Errorf("the artifact should not be resolved for an unknown issuer: %s", res)
+	}
+
+	request := []byte(fmt.Sprintf(testSamlArtifactResolve, "https://sp.example.com", artifact))
+	res, err = ResolveSamlArtifact(application, request, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res, SamlStatusSuccess) || !strings.Contains(res, `InResponseTo="_resolve"`) || !strings.Contains(res, "_response") {
+		t.Errorf("unexpected ArtifactResponse: %s", res)
+	}
+
+	// the artifact is single-use
+	res, err = ResolveSamlArtifact(application, request, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(res, "_response") {
+		t.Errorf("the artifact should only be resolved once: %s", res)
+	}
+}
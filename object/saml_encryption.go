@@ -0,0 +1,104 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/russellhaering/gosaml2/types"
+)
+
+const (
+	xmlEncNamespace   = "http://www.w3.org/2001/04/xmlenc#"
+	xmlEncTypeElement = "http://www.w3.org/2001/04/xmlenc#Element"
+)
+
+// encryptSamlAssertion replaces the assertion of the response with an EncryptedAssertion for the encryption
+// certificate of the SP. The assertion is encrypted with a random AES-256-GCM key, which is encrypted with RSA-OAEP
+func encryptSamlAssertion(application *Application, samlResponse *etree.Element, assertion *etree.Element) error {
+	certificate, err := parseSamlCertificate(application, application.SamlSpEncryptionCertificate)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("the SAML SP encryption certificate should be an RSA certificate")
+	}
+
+	// the assertion is serialized on its own, so the namespaces declared by the response are declared on it
+	plainAssertion := assertion.Copy()
+	plainAssertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	doc := etree.NewDocument()
+	doc.SetRoot(plainAssertion)
+	plainText, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	cipherText := gcm.Seal(nonce, nonce, plainText, nil)
+
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt the key of the SAML assertion: %s", err.Error())
+	}
+
+	samlResponse.RemoveChild(assertion)
+	encryptedAssertion := samlResponse.CreateElement("saml:EncryptedAssertion")
+	encryptedData := encryptedAssertion.CreateElement("xenc:EncryptedData")
+	encryptedData.CreateAttr("xmlns:xenc", xmlEncNamespace)
+	encryptedData.CreateAttr("Type", xmlEncTypeElement)
+	encryptedData.CreateElement("xenc:EncryptionMethod").CreateAttr("Algorithm", types.MethodAES256GCM)
+
+	keyInfo := encryptedData.CreateElement("ds:KeyInfo")
+	keyInfo.CreateAttr("xmlns:ds", "http://www.w3.org/2000/09/xmldsig#")
+	encryptedKeyElement := keyInfo.CreateElement("xenc:EncryptedKey")
+	encryptionMethod := encryptedKeyElement.CreateElement("xenc:EncryptionMethod")
+	encryptionMethod.CreateAttr("Algorithm", types.MethodRSAOAEP)
+	encryptionMethod.CreateElement("ds:DigestMethod").CreateAttr("Algorithm", types.MethodSHA1)
+	encryptedKeyElement.CreateElement("ds:KeyInfo").CreateElement("ds:X509Data").CreateElement("ds:X509Certificate").SetText(base64.StdEncoding.EncodeToString(certificate.Raw))
+	encryptedKeyElement.CreateElement("xenc:CipherData").CreateElement("xenc:CipherValue").SetText(base64.StdEncoding.EncodeToString(encryptedKey))
+
+	encryptedData.CreateElement("xenc:CipherData").CreateElement("xenc:CipherValue").SetText(base64.StdEncoding.EncodeToString(cipherText))
+	return nil
+}
@@ -15,17 +15,17 @@
 package object
 
 import (
-	"bytes"
-	"compress/flate"
 	"crypto"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/beevik/etree"
@@ -35,9 +35,11 @@ import (
 	dsig "github.com/russellhaering/goxmldsig"
 )
 
+const samlAttributeUserPrefix = "$user."
+
 // NewSamlResponse
 // returns a saml2 response
-func NewSamlResponse(application *Application, user *User, host string, certificate string, destination string, iss string, requestId string, redirectUri []string) (*etree.Element, error) {
+func NewSamlResponse(application *Application, user *User, host string, certificate string, destination string, iss string, requestId string, redirectUri []string, sid string) (*etree.Element, error) {
 	samlResponse := &etree.Element{
 		Space: "samlp",
 		Tag:   "Response",
@@ -52,7 +54,9 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	samlResponse.CreateAttr("Version", "2.0")
 	samlResponse.CreateAttr("IssueInstant", now)
 	samlResponse.CreateAttr("Destination", destination)
-	samlResponse.CreateAttr("InResponseTo", requestId)
+	if requestId != "" {
+		samlResponse.CreateAttr("InResponseTo", requestId)
+	}
 	samlResponse.CreateElement("saml:Issuer").SetText(host)
 
 	samlResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Success")
@@ -69,11 +73,15 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	if application.UseEmailAsSamlNameId {
 		nameIDValue = user.Email
 	}
-	subject.CreateElement("saml:NameID").SetText(nameIDValue)
+	nameId := subject.CreateElement("saml:NameID")
+	nameId.CreateAttr("Format", getSamlNameIdFormat(application))
+	nameId.SetText(nameIDValue)
 	subjectConfirmation := subject.CreateElement("saml:SubjectConfirmation")
 	subjectConfirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
 	subjectConfirmationData := subjectConfirmation.CreateElement("saml:SubjectConfirmationData")
-	subjectConfirmationData.CreateAttr("InResponseTo", requestId)
+	if requestId != "" {
+		subjectConfirmationData.CreateAttr("InResponseTo", requestId)
+	}
 	subjectConfirmationData.CreateAttr("Recipient", destination)
 	subjectConfirmationData.CreateAttr("NotOnOrAfter", expireTime)
 	condition := assertion.CreateElement("saml:Conditions")
@@ -86,7 +94,7 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	}
 	authnStatement := assertion.CreateElement("saml:AuthnStatement")
 	authnStatement.CreateAttr("AuthnInstant", now)
-	authnStatement.CreateAttr("SessionIndex", fmt.Sprintf("_%s", uuid.New()))
+	authnStatement.CreateAttr("SessionIndex", getSamlSessionIndex(sid))
 	authnStatement.CreateAttr("SessionNotOnOrAfter", expireTime)
 	authnStatement.CreateElement("saml:AuthnContext").CreateElement("saml:AuthnContextClassRef").SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport")
 
@@ -107,17 +115,24 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 	displayName.CreateAttr("NameFormat", "urn:oasis:names:tc:SAML:2.0:attrname-format:basic")
 	displayName.CreateElement("saml:AttributeValue").CreateAttr("xsi:type", "xs:string").Element().SetText(user.DisplayName)
 
+	parameters, err := getSamlAttributeParameters(application, user)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, item := range application.SamlAttributes {
 		role := attributes.CreateElement("saml:Attribute")
 		role.CreateAttr("Name", item.Name)
 		role.CreateAttr("NameFormat", item.NameFormat)
-		role.CreateElement("saml:AttributeValue").CreateAttr("xsi:type", "xs:string").Element().SetText(item.Value)
+		for _, value := range getSamlAttributeValues(parameters, item.Value) {
+			role.CreateElement("saml:AttributeValue").CreateAttr("xsi:type", "xs:string").Element().SetText(value)
+		}
 	}
 
 	roles := attributes.CreateElement("saml:Attribute")
 	roles.CreateAttr("Name", "Roles")
 	roles.CreateAttr("NameFormat", "urn:oasis:names:tc:SAML:2.0:attrname-format:basic")
-	err := ExtendUserWithRolesAndPermissions(user)
+	err = ExtendUserWithRolesAndPermissions(user)
 	if err != nil {
 		return nil, err
 	}
@@ -126,9 +141,53 @@ func NewSamlResponse(application *Application, user *User, host string, certific
 		roles.CreateElement("saml:AttributeValue").CreateAttr("xsi:type", "xs:string").Element().SetText(role.Name)
 	}
 
+	if application.EnableSamlAssertionEncryption {
+		err = encryptSamlAssertion(application, samlResponse, assertion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return samlResponse, nil
 }
 
+// getSamlAttributeParameters returns the fields of the user referred to by the SAML attributes of the application,
+// they are only loaded if an attribute refers to them
+func getSamlAttributeParameters(application *Application, user *User) (claimParameters, error) {
+	for _, item := range application.SamlAttributes {
+		if strings.HasPrefix(item.Value, samlAttributeUserPrefix) {
+			return getClaimParameters(user)
+		}
+	}
+	return claimParameters{}, nil
+}
+
+// getSamlAttributeValues returns the values of a SAML attribute, a value of "$user.<field>" is the field of the user
+// with a value for each item of a list field, like "$user.roles", and the other values are literal
+func getSamlAttributeValues(parameters claimParameters, value string) []string {
+	if !strings.HasPrefix(value, samlAttributeUserPrefix) {
+		return []string{value}
+	}
+
+	field, err := parameters.Get(strings.TrimPrefix(value, samlAttributeUserPrefix))
+	if err != nil || field == nil {
+		return []string{}
+	}
+
+	items, ok := field.([]interface{})
+	if !ok {
+		items = []interface{}{field}
+	}
+
+	res := []string{}
+	for _, item := range items {
+		if text := fmt.Sprint(item); text != "" {
+			res = append(res, text)
+		}
+	}
+	return res
+}
+
 type X509Key struct {
 	X509Certificate string
 	PrivateKey      string
@@ -177,6 +236,8 @@ type IdpSSODescriptor struct {
 	XMLName                    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
 	ProtocolSupportEnumeration string   `xml:"protocolSupportEnumeration,attr"`
 	SigningKeyDescriptor       KeyDescriptor
+	ArtifactResolutionService  []ArtifactResolutionService
+	SingleLogoutService        []SingleLogoutService
 	NameIDFormats              []NameIDFormat      `xml:"NameIDFormat"`
 	SingleSignOnService        SingleSignOnService `xml:"SingleSignOnService"`
 	Attribute                  []Attribute         `xml:"Attribute"`
@@ -193,6 +254,17 @@ type SingleSignOnService struct {
 	Location string `xml:"Location,attr"`
 }
 
+type ArtifactResolutionService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+	Index    int    `xml:"index,attr"`
+}
+
+type SingleLogoutService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+}
+
 type Attribute struct {
 	// XMLName      xml.Name
 	Xmlns        string   `xml:"xmlns,attr"`
@@ -247,6 +319,10 @@ func GetSamlMeta(application *Application, host string, enablePostBinding bool)
 					},
 				},
 			},
+			ArtifactResolutionService: []ArtifactResolutionService{
+				{Binding: SamlBindingSoap, Location: getSamlArtifactResolutionLocation(application, host), Index: 0},
+			},
+			SingleLogoutService: getSamlLogoutServices(application, host),
 			NameIDFormats: []NameIDFormat{
 				{Value: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"},
 				{Value: "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"},
@@ -269,8 +345,9 @@ func GetSamlMeta(application *Application, host string, enablePostBinding bool)
 }
 
 // GetSamlResponse generates a SAML2.0 response
-// parameter samlRequest is saml request in base64 format
-func GetSamlResponse(application *Application, user *User, samlRequest string, host string) (string, string, string, error) {
+// parameter samlRequest is saml request in base64 format, samlQuery is the raw query of the HTTP-Redirect binding
+// which carries the signature of the request
+func GetSamlResponse(application *Application, user *User, samlRequest string, samlQuery string, host string, sid string) (string, string, string, error) {
 	// request type
 	method := "GET"
 
@@ -280,44 +357,36 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 		return "", "", "", fmt.Errorf("err: Failed to decode SAML request, %s", err.Error())
 	}
 
-	// decompress
-	var buffer bytes.Buffer
-	rdr := flate.NewReader(bytes.NewReader(defated))
+	// decompress, the request of the HTTP-POST binding is not compressed
+	data, err := inflateSamlMessage(defated)
+	isDeflated := err == nil
+	if !isDeflated {
+		data = defated
+	}
 
-	for {
-		_, err = io.CopyN(&buffer, rdr, 1024)
+	if application.SamlAuthnRequestsSigned {
+		data, err = verifySamlAuthnRequest(application, samlRequest, samlQuery, data, isDeflated)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
 			return "", "", "", err
 		}
 	}
 
 	var authnRequest saml.AuthNRequest
-	err = xml.Unmarshal(buffer.Bytes(), &authnRequest)
+	err = xml.Unmarshal(data, &authnRequest)
 	if err != nil {
 		return "", "", "", fmt.Errorf("err: Failed to unmarshal AuthnRequest, please check the SAML request, %s", err.Error())
 	}
 
 	// verify samlRequest
-	if isValid := application.IsRedirectUriValid(authnRequest.Issuer); !isValid {
+	if isValid := isSamlIssuerValid(application, authnRequest.Issuer); !isValid {
 		return "", "", "", fmt.Errorf("err: Issuer URI: %s doesn't exist in the allowed Redirect URI list", authnRequest.Issuer)
 	}
 
-	// get certificate string
-	cert, err := getCertByApplication(application)
+	cert, certificate, err := getSamlCert(application)
 	if err != nil {
 		return "", "", "", err
 	}
 
-	if cert.Certificate == "" {
-		return "", "", "", fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
-	}
-
-	block, _ := pem.Decode([]byte(cert.Certificate))
-	certificate := base64.StdEncoding.EncodeToString(block.Bytes)
-
 	// redirect Url (Assertion Consumer Url)
 	if application.SamlReplyUrl != "" {
 		method = "POST"
@@ -329,11 +398,90 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 	_, originBackend := getOriginFromHost(host)
 
 	// build signedResponse
-	samlResponse, err := NewSamlResponse(application, user, originBackend, certificate, authnRequest.AssertionConsumerServiceURL, authnRequest.Issuer, authnRequest.ID, application.RedirectUris)
+	samlResponse, err := NewSamlResponse(application, user, originBackend, certificate, authnRequest.AssertionConsumerServiceURL, authnRequest.Issuer, authnRequest.ID, application.RedirectUris, sid)
+	if err != nil {
+		return "", "", "", fmt.Errorf("err: NewSamlResponse() error, %s", err.Error())
+	}
+
+	res, err := encodeSamlResponse(application, samlResponse, cert, certificate, authnRequest.Issuer, originBackend)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if application.EnableSamlArtifactBinding {
+		method = SamlMethodArtifact
+	}
+	return res, authnRequest.AssertionConsumerServiceURL, method, nil
+}
+
+// GetSamlIdpInitiatedResponse generates an unsolicited SAML2.0 response for the IdP-initiated SSO, it's sent to the
+// SAML reply URL of the application as there is no AuthnRequest
+func GetSamlIdpInitiatedResponse(application *Application, user *User, host string, sid string) (string, string, string, error) {
+	if application.SamlReplyUrl == "" {
+		return "", "", "", fmt.Errorf("the SAML reply URL of the application: %s should be set for the IdP-initiated SSO", application.Name)
+	}
+
+	cert, certificate, err := getSamlCert(application)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	audience := application.SamlSpEntityId
+	if audience == "" {
+		audience = application.SamlReplyUrl
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	samlResponse, err := NewSamlResponse(application, user, originBackend, certificate, application.SamlReplyUrl, audience, "", application.RedirectUris, sid)
 	if err != nil {
 		return "", "", "", fmt.Errorf("err: NewSamlResponse() error, %s", err.Error())
 	}
 
+	res, err := encodeSamlResponse(application, samlResponse, cert, certificate, audience, originBackend)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	method := "POST"
+	if application.EnableSamlArtifactBinding {
+		method = SamlMethodArtifact
+	}
+	return res, application.SamlReplyUrl, method, nil
+}
+
+// GetSamlIdpInitiatedRelayState returns the RelayState sent with the unsolicited response, the one of the request
+// takes precedence over the one of the application
+func GetSamlIdpInitiatedRelayState(application *Application, relayState string) string {
+	if relayState != "" {
+		return relayState
+	}
+	return application.SamlIdpInitiatedRelayState
+}
+
+func getSamlCert(application *Application) (*Cert, string, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cert == nil {
+		return nil, "", errors.New("please set a cert for the application first")
+	}
+
+	if cert.Certificate == "" {
+		return nil, "", fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
+	}
+
+	block, _ := pem.Decode([]byte(cert.Certificate))
+	if block == nil {
+		return nil, "", fmt.Errorf("the certificate of the cert: %s is invalid", cert.Name)
+	}
+	return cert, base64.StdEncoding.EncodeToString(block.Bytes), nil
+}
+
+// encodeSamlResponse signs the response and encodes it for the binding of the application, it's either the base64
+// response or the artifact referring to it for the HTTP-Artifact binding
+func encodeSamlResponse(application *Application, samlResponse *etree.Element, cert *Cert, certificate string, issuer string, host string) (string, error) {
 	randomKeyStore := &X509Key{
 		PrivateKey:      cert.PrivateKey,
 		X509Certificate: certificate,
@@ -341,7 +489,9 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 	ctx := dsig.NewDefaultSigningContext(randomKeyStore)
 	ctx.Hash = crypto.SHA1
 
-	if application.EnableSamlC14n10 {
+	// the response of the HTTP-Artifact binding is embedded in the ArtifactResponse, so the canonicalization must not
+	// depend on the namespaces of its ancestors
+	if application.EnableSamlC14n10 || application.EnableSamlArtifactBinding {
 		ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
 	}
 
@@ -352,7 +502,7 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 
 	sig, err := ctx.ConstructSignature(samlResponse, true)
 	if err != nil {
-		return "", "", "", fmt.Errorf("err: Failed to serializes the SAML request into bytes, %s", err.Error())
+		return "", fmt.Errorf("err: Failed to serializes the SAML request into bytes, %s", err.Error())
 	}
 
 	samlResponse.InsertChildAt(1, sig)
@@ -361,32 +511,71 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 	doc.SetRoot(samlResponse)
 	xmlBytes, err := doc.WriteToBytes()
 	if err != nil {
-		return "", "", "", fmt.Errorf("err: Failed to serializes the SAML request into bytes, %s", err.Error())
+		return "", fmt.Errorf("err: Failed to serializes the SAML request into bytes, %s", err.Error())
+	}
+
+	if application.EnableSamlArtifactBinding {
+		return newSamlArtifact(application, host, issuer, xmlBytes)
 	}
 
 	// compress
 	if application.EnableSamlCompress {
-		flated := bytes.NewBuffer(nil)
-		writer, err := flate.NewWriter(flated, flate.DefaultCompression)
+		xmlBytes, err = deflateSamlMessage(xmlBytes)
 		if err != nil {
-			return "", "", "", err
+			return "", err
 		}
+	}
+	// base64 encode
+	return base64.StdEncoding.EncodeToString(xmlBytes), nil
+}
 
-		_, err = writer.Write(xmlBytes)
+// verifySamlAuthnRequest verifies the signature of the AuthnRequest with the signing certificate of the SP, it's in
+// the query for the HTTP-Redirect binding and enveloped in the request for the HTTP-POST binding. The request that
+// is covered by the signature is returned
+func verifySamlAuthnRequest(application *Application, samlRequest string, samlQuery string, data []byte, isDeflated bool) ([]byte, error) {
+	if application.SamlSpCertificate == "" {
+		return nil, errors.New("the SAML SP certificate should be set to verify the signed AuthnRequest")
+	}
+
+	certificate, err := getSamlSpCertificate(application)
+	if err != nil {
+		return nil, err
+	}
+
+	if isDeflated {
+		queryRequest, err := url.QueryUnescape(getRawQueryValue(samlQuery, "SAMLRequest"))
 		if err != nil {
-			return "", "", "", err
+			return nil, err
+		}
+		if queryRequest != samlRequest {
+			return nil, errors.New("the SAML AuthnRequest is not the one in the signed query")
 		}
 
-		err = writer.Close()
+		err = verifySamlRedirectSignature(certificate, samlQuery, "SAMLRequest")
 		if err != nil {
-			return "", "", "", err
+			return nil, fmt.Errorf("failed to validate the signature of the SAML AuthnRequest: %s", err.Error())
 		}
+		return data, nil
+	}
 
-		xmlBytes = flated.Bytes()
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(data)
+	if err != nil {
+		return nil, err
 	}
-	// base64 encode
-	res := base64.StdEncoding.EncodeToString(xmlBytes)
-	return res, authnRequest.AssertionConsumerServiceURL, method, err
+	if doc.Root() == nil {
+		return nil, errors.New("the SAML AuthnRequest is empty")
+	}
+
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+	validated, err := ctx.Validate(doc.Root())
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate the signature of the SAML AuthnRequest: %s", err.Error())
+	}
+
+	validatedDoc := etree.NewDocument()
+	validatedDoc.SetRoot(validated)
+	return validatedDoc.WriteToBytes()
 }
 
 // NewSamlResponse11 return a saml1.1 response(not 2.0)
@@ -483,3 +672,10 @@ func GetSamlRedirectAddress(owner string, application string, relayState string,
 	originF, _ := getOriginFromHost(host)
 	return fmt.Sprintf("%s/login/saml/authorize/%s/%s?relayState=%s&samlRequest=%s", originF, owner, application, relayState, samlRequest)
 }
+
+// GetSamlIdpInitiatedAddress returns the login page of the IdP-initiated SSO, the signed-in user is sent to the
+// application without being asked to sign in again
+func GetSamlIdpInitiatedAddress(owner string, application string, relayState string, host string) string {
+	originF, _ := getOriginFromHost(host)
+	return fmt.Sprintf("%s/login/saml/authorize/%s/%s?idpInitiated=1&silentSignin=1&RelayState=%s", originF, owner, application, url.QueryEscape(relayState))
+}
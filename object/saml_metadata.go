@@ -0,0 +1,241 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+	saml "github.com/russellhaering/gosaml2"
+	"github.com/russellhaering/gosaml2/types"
+)
+
+const (
+	SamlNameIdFormatUnspecified  = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	SamlNameIdFormatEmailAddress = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	SamlNameIdFormatPersistent   = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+)
+
+// the metadata of an SP is small, anything larger is not worth parsing
+const samlMetadataMaxSize = 1 << 20
+
+type samlEntitiesDescriptor struct {
+	XMLName           xml.Name                 `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntitiesDescriptor"`
+	EntityDescriptors []types.EntityDescriptor `xml:"EntityDescriptor"`
+}
+
+// fetchSamlSpMetadata downloads the metadata of an SP, the URL goes through the same check as the URIs that the
+// registered clients make Casdoor call, so it can't be used to reach the internal network
+func fetchSamlSpMetadata(metadataUrl string) ([]byte, error) {
+	err := checkOutboundUri(metadataUrl)
+	if err != nil {
+		return nil, fmt.Errorf("the SAML metadata URL is invalid: %s", err.Error())
+	}
+
+	resp, err := proxy.DefaultHttpClient.Get(metadataUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the SAML metadata from: %s, status: %s", metadataUrl, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, samlMetadataMaxSize))
+}
+
+// ParseSamlSpMetadata parses the metadata of an SP, it's either an EntityDescriptor or an EntitiesDescriptor in which
+// the first entity with an SPSSODescriptor is used
+func ParseSamlSpMetadata(data []byte) (*types.EntityDescriptor, error) {
+	entity := &types.EntityDescriptor{}
+	err := xml.Unmarshal(data, entity)
+	if err != nil {
+		entities := &samlEntitiesDescriptor{}
+		if xml.Unmarshal(data, entities) != nil {
+			return nil, fmt.Errorf("the SAML metadata is invalid: %s", err.Error())
+		}
+
+		entity = nil
+		for i := range entities.EntityDescriptors {
+			if entities.EntityDescriptors[i].SPSSODescriptor != nil {
+				entity = &entities.EntityDescriptors[i]
+				break
+			}
+		}
+	}
+
+	if entity == nil || entity.SPSSODescriptor == nil {
+		return nil, errors.New("the SAML metadata doesn't contain an SPSSODescriptor")
+	}
+	if entity.EntityID == "" {
+		return nil, errors.New("the entityID of the SAML metadata should not be empty")
+	}
+	return entity, nil
+}
+
+// getSamlSpCertificates returns the base64 DER signing and encryption certificates of the SP, a KeyDescriptor without
+// use is for both
+func getSamlSpCertificates(descriptor *types.SPSSODescriptor) (string, string) {
+	signingCertificate := ""
+	encryptionCertificate := ""
+	for _, keyDescriptor := range descriptor.KeyDescriptors {
+		if len(keyDescriptor.KeyInfo.X509Data.X509Certificates) == 0 {
+			continue
+		}
+
+		certificate := strings.Join(strings.Fields(keyDescriptor.KeyInfo.X509Data.X509Certificates[0].Data), "")
+		if signingCertificate == "" && keyDescriptor.Use != "encryption" {
+			signingCertificate = certificate
+		}
+		if encryptionCertificate == "" && keyDescriptor.Use != "signing" {
+			encryptionCertificate = certificate
+		}
+	}
+	return signingCertificate, encryptionCertificate
+}
+
+func hasSamlEncryptionKey(descriptor *types.SPSSODescriptor) bool {
+	for _, keyDescriptor := range descriptor.KeyDescriptors {
+		if keyDescriptor.Use == "encryption" {
+			return true
+		}
+	}
+	return false
+}
+
+// getSamlAssertionConsumerServices returns the locations of the HTTP-POST ACS endpoints of the SP ordered by index
+func getSamlAssertionConsumerServices(descriptor *types.SPSSODescriptor) []string {
+	services := []types.IndexedEndpoint{}
+	for _, service := range descriptor.AssertionConsumerServices {
+		if service.Binding == saml.BindingHttpPost && service.Location != "" {
+			services = append(services, service)
+		}
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		return services[i].Index < services[j].Index
+	})
+
+	res := []string{}
+	for _, service := range services {
+		res = append(res, service.Location)
+	}
+	return res
+}
+
+func getSamlSpLogoutUrl(descriptor *types.SPSSODescriptor) string {
+	for _, service := range descriptor.SingleLogoutServices {
+		if service.Binding == saml.BindingHttpRedirect {
+			return service.Location
+		}
+	}
+	return ""
+}
+
+// ImportSamlSpMetadata fills the SAML settings of the application from the metadata of the SP, which is either the
+// XML or fetched from the URL. The application is not saved
+func ImportSamlSpMetadata(application *Application, metadataUrl string, metadata string) error {
+	data := []byte(metadata)
+	if metadata == "" {
+		if metadataUrl == "" {
+			return errors.New("the SAML metadata and its URL should not be both empty")
+		}
+
+		var err error
+		data, err = fetchSamlSpMetadata(metadataUrl)
+		if err != nil {
+			return err
+		}
+	}
+
+	entity, err := ParseSamlSpMetadata(data)
+	if err != nil {
+		return err
+	}
+	descriptor := entity.SPSSODescriptor
+
+	signingCertificate, encryptionCertificate := getSamlSpCertificates(descriptor)
+	if signingCertificate != "" {
+		_, err = parseSamlCertificate(application, signingCertificate)
+		if err != nil {
+			return err
+		}
+	}
+	if encryptionCertificate != "" {
+		_, err = parseSamlCertificate(application, encryptionCertificate)
+		if err != nil {
+			return err
+		}
+	}
+
+	application.SamlSpMetadataUrl = metadataUrl
+	application.SamlSpEntityId = entity.EntityID
+	application.SamlSpCertificate = signingCertificate
+	application.SamlSpEncryptionCertificate = encryptionCertificate
+	application.EnableSamlAssertionEncryption = encryptionCertificate != "" && hasSamlEncryptionKey(descriptor)
+	application.SamlAuthnRequestsSigned = descriptor.AuthnRequestsSigned
+
+	application.SamlNameIdFormats = []string{}
+	for _, format := range descriptor.NameIDFormats {
+		application.SamlNameIdFormats = append(application.SamlNameIdFormats, strings.TrimSpace(format))
+	}
+	if len(application.SamlNameIdFormats) != 0 {
+		application.UseEmailAsSamlNameId = application.SamlNameIdFormats[0] == SamlNameIdFormatEmailAddress
+	}
+
+	services := getSamlAssertionConsumerServices(descriptor)
+	if len(services) != 0 {
+		application.SamlReplyUrl = services[0]
+	}
+	for _, service := range services {
+		if !util.InSlice(application.RedirectUris, service) {
+			application.RedirectUris = append(application.RedirectUris, service)
+		}
+	}
+
+	if logoutUrl := getSamlSpLogoutUrl(descriptor); logoutUrl != "" {
+		application.SamlLogoutUrl = logoutUrl
+	}
+	return nil
+}
+
+// isSamlIssuerValid checks the issuer of a message of the SP, it's either the imported entity ID or one of the
+// redirect URIs
+func isSamlIssuerValid(application *Application, issuer string) bool {
+	if application.SamlSpEntityId != "" && issuer == application.SamlSpEntityId {
+		return true
+	}
+	return application.IsRedirectUriValid(issuer)
+}
+
+// getSamlNameIdFormat returns the format of the NameID issued to the SP, the email address if it's used as the NameID,
+// otherwise the user name which is persistent if the SP accepts it
+func getSamlNameIdFormat(application *Application) string {
+	if application.UseEmailAsSamlNameId {
+		return SamlNameIdFormatEmailAddress
+	}
+
+	if util.InSlice(application.SamlNameIdFormats, SamlNameIdFormatPersistent) {
+		return SamlNameIdFormatPersistent
+	}
+	return SamlNameIdFormatUnspecified
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/russellhaering/gosaml2/types"
+)
+
+const testSamlSpMetadata = `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://sp.example.com/metadata">
+  <md:SPSSODescriptor AuthnRequestsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="encryption">
+      <ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://sp.example.com/slo"/>
+    <md:NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress</md:NameIDFormat>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs2" index="2"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs1" index="1"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>`
+
+func TestImportSamlSpMetadata(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	application := &Application{Owner: "admin", Name: "app-saml", RedirectUris: []string{"https://sp.example.com/acs1"}}
+	err = ImportSamlSpMetadata(application, "", fmt.Sprintf(testSamlSpMetadata, base64.StdEncoding.EncodeToString(der)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if application.SamlSpEntityId != "https://sp.example.com/metadata" || !isSamlIssuerValid(application, "https://sp.example.com/metadata") {
+		t.Errorf("unexpected entity ID: %s", application.SamlSpEntityId)
+	}
+	if application.SamlReplyUrl != "https://sp.example.com/acs1" || len(application.RedirectUris) != 2 {
+		t.Errorf("unexpected ACS: %s, %v", application.SamlReplyUrl, application.RedirectUris)
+	}
+	if application.SamlLogoutUrl != "https://sp.example.com/slo" {
+		t.Errorf("unexpected logout URL: %s", application.SamlLogoutUrl)
+	}
+	if application.SamlSpCertificate != "" || application.SamlSpEncryptionCertificate == "" || !application.EnableSamlAssertionEncryption {
+		t.Errorf("the encryption key should only be used for encryption")
+	}
+	if !application.SamlAuthnRequestsSigned || !application.UseEmailAsSamlNameId {
+		t.Errorf("AuthnRequestsSigned and the NameID format should be imported")
+	}
+
+	samlResponse := &etree.Element{Space: "samlp", Tag: "Response"}
+	assertion := samlResponse.CreateElement("saml:Assertion")
+	assertion.CreateElement("saml:Subject").CreateElement("saml:NameID").SetText("alice@example.com")
+	err = encryptSamlAssertion(application, samlResponse, assertion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if samlResponse.FindElement("./saml:Assertion") != nil {
+		t.Fatal("the plain assertion should be removed")
+	}
+
+	encryptedElement := samlResponse.FindElement("./saml:EncryptedAssertion")
+	encryptedElement.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	doc := etree.NewDocument()
+	doc.SetRoot(encryptedElement.Copy())
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedAssertion := &types.EncryptedAssertion{}
+	err = xml.Unmarshal(data, encryptedAssertion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainText, err := encryptedAssertion.DecryptBytes(&tls.Certificate{Certificate: [][]byte{der}, PrivateKey: privateKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(plainText), "alice@example.com") {
+		t.Errorf("unexpected decrypted assertion: %s", plainText)
+	}
+}
@@ -0,0 +1,459 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	saml "github.com/russellhaering/gosaml2"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	SamlStatusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	SamlStatusRequester = "urn:oasis:names:tc:SAML:2.0:status:Requester"
+)
+
+// SamlLogoutRequest is the LogoutRequest of SAML 2.0 core section 3.7.1
+type SamlLogoutRequest struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+	ID           string   `xml:"ID,attr"`
+	Version      string   `xml:"Version,attr"`
+	IssueInstant string   `xml:"IssueInstant,attr"`
+	Destination  string   `xml:"Destination,attr"`
+	NotOnOrAfter string   `xml:"NotOnOrAfter,attr"`
+	Issuer       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameId       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SessionIndex []string `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+}
+
+// getSamlSessionIndex returns the SessionIndex of the assertions issued in a browser session, so that a LogoutRequest
+// can refer to the session
+func getSamlSessionIndex(sid string) string {
+	if sid == "" {
+		return fmt.Sprintf("_%s", uuid.New())
+	}
+	return fmt.Sprintf("_%s", sid)
+}
+
+func getSamlLogoutLocation(application *Application, host string) string {
+	_, originBackend := getOriginFromHost(host)
+	return fmt.Sprintf("%s/api/saml/slo/%s/%s", originBackend, application.Owner, application.Name)
+}
+
+func getSamlNameId(application *Application, user *User) string {
+	if application.UseEmailAsSamlNameId {
+		return user.Email
+	}
+	return user.Name
+}
+
+func getSamlSpCertificate(application *Application) (*x509.Certificate, error) {
+	return parseSamlCertificate(application, application.SamlSpCertificate)
+}
+
+// parseSamlCertificate parses a certificate of the SP, it can be either PEM or the base64 DER of the SP metadata
+func parseSamlCertificate(application *Application, certificate string) (*x509.Certificate, error) {
+	data := []byte(certificate)
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	} else {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(certificate), ""))
+		if err != nil {
+			return nil, fmt.Errorf("the SAML SP certificate of the application: %s is invalid: %s", application.Name, err.Error())
+		}
+		data = der
+	}
+
+	return x509.ParseCertificate(data)
+}
+
+func inflateSamlMessage(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	rdr := flate.NewReader(bytes.NewReader(data))
+	defer rdr.Close()
+
+	for {
+		_, err := io.CopyN(&buffer, rdr, 1024)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func deflateSamlMessage(data []byte) ([]byte, error) {
+	flated := bytes.NewBuffer(nil)
+	writer, err := flate.NewWriter(flated, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return flated.Bytes(), nil
+}
+
+// getRawQueryValue returns the value of the parameter as it's encoded in the query, which is what the HTTP-Redirect
+// binding signs
+func getRawQueryValue(rawQuery string, key string) string {
+	for _, item := range strings.Split(rawQuery, "&") {
+		if strings.HasPrefix(item, key+"=") {
+			return item[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+func getSamlSigAlgHash(sigAlg string) (crypto.Hash, error) {
+	switch sigAlg {
+	case dsig.RSASHA1SignatureMethod:
+		return crypto.SHA1, nil
+	case dsig.RSASHA256SignatureMethod:
+		return crypto.SHA256, nil
+	case dsig.RSASHA512SignatureMethod:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("the SAML signature algorithm: %s is not supported", sigAlg)
+	}
+}
+
+// verifySamlRedirectSignature verifies the signature of a message of the HTTP-Redirect binding, per SAML 2.0 bindings
+// section 3.4.4.1
+func verifySamlRedirectSignature(certificate *x509.Certificate, rawQuery string, param string) error {
+	signature := getRawQueryValue(rawQuery, "Signature")
+	sigAlg := getRawQueryValue(rawQuery, "SigAlg")
+	if signature == "" || sigAlg == "" {
+		return errors.New("the SAML message should be signed")
+	}
+
+	signedQuery := fmt.Sprintf("%s=%s", param, getRawQueryValue(rawQuery, param))
+	if relayState := getRawQueryValue(rawQuery, "RelayState"); relayState != "" {
+		signedQuery = fmt.Sprintf("%s&RelayState=%s", signedQuery, relayState)
+	}
+	signedQuery = fmt.Sprintf("%s&SigAlg=%s", signedQuery, sigAlg)
+
+	decodedSigAlg, err := url.QueryUnescape(sigAlg)
+	if err != nil {
+		return err
+	}
+	hash, err := getSamlSigAlgHash(decodedSigAlg)
+	if err != nil {
+		return err
+	}
+
+	decodedSignature, err := url.QueryUnescape(signature)
+	if err != nil {
+		return err
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(decodedSignature)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("the SAML SP certificate should have an RSA public key")
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signedQuery))
+	return rsa.VerifyPKCS1v15(publicKey, hash, hasher.Sum(nil), signatureBytes)
+}
+
+// ParseSamlLogoutRequest decodes the LogoutRequest of the SP sent with the HTTP-POST or HTTP-Redirect binding, the
+// signature of it is required and verified if the SP certificate of the application is set
+func ParseSamlLogoutRequest(application *Application, samlRequest string, rawQuery string, isPostBinding bool, host string) (*SamlLogoutRequest, error) {
+	data, err := base64.StdEncoding.DecodeString(samlRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the SAML LogoutRequest: %s", err.Error())
+	}
+
+	var certificate *x509.Certificate
+	if application.SamlSpCertificate != "" {
+		certificate, err = getSamlSpCertificate(application)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isPostBinding {
+		if certificate != nil {
+			doc := etree.NewDocument()
+			err = doc.ReadFromBytes(data)
+			if err != nil {
+				return nil, err
+			}
+			if doc.Root() == nil {
+				return nil, errors.New("the SAML LogoutRequest is empty")
+			}
+
+			ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+			validated, err := ctx.Validate(doc.Root())
+			if err != nil {
+				return nil, fmt.Errorf("failed to validate the signature of the SAML LogoutRequest: %s", err.Error())
+			}
+
+			validatedDoc := etree.NewDocument()
+			validatedDoc.SetRoot(validated)
+			data, err = validatedDoc.WriteToBytes()
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		data, err = inflateSamlMessage(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress the SAML LogoutRequest: %s", err.Error())
+		}
+
+		if certificate != nil {
+			err = verifySamlRedirectSignature(certificate, rawQuery, "SAMLRequest")
+			if err != nil {
+				return nil, fmt.Errorf("failed to validate the signature of the SAML LogoutRequest: %s", err.Error())
+			}
+		}
+	}
+
+	var logoutRequest SamlLogoutRequest
+	err = xml.Unmarshal(data, &logoutRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the SAML LogoutRequest: %s", err.Error())
+	}
+
+	if logoutRequest.Version != "2.0" {
+		return nil, fmt.Errorf("the SAML version: %s is not supported", logoutRequest.Version)
+	}
+	if !isSamlIssuerValid(application, logoutRequest.Issuer) {
+		return nil, fmt.Errorf("the issuer: %s doesn't exist in the allowed Redirect URI list", logoutRequest.Issuer)
+	}
+	if logoutRequest.Destination != "" && logoutRequest.Destination != getSamlLogoutLocation(application, host) {
+		return nil, fmt.Errorf("the destination: %s of the SAML LogoutRequest is invalid", logoutRequest.Destination)
+	}
+	if logoutRequest.NotOnOrAfter != "" {
+		notOnOrAfter, err := time.Parse(time.RFC3339, logoutRequest.NotOnOrAfter)
+		if err == nil && !time.Now().Before(notOnOrAfter) {
+			return nil, errors.New("the SAML LogoutRequest has expired")
+		}
+	}
+
+	return &logoutRequest, nil
+}
+
+// IsForSession checks whether the LogoutRequest refers to the browser session of the user, a request without
+// SessionIndex refers to all sessions of the user
+func (logoutRequest *SamlLogoutRequest) IsForSession(application *Application, user *User, sessionId string) bool {
+	if logoutRequest.NameId != getSamlNameId(application, user) {
+		return false
+	}
+	if len(logoutRequest.SessionIndex) == 0 {
+		return true
+	}
+
+	for _, sessionIndex := range logoutRequest.SessionIndex {
+		if sessionIndex == getSamlSessionIndex(GetOidcSid(sessionId)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSamlSigningKey returns the signing context of the application cert for the signatures of the HTTP-POST binding,
+// and the private key for the ones of the HTTP-Redirect binding
+func getSamlSigningKey(application *Application) (*dsig.SigningContext, *rsa.PrivateKey, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cert == nil {
+		return nil, nil, errors.New("please set a cert for the application first")
+	}
+	if cert.Certificate == "" {
+		return nil, nil, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
+	}
+
+	block, _ := pem.Decode([]byte(cert.Certificate))
+	if block == nil {
+		return nil, nil, fmt.Errorf("the certificate of the cert: %s is invalid", cert.Name)
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx := dsig.NewDefaultSigningContext(&X509Key{
+		PrivateKey:      cert.PrivateKey,
+		X509Certificate: base64.StdEncoding.EncodeToString(block.Bytes),
+	})
+	ctx.Hash = crypto.SHA1
+	if application.EnableSamlC14n10 {
+		ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	}
+
+	return ctx, privateKey, nil
+}
+
+// getSamlRedirectUrl encodes the message with the HTTP-Redirect binding and signs the query with RSA-SHA256
+func getSamlRedirectUrl(location string, param string, message *etree.Element, relayState string, privateKey *rsa.PrivateKey) (string, error) {
+	doc := etree.NewDocument()
+	doc.SetRoot(message)
+	xmlBytes, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	deflated, err := deflateSamlMessage(xmlBytes)
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("%s=%s", param, url.QueryEscape(base64.StdEncoding.EncodeToString(deflated)))
+	if relayState != "" {
+		query = fmt.Sprintf("%s&RelayState=%s", query, url.QueryEscape(relayState))
+	}
+	query = fmt.Sprintf("%s&SigAlg=%s", query, url.QueryEscape(dsig.RSASHA256SignatureMethod))
+
+	hasher := crypto.SHA256.New()
+	hasher.Write([]byte(query))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hasher.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	query = fmt.Sprintf("%s&Signature=%s", query, url.QueryEscape(base64.StdEncoding.EncodeToString(signature)))
+
+	sep := "?"
+	if strings.Contains(location, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s%s", location, sep, query), nil
+}
+
+func newSamlLogoutResponse(host string, destination string, inResponseTo string, status string) *etree.Element {
+	_, originBackend := getOriginFromHost(host)
+
+	logoutResponse := &etree.Element{
+		Space: "samlp",
+		Tag:   "LogoutResponse",
+	}
+	logoutResponse.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	logoutResponse.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	logoutResponse.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	logoutResponse.CreateAttr("Version", "2.0")
+	logoutResponse.CreateAttr("IssueInstant", time.Now().UTC().Format(time.RFC3339))
+	logoutResponse.CreateAttr("Destination", destination)
+	logoutResponse.CreateAttr("InResponseTo", inResponseTo)
+	logoutResponse.CreateElement("saml:Issuer").SetText(originBackend)
+	logoutResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", status)
+	return logoutResponse
+}
+
+// GetSamlLogoutResponse returns the signed LogoutResponse to the LogoutRequest of the SP, sent back to the SAML logout
+// URL of the application with the binding of the request: the URL for the HTTP-Redirect binding, or the base64
+// SAMLResponse to be posted for the HTTP-POST binding
+func GetSamlLogoutResponse(application *Application, logoutRequest *SamlLogoutRequest, host string, relayState string, status string, isPostBinding bool) (string, error) {
+	if application.SamlLogoutUrl == "" {
+		return "", fmt.Errorf("the SAML logout URL of the application: %s should not be empty", application.Name)
+	}
+
+	ctx, privateKey, err := getSamlSigningKey(application)
+	if err != nil {
+		return "", err
+	}
+
+	logoutResponse := newSamlLogoutResponse(host, application.SamlLogoutUrl, logoutRequest.ID, status)
+	if !isPostBinding {
+		return getSamlRedirectUrl(application.SamlLogoutUrl, "SAMLResponse", logoutResponse, relayState, privateKey)
+	}
+
+	sig, err := ctx.ConstructSignature(logoutResponse, true)
+	if err != nil {
+		return "", err
+	}
+	// the signature follows the issuer
+	logoutResponse.InsertChildAt(1, sig)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(logoutResponse)
+	xmlBytes, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(xmlBytes), nil
+}
+
+// getSamlLogoutRequestUrl returns the URL that sends a signed LogoutRequest of the browser session to the SP with the
+// HTTP-Redirect binding, it's loaded by the logout page like a front-channel logout URL
+func getSamlLogoutRequestUrl(application *Application, user *User, sid string, host string) (string, error) {
+	_, privateKey, err := getSamlSigningKey(application)
+	if err != nil {
+		return "", err
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	now := time.Now().UTC()
+
+	logoutRequest := &etree.Element{
+		Space: "samlp",
+		Tag:   "LogoutRequest",
+	}
+	logoutRequest.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	logoutRequest.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	logoutRequest.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	logoutRequest.CreateAttr("Version", "2.0")
+	logoutRequest.CreateAttr("IssueInstant", now.Format(time.RFC3339))
+	logoutRequest.CreateAttr("NotOnOrAfter", now.Add(logoutTokenExpireInSeconds*time.Second).Format(time.RFC3339))
+	logoutRequest.CreateAttr("Destination", application.SamlLogoutUrl)
+	logoutRequest.CreateElement("saml:Issuer").SetText(originBackend)
+	logoutRequest.CreateElement("saml:NameID").SetText(getSamlNameId(application, user))
+	logoutRequest.CreateElement("samlp:SessionIndex").SetText(getSamlSessionIndex(sid))
+
+	return getSamlRedirectUrl(application.SamlLogoutUrl, "SAMLRequest", logoutRequest, "", privateKey)
+}
+
+func getSamlLogoutServices(application *Application, host string) []SingleLogoutService {
+	location := getSamlLogoutLocation(application, host)
+	return []SingleLogoutService{
+		{Binding: saml.BindingHttpRedirect, Location: location},
+		{Binding: saml.BindingHttpPost, Location: location},
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+func newTestSamlLogoutRequest(destination string) *etree.Element {
+	logoutRequest := &etree.Element{Space: "samlp", Tag: "LogoutRequest"}
+	logoutRequest.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	logoutRequest.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	logoutRequest.CreateAttr("ID", "_request")
+	logoutRequest.CreateAttr("Version", "2.0")
+	logoutRequest.CreateAttr("Destination", destination)
+	logoutRequest.CreateElement("saml:Issuer").SetText("https://sp.example.com")
+	logoutRequest.CreateElement("saml:NameID").SetText("alice")
+	logoutRequest.CreateElement("samlp:SessionIndex").SetText(getSamlSessionIndex(GetOidcSid("session")))
+	return logoutRequest
+}
+
+func TestParseSamlLogoutRequest(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	application := &Application{
+		Owner:             "admin",
+		Name:              "app-saml",
+		RedirectUris:      []string{"https://sp.example.com"},
+		SamlSpCertificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+	host := "door.example.com"
+	destination := getSamlLogoutLocation(application, host)
+
+	// HTTP-Redirect binding
+	redirectUrl, err := getSamlRedirectUrl(destination, "SAMLRequest", newTestSamlLogoutRequest(destination), "relay", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedUrl, _ := url.Parse(redirectUrl)
+	logoutRequest, err := ParseSamlLogoutRequest(application, parsedUrl.Query().Get("SAMLRequest"), parsedUrl.RawQuery, false, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !logoutRequest.IsForSession(application, &User{Name: "alice"}, "session") {
+		t.Errorf("the LogoutRequest should be for the session")
+	}
+	if logoutRequest.IsForSession(application, &User{Name: "alice"}, "another-session") {
+		t.Errorf("the LogoutRequest should not be for another session")
+	}
+
+	tamperedQuery := strings.Replace(parsedUrl.RawQuery, "RelayState=relay", "RelayState=tampered", 1)
+	_, err = ParseSamlLogoutRequest(application, parsedUrl.Query().Get("SAMLRequest"), tamperedQuery, false, host)
+	if err == nil {
+		t.Errorf("the LogoutRequest with a tampered query should be rejected")
+	}
+
+	// HTTP-POST binding
+	ctx := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: privateKey}))
+	signed, err := ctx.SignEnveloped(newTestSamlLogoutRequest(destination))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := etree.NewDocument()
+	doc.SetRoot(signed)
+	xmlString, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseSamlLogoutRequest(application, base64.StdEncoding.EncodeToString([]byte(xmlString)), "", true, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := strings.Replace(xmlString, ">alice<", ">bob<", 1)
+	_, err = ParseSamlLogoutRequest(application, base64.StdEncoding.EncodeToString([]byte(tampered)), "", true, host)
+	if err == nil {
+		t.Errorf("the LogoutRequest with a tampered NameID should be rejected")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/xorm-io/core"
+)
+
+const (
+	SharedEntryStoreDatabase = "Database"
+	SharedEntryStoreRedis    = "Redis"
+)
+
+// SharedEntry is a short-lived value that must be seen by all Casdoor instances, such as a pushed authorization
+// request or a used DPoP proof. The owner is the kind of the entry, and the name is its key within the kind
+type SharedEntry struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	ExpireTime  string `xorm:"varchar(100) index" json:"expireTime"`
+
+	Value string `xorm:"mediumtext" json:"value"`
+}
+
+// SharedEntryStore keeps the shared entries. AddEntry returns false if an unexpired entry with the same key exists,
+// and ConsumeEntry must return an entry to only one of the concurrent callers
+type SharedEntryStore interface {
+	AddEntry(entry *SharedEntry) (bool, error)
+	GetEntry(owner string, name string) (*SharedEntry, error)
+	ConsumeEntry(owner string, name string) (*SharedEntry, error)
+	PurgeExpiredEntries(cutoff time.Time) (int64, error)
+}
+
+var (
+	sharedEntryStore     SharedEntryStore
+	sharedEntryStoreOnce sync.Once
+)
+
+// getSharedEntryStore returns the store configured by sharedEntryStore, the entries are kept in the database by
+// default
+func getSharedEntryStore() SharedEntryStore {
+	sharedEntryStoreOnce.Do(func() {
+		if sharedEntryStore != nil {
+			return
+		}
+
+		if conf.GetConfigString("sharedEntryStore") == SharedEntryStoreRedis {
+			sharedEntryStore = newRedisSharedEntryStore(conf.GetConfigString("redisEndpoint"))
+		} else {
+			sharedEntryStore = &databaseSharedEntryStore{}
+		}
+	})
+	return sharedEntryStore
+}
+
+func (entry *SharedEntry) isExpired() bool {
+	expireTime, err := time.Parse(time.RFC3339, entry.ExpireTime)
+	return err != nil || time.Now().After(expireTime)
+}
+
+// databaseSharedEntryStore keeps the entries in the shared_entry table
+type databaseSharedEntryStore struct{}
+
+func (store *databaseSharedEntryStore) AddEntry(entry *SharedEntry) (bool, error) {
+	// an expired entry that hasn't been purged yet doesn't hold the key
+	_, err := ormer.Engine.ID(core.PK{entry.Owner, entry.Name}).Where("expire_time < ?", time.Now().Format(time.RFC3339)).Delete(&SharedEntry{})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = ormer.Engine.Insert(entry)
+	if err != nil {
+		// the insert fails on the primary key if a concurrent caller has added the entry first
+		existing, getErr := store.GetEntry(entry.Owner, entry.Name)
+		if getErr == nil && existing != nil {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (store *databaseSharedEntryStore) GetEntry(owner string, name string) (*SharedEntry, error) {
+	entry := SharedEntry{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&entry)
+	if err != nil {
+		return nil, err
+	}
+	if !existed {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// ConsumeEntry deletes the entry and returns it only if this call is the one deleting it
+func (store *databaseSharedEntryStore) ConsumeEntry(owner string, name string) (*SharedEntry, error) {
+	entry, err := store.GetEntry(owner, name)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).Delete(&SharedEntry{})
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (store *databaseSharedEntryStore) PurgeExpiredEntries(cutoff time.Time) (int64, error) {
+	return purgeRecords(&SharedEntry{}, "shared_entry", "expire_time < ?", cutoff.Format(time.RFC3339))
+}
+
+// addSharedEntry adds the value as JSON under the key until the expire time, it returns false if the key is taken
+func addSharedEntry(owner string, name string, value interface{}, expireTime time.Time) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	entry := &SharedEntry{
+		Owner:       owner,
+		Name:        name,
+		CreatedTime: time.Now().Format(time.RFC3339),
+		ExpireTime:  expireTime.Format(time.RFC3339),
+		Value:       string(data),
+	}
+	return getSharedEntryStore().AddEntry(entry)
+}
+
+// getSharedEntry reads the value of the key into value, it returns false if the key doesn't exist or has expired
+func getSharedEntry(owner string, name string, value interface{}) (bool, error) {
+	entry, err := getSharedEntryStore().GetEntry(owner, name)
+	if err != nil || entry == nil || entry.isExpired() {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(entry.Value), value)
+}
+
+// consumeSharedEntry reads the value of the key into value and removes the key, it returns false if the key doesn't
+// exist, has expired or has been consumed by another caller
+func consumeSharedEntry(owner string, name string, value interface{}) (bool, error) {
+	entry, err := getSharedEntryStore().ConsumeEntry(owner, name)
+	if err != nil || entry == nil || entry.isExpired() {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(entry.Value), value)
+}
+
+// purgeExpiredSharedEntries removes the entries that have expired before the cutoff, the ones in Redis expire by
+// themselves
+func purgeExpiredSharedEntries(cutoff time.Time) (int64, error) {
+	return getSharedEntryStore().PurgeExpiredEntries(cutoff)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const sharedEntryRedisKeyPrefix = "shared_entry:"
+
+// redisSharedEntryStore keeps the entries in Redis, they expire with the keys
+type redisSharedEntryStore struct {
+	pool *redis.Pool
+}
+
+func newRedisSharedEntryStore(endpoint string) *redisSharedEntryStore {
+	return &redisSharedEntryStore{pool: newRedisPool(endpoint)}
+}
+
+func getSharedEntryRedisKey(owner string, name string) string {
+	return sharedEntryRedisKeyPrefix + owner + ":" + name
+}
+
+// AddEntry sets the key only if it doesn't exist, so only one of the concurrent callers adds it
+func (store *redisSharedEntryStore) AddEntry(entry *SharedEntry) (bool, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+
+	expireTime, err := time.Parse(time.RFC3339, entry.ExpireTime)
+	if err != nil {
+		return false, err
+	}
+
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	_, err = redis.String(conn.Do("SET", getSharedEntryRedisKey(entry.Owner, entry.Name), data, "EX", getRedisExpireSeconds(expireTime), "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (store *redisSharedEntryStore) GetEntry(owner string, name string) (*SharedEntry, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", getSharedEntryRedisKey(owner, name)))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalSharedEntry(data)
+}
+
+// ConsumeEntry gets and deletes the entry with a script, so only one of the concurrent callers gets it
+func (store *redisSharedEntryStore) ConsumeEntry(owner string, name string) (*SharedEntry, error) {
+	data, err := consumeRedisKey(store.pool, getSharedEntryRedisKey(owner, name))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return unmarshalSharedEntry(data)
+}
+
+func (store *redisSharedEntryStore) PurgeExpiredEntries(cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func unmarshalSharedEntry(data []byte) (*SharedEntry, error) {
+	entry := SharedEntry{}
+	err := json.Unmarshal(data, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+)
+
+func testSharedEntryStore(t *testing.T, store SharedEntryStore) {
+	now := time.Now()
+	entry := &SharedEntry{Owner: "test", Name: "entry-" + now.Format("150405.000000"), ExpireTime: now.Add(time.Minute).Format(time.RFC3339), Value: `"value"`}
+
+	added, err := store.AddEntry(entry)
+	if err != nil || !added {
+		t.Fatalf("the entry should be added: %v", err)
+	}
+	if added, _ = store.AddEntry(entry); added {
+		t.Errorf("the entry should not be added twice")
+	}
+
+	res, err := store.GetEntry(entry.Owner, entry.Name)
+	if err != nil || res == nil || res.Value != entry.Value {
+		t.Fatalf("the entry should be read back: %v, %v", res, err)
+	}
+
+	testConsumeOnce(t, "the entry", func() (bool, error) {
+		res, err := store.ConsumeEntry(entry.Owner, entry.Name)
+		return res != nil, err
+	})
+}
+
+func TestDatabaseSharedEntryStore(t *testing.T) {
+	initTestOrmer(t, &SharedEntry{})
+	store := &databaseSharedEntryStore{}
+	testSharedEntryStore(t, store)
+
+	// an expired entry doesn't hold its key
+	entry := &SharedEntry{Owner: "test", Name: "expired", ExpireTime: time.Now().Add(-time.Minute).Format(time.RFC3339)}
+	if added, err := store.AddEntry(entry); err != nil || !added {
+		t.Fatalf("the entry should be added: %v", err)
+	}
+	entry.ExpireTime = time.Now().Add(time.Minute).Format(time.RFC3339)
+	if added, err := store.AddEntry(entry); err != nil || !added {
+		t.Errorf("the entry should replace the expired one: %v", err)
+	}
+}
+
+func TestRedisSharedEntryStore(t *testing.T) {
+	endpoint := conf.GetConfigString("redisEndpoint")
+	if endpoint == "" {
+		t.Skip("redisEndpoint is not configured")
+	}
+
+	testSharedEntryStore(t, newRedisSharedEntryStore(endpoint))
+}
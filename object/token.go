@@ -43,6 +43,21 @@ type Token struct {
 	CodeChallenge    string `xorm:"varchar(100)" json:"codeChallenge"`
 	CodeIsUsed       bool   `json:"codeIsUsed"`
 	CodeExpireIn     int64  `json:"codeExpireIn"`
+	DpopJkt          string `xorm:"varchar(100)" json:"dpopJkt"`
+	CertThumbprint   string `xorm:"varchar(100)" json:"certThumbprint"`
+	Sid              string `xorm:"varchar(100)" json:"sid"`
+	IdToken          string `xorm:"mediumtext" json:"idToken"`
+	IsOpaque         bool   `json:"isOpaque"`
+
+	FamilyId          string `xorm:"varchar(100) index" json:"familyId"`
+	FamilyCreatedTime string `xorm:"varchar(100)" json:"familyCreatedTime"`
+	IsRotated         bool   `json:"isRotated"`
+
+	AuthTime int64    `json:"authTime"`
+	Amr      []string `xorm:"mediumtext" json:"amr"`
+	Claims   string   `xorm:"mediumtext" json:"claims"`
+
+	AuthorizationDetails string `xorm:"mediumtext" json:"authorizationDetails"`
 }
 
 func GetTokenCount(owner, organization, field, value string) (int64, error) {
@@ -206,7 +221,7 @@ func UpdateToken(id string, token *Token) (bool, error) {
 
 	token.popularHashes()
 
-	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(token)
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(token.getPersistedToken())
 	if err != nil {
 		return false, err
 	}
@@ -217,7 +232,7 @@ func UpdateToken(id string, token *Token) (bool, error) {
 func AddToken(token *Token) (bool, error) {
 	token.popularHashes()
 
-	affected, err := ormer.Engine.Insert(token)
+	affected, err := ormer.Engine.Insert(token.getPersistedToken())
 	if err != nil {
 		return false, err
 	}
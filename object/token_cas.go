@@ -21,8 +21,6 @@ import (
 	"encoding/pem"
 	"encoding/xml"
 	"fmt"
-	"math/rand"
-	"sync"
 	"time"
 
 	"github.com/beevik/etree"
@@ -87,12 +85,6 @@ type CasAnyAttribute struct {
 	Value   string `xml:",chardata"`
 }
 
-type CasAuthenticationSuccessWrapper struct {
-	AuthenticationSuccess *CasAuthenticationSuccess // the token we issued
-	Service               string                    // to which service this token is issued
-	UserId                string
-}
-
 type CasProxySuccess struct {
 	XMLName     xml.Name `xml:"cas:proxySuccess" json:"-"`
 	ProxyTicket string   `xml:"cas:proxyTicket"`
@@ -117,12 +109,6 @@ type Saml11AssertionArtifact struct {
 	InnerXML string   `xml:",innerxml"`
 }
 
-// st is short for service ticket
-var stToServiceResponse sync.Map
-
-// pgt is short for proxy granting ticket
-var pgtToServiceResponse sync.Map
-
 func CheckCasLogin(application *Application, lang string, service string) error {
 	if len(application.RedirectUris) > 0 && !application.IsRedirectUriValid(service) {
 		return fmt.Errorf(i18n.Translate(lang, "token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), service)
@@ -130,14 +116,8 @@ func CheckCasLogin(application *Application, lang string, service string) error
 	return nil
 }
 
-func StoreCasTokenForPgt(token *CasAuthenticationSuccess, service, userId string) string {
-	pgt := fmt.Sprintf("PGT-%s", util.GenerateId())
-	pgtToServiceResponse.Store(pgt, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: token,
-		Service:               service,
-		UserId:                userId,
-	})
-	return pgt
+func StoreCasTokenForPgt(token *CasAuthenticationSuccess, service, userId string) (string, error) {
+	return addCasTicket(CasTicketTypeProxyGranting, token, service, userId, false)
 }
 
 func GenerateId() {
@@ -150,41 +130,43 @@ func GenerateId() {
 @ret2: token, nil if not found
 @ret3: the service URL who requested to issue this token
 @ret4: userIf of user who requested to issue this token
+@ret5: error
 */
-func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := pgtToServiceResponse.LoadAndDelete(pgt); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
+func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, string, error) {
+	ticket, err := getCasPgt(pgt)
+	if err != nil || ticket == nil {
+		return false, nil, "", "", err
 	}
-	return false, nil, "", ""
+	return true, ticket.Response, ticket.Service, ticket.UserId, nil
 }
 
 // GetCasTokenByTicket
 /**
+the ticket is invalidated once it's looked up. With renew, only the service tickets issued from a new login are found
 @ret1: whether a token is found
 @ret2: token, nil if not found
 @ret3: the service URL who requested to issue this token
 @ret4: userIf of user who requested to issue this token
+@ret5: error
 */
-func GetCasTokenByTicket(ticket string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := stToServiceResponse.LoadAndDelete(ticket); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
+func GetCasTokenByTicket(ticket string, renew bool) (bool, *CasAuthenticationSuccess, string, string, error) {
+	casTicket, err := consumeCasTicket(ticket)
+	if err != nil || casTicket == nil {
+		return false, nil, "", "", err
 	}
-	return false, nil, "", ""
+	if renew && (casTicket.Type != CasTicketTypeService || !casTicket.IsFromNewLogin) {
+		return false, nil, "", "", nil
+	}
+	return true, casTicket.Response, casTicket.Service, casTicket.UserId, nil
 }
 
-func StoreCasTokenForProxyTicket(token *CasAuthenticationSuccess, targetService, userId string) string {
-	proxyTicket := fmt.Sprintf("PT-%s", util.GenerateId())
-	stToServiceResponse.Store(proxyTicket, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: token,
-		Service:               targetService,
-		UserId:                userId,
-	})
-	return proxyTicket
+func StoreCasTokenForProxyTicket(token *CasAuthenticationSuccess, targetService, userId string) (string, error) {
+	return addCasTicket(CasTicketTypeProxy, token, targetService, userId, false)
 }
 
-func GenerateCasToken(userId string, service string) (string, error) {
+// GenerateCasToken issues the service ticket of the user for the service, isFromNewLogin is whether the user has
+// presented the credentials rather than being signed in by the existing session, which is required by renew
+func GenerateCasToken(userId string, service string, isFromNewLogin bool) (string, error) {
 	user, err := GetUser(userId)
 	if err != nil {
 		return "", err
@@ -202,6 +184,7 @@ func GenerateCasToken(userId string, service string) (string, error) {
 		User: user.Name,
 		Attributes: &CasAttributes{
 			AuthenticationDate: time.Now(),
+			IsFromNewLogin:     isFromNewLogin,
 			UserAttributes:     &CasUserAttributes{},
 		},
 		ProxyGrantingTicket: fmt.Sprintf("PGTIOU-%s", util.GenerateId()),
@@ -232,13 +215,7 @@ func GenerateCasToken(userId string, service string) (string, error) {
 		}
 	}
 
-	st := fmt.Sprintf("ST-%d", rand.Int())
-	stToServiceResponse.Store(st, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: &authenticationSuccess,
-		Service:               service,
-		UserId:                userId,
-	})
-	return st, nil
+	return addCasTicket(CasTicketTypeService, &authenticationSuccess, service, userId, isFromNewLogin)
 }
 
 // GetValidationBySaml
@@ -259,7 +236,10 @@ func GetValidationBySaml(samlRequest string, host string) (string, string, error
 		return "", "", fmt.Errorf("request.AssertionArtifact.InnerXML error, AssertionArtifact field not found")
 	}
 
-	ok, _, service, userId := GetCasTokenByTicket(ticket)
+	ok, _, service, userId, err := GetCasTokenByTicket(ticket, false)
+	if err != nil {
+		return "", "", err
+	}
 	if !ok {
 		return "", "", fmt.Errorf("the CAS token for ticket %s is not found", ticket)
 	}
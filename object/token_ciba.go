@@ -0,0 +1,335 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	CibaGrantType = "urn:openid:params:grant-type:ciba"
+
+	CibaDeliveryModePoll = "poll"
+	CibaDeliveryModePing = "ping"
+
+	UnknownUserId         = "unknown_user_id"
+	InvalidBindingMessage = "invalid_binding_message"
+
+	cibaExpireInSeconds    = 300
+	cibaMaxExpireInSeconds = 1800
+	cibaIntervalSeconds    = 5
+	bindingMessageMaxLen   = 100
+)
+
+type CibaAuthResponse struct {
+	AuthReqId string `json:"auth_req_id"`
+	ExpiresIn int    `json:"expires_in"`
+	Interval  int    `json:"interval,omitempty"`
+}
+
+// getCibaAuthById returns the request the user has not decided yet by the id shown to the user, the auth_req_id is
+// only known to the client
+func getCibaAuthById(id string, userId string) (*PendingAuth, error) {
+	if id == "" || userId == "" {
+		return nil, nil
+	}
+
+	auths, err := getUndecidedPendingAuths(&PendingAuth{Owner: PendingAuthTypeCiba, Code: id, User: userId})
+	if err != nil || len(auths) == 0 {
+		return nil, err
+	}
+	return auths[0], nil
+}
+
+func getCibaExpireInSeconds(requestedExpiry string) (int, bool) {
+	if requestedExpiry == "" {
+		return cibaExpireInSeconds, true
+	}
+
+	expireInSeconds, err := strconv.Atoi(requestedExpiry)
+	if err != nil || expireInSeconds <= 0 {
+		return 0, false
+	}
+	if expireInSeconds > cibaMaxExpireInSeconds {
+		expireInSeconds = cibaMaxExpireInSeconds
+	}
+	return expireInSeconds, true
+}
+
+// getCibaUser returns the user identified by the login_hint (username, email or phone) or the id_token_hint
+func getCibaUser(application *Application, loginHint string, idTokenHint string) (*User, error) {
+	if loginHint != "" {
+		return GetUserByFields(application.Organization, loginHint)
+	}
+
+	_, claims, err := ParseIdTokenHint(idTokenHint, application.ClientId)
+	if err != nil {
+		return nil, nil
+	}
+	return GetIdTokenHintUser(application, idTokenHint, claims)
+}
+
+// notifyCibaUser sends the authentication request to the user through the email and notification providers of the
+// application, the request can always be found on the CIBA page of the web UI as well. The SMS providers are not used,
+// their templates are for verification codes only
+func notifyCibaUser(application *Application, user *User, auth *PendingAuth, host string) {
+	originFrontend, _ := getOriginFromHost(host)
+	link := fmt.Sprintf("%s/login/oauth/ciba?id=%s", originFrontend, auth.Code)
+	content := fmt.Sprintf("%s is requesting to sign in as you, please approve or deny it at: %s", application.DisplayName, link)
+	if auth.BindingMessage != "" {
+		content = fmt.Sprintf("%s is requesting to sign in as you (%s), please approve or deny it at: %s", application.DisplayName, auth.BindingMessage, link)
+	}
+
+	if user.Email != "" {
+		provider, err := application.GetEmailProvider("Login")
+		if err == nil && provider != nil {
+			err = SendEmail(provider, provider.Title, content, user.Email, application.DisplayName)
+		}
+		if err != nil {
+			logs.Warning(fmt.Sprintf("notifyCibaUser() error: failed to send the email to the user: %s, error: %s", user.GetId(), err.Error()))
+		}
+	}
+
+	provider, err := application.GetProviderByCategory("Notification")
+	if err == nil && provider != nil {
+		err = SendNotification(provider, fmt.Sprintf("%s: %s", user.GetId(), content))
+	}
+	if err != nil {
+		logs.Warning(fmt.Sprintf("notifyCibaUser() error: failed to send the notification to the user: %s, error: %s", user.GetId(), err.Error()))
+	}
+}
+
+// sendCibaPing notifies the client that the authentication request has been completed (CIBA section 10.2)
+func sendCibaPing(application *Application, authReqId string, clientNotificationToken string) error {
+	body, err := json.Marshal(map[string]string{"auth_req_id": authReqId})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", application.ClientNotificationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+clientNotificationToken)
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("the CIBA client notification endpoint of the application: %s returns status: %s", application.GetId(), resp.Status)
+	}
+	return nil
+}
+
+// GetCibaAuthResponse
+// Backchannel Authentication Request, per OpenID Connect CIBA Core 1.0 section 7
+func GetCibaAuthResponse(clientId string, clientSecret string, scope string, loginHint string, idTokenHint string, bindingMessage string, requestedExpiry string, clientNotificationToken string, host string) (*CibaAuthResponse, *TokenError, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id is invalid",
+		}, nil
+	}
+
+	// CIBA is only for confidential clients
+	if application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if !IsGrantTypeValid(CibaGrantType, application.GrantTypes) {
+		return nil, &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: fmt.Sprintf("grant_type: %s is not supported in this application", CibaGrantType),
+		}, nil
+	}
+
+	if !util.InSlice(strings.Fields(scope), "openid") {
+		return nil, &TokenError{
+			Error:            InvalidScope,
+			ErrorDescription: "scope should contain openid",
+		}, nil
+	}
+
+	if (loginHint == "") == (idTokenHint == "") {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "exactly one of login_hint and id_token_hint should be provided",
+		}, nil
+	}
+
+	if len([]rune(bindingMessage)) > bindingMessageMaxLen {
+		return nil, &TokenError{
+			Error:            InvalidBindingMessage,
+			ErrorDescription: fmt.Sprintf("binding_message should not be longer than %d characters", bindingMessageMaxLen),
+		}, nil
+	}
+
+	expireInSeconds, ok := getCibaExpireInSeconds(requestedExpiry)
+	if !ok {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "requested_expiry should be a positive integer",
+		}, nil
+	}
+
+	if application.BackchannelTokenDeliveryMode == CibaDeliveryModePing {
+		if application.ClientNotificationEndpoint == "" {
+			return nil, &TokenError{
+				Error:            UnauthorizedClient,
+				ErrorDescription: "the application has no client notification endpoint for the ping mode",
+			}, nil
+		}
+		if clientNotificationToken == "" {
+			return nil, &TokenError{
+				Error:            InvalidRequest,
+				ErrorDescription: "client_notification_token should not be empty in the ping mode",
+			}, nil
+		}
+	}
+
+	user, err := getCibaUser(application, loginHint, idTokenHint)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, &TokenError{
+			Error:            UnknownUserId,
+			ErrorDescription: "the user identified by the hint is not found",
+		}, nil
+	}
+
+	allowed, err := CheckLoginPermission(user.GetId(), application)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.IsForbidden || !allowed {
+		return nil, &TokenError{
+			Error:            AccessDenied,
+			ErrorDescription: "the user is not allowed to sign in to the application",
+		}, nil
+	}
+
+	authReqId := util.GenerateId()
+	now := time.Now()
+	auth := &PendingAuth{
+		Owner:                   PendingAuthTypeCiba,
+		Name:                    authReqId,
+		CreatedTime:             now.Format(time.RFC3339),
+		ExpireTime:              now.Add(time.Duration(expireInSeconds) * time.Second).Format(time.RFC3339),
+		Code:                    util.GenerateClientId(),
+		Application:             application.GetId(),
+		Scope:                   scope,
+		BindingMessage:          bindingMessage,
+		User:                    user.GetId(),
+		ClientNotificationToken: clientNotificationToken,
+		PollInterval:            cibaIntervalSeconds,
+	}
+	err = addPendingAuth(auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	util.SafeGoroutine(func() {
+		notifyCibaUser(application, user, auth, host)
+	})
+
+	resp := &CibaAuthResponse{
+		AuthReqId: authReqId,
+		ExpiresIn: expireInSeconds,
+	}
+	if application.BackchannelTokenDeliveryMode != CibaDeliveryModePing {
+		resp.Interval = cibaIntervalSeconds
+	}
+	return resp, nil, nil
+}
+
+// GetCibaAuthsByUser returns the pending authentication requests of the user for the CIBA page
+func GetCibaAuthsByUser(userId string) ([]*PendingAuth, error) {
+	if userId == "" {
+		return []*PendingAuth{}, nil
+	}
+
+	return getUndecidedPendingAuths(&PendingAuth{Owner: PendingAuthTypeCiba, User: userId})
+}
+
+// ApproveCibaAuth records the decision of the user on the CIBA page and pings the client in the ping mode
+func ApproveCibaAuth(application *Application, id string, userId string, approved bool) (bool, error) {
+	auth, err := getCibaAuthById(id, userId)
+	if err != nil || auth == nil || auth.Application != application.GetId() {
+		return false, err
+	}
+
+	decided, err := decidePendingAuth(auth, userId, approved)
+	if err != nil || !decided {
+		return false, err
+	}
+
+	if application.BackchannelTokenDeliveryMode == CibaDeliveryModePing {
+		util.SafeGoroutine(func() {
+			err := sendCibaPing(application, auth.Name, auth.ClientNotificationToken)
+			if err != nil {
+				logs.Warning(fmt.Sprintf("ApproveCibaAuth() error: failed to ping the client of the application: %s, error: %s", application.GetId(), err.Error()))
+			}
+		})
+	}
+	return true, nil
+}
+
+// GetCibaAuth returns the pending authentication request of the user by its id
+func GetCibaAuth(id string, userId string) (*PendingAuth, error) {
+	return getCibaAuthById(id, userId)
+}
+
+// GetCibaToken
+// Token Request of the poll and ping modes, per OpenID Connect CIBA Core 1.0 section 10.1 and 11
+func GetCibaToken(application *Application, clientSecret string, authReqId string, host string) (*Token, *TokenError, error) {
+	if authReqId == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "auth_req_id should not be empty",
+		}, nil
+	}
+
+	if application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	return getPendingAuthToken(application, PendingAuthTypeCiba, authReqId, host)
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	deviceCodeExpireInSeconds = 600
+	deviceCodeIntervalSeconds = 5
+	// RFC 8628 section 8: the user code uses a base-20 charset without vowels to avoid ambiguous or offensive words
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+type DeviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func generateUserCode() (string, error) {
+	res := make([]byte, userCodeLength)
+	for i := range res {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeCharset))))
+		if err != nil {
+			return "", err
+		}
+		res[i] = userCodeCharset[n.Int64()]
+	}
+	return string(res), nil
+}
+
+// normalizeUserCode strips the dash and spaces users may type and upper-cases the rest
+func normalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	userCode = strings.ReplaceAll(userCode, "-", "")
+	userCode = strings.ReplaceAll(userCode, " ", "")
+	return userCode
+}
+
+func formatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return fmt.Sprintf("%s-%s", userCode[:userCodeLength/2], userCode[userCodeLength/2:])
+}
+
+// GetDeviceAuthResponse
+// Device Authorization Request, per RFC 8628 section 3.1
+func GetDeviceAuthResponse(clientId string, clientSecret string, scope string, host string) (*DeviceAuthResponse, *TokenError, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id is invalid",
+		}, nil
+	}
+
+	if clientSecret != "" && application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if !IsGrantTypeValid(DeviceCodeGrantType, application.GrantTypes) {
+		return nil, &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: fmt.Sprintf("grant_type: %s is not supported in this application", DeviceCodeGrantType),
+		}, nil
+	}
+
+	var userCode string
+	for {
+		userCode, err = generateUserCode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		auths, err := getUndecidedPendingAuths(&PendingAuth{Owner: PendingAuthTypeDevice, Code: userCode})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(auths) == 0 {
+			break
+		}
+	}
+
+	deviceCode := util.GenerateId()
+	now := time.Now()
+	auth := &PendingAuth{
+		Owner:        PendingAuthTypeDevice,
+		Name:         deviceCode,
+		CreatedTime:  now.Format(time.RFC3339),
+		ExpireTime:   now.Add(deviceCodeExpireInSeconds * time.Second).Format(time.RFC3339),
+		Code:         userCode,
+		Application:  application.GetId(),
+		Scope:        scope,
+		PollInterval: deviceCodeIntervalSeconds,
+	}
+	err = addPendingAuth(auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	originFrontend, _ := getOriginFromHost(host)
+	verificationUri := fmt.Sprintf("%s/login/oauth/device", originFrontend)
+
+	return &DeviceAuthResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationUri:         verificationUri,
+		VerificationUriComplete: fmt.Sprintf("%s?user_code=%s", verificationUri, formatUserCode(userCode)),
+		ExpiresIn:               deviceCodeExpireInSeconds,
+		Interval:                deviceCodeIntervalSeconds,
+	}, nil, nil
+}
+
+// getDeviceAuthByUserCode returns the device authorization the user has not decided yet by the user code
+func getDeviceAuthByUserCode(userCode string) (*PendingAuth, error) {
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return nil, nil
+	}
+
+	auths, err := getUndecidedPendingAuths(&PendingAuth{Owner: PendingAuthTypeDevice, Code: userCode})
+	if err != nil || len(auths) == 0 {
+		return nil, err
+	}
+	return auths[0], nil
+}
+
+// GetDeviceAuthByUserCode returns the pending device authorization for the verification page
+func GetDeviceAuthByUserCode(userCode string) (*PendingAuth, error) {
+	auth, err := getDeviceAuthByUserCode(userCode)
+	if err != nil || auth == nil {
+		return nil, err
+	}
+
+	auth.Code = formatUserCode(auth.Code)
+	return auth, nil
+}
+
+// ApproveDeviceAuth records the decision of the signed-in user on the verification page, the user code is
+// single-use, a second visitor must not be able to bind it again
+func ApproveDeviceAuth(userCode string, userId string, approved bool) (bool, error) {
+	auth, err := getDeviceAuthByUserCode(userCode)
+	if err != nil || auth == nil {
+		return false, err
+	}
+
+	return decidePendingAuth(auth, userId, approved)
+}
+
+// GetDeviceCodeToken
+// Device Access Token Request, per RFC 8628 section 3.4 and 3.5
+func GetDeviceCodeToken(application *Application, clientSecret string, deviceCode string, host string) (*Token, *TokenError, error) {
+	if deviceCode == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "device_code should not be empty",
+		}, nil
+	}
+
+	if clientSecret != "" && application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	return getPendingAuthToken(application, PendingAuthTypeDevice, deviceCode, host)
+}
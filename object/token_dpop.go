@@ -0,0 +1,301 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	DpopTokenType    = "DPoP"
+	InvalidDpopProof = "invalid_dpop_proof"
+	UseDpopNonce     = "use_dpop_nonce"
+
+	// a proof is accepted within this window around its iat, its jti is remembered for as long
+	dpopProofMaxAgeSeconds  = 300
+	dpopProofMaxSkewSeconds = 60
+	dpopNonceRotateSeconds  = 300
+
+	dpopNonceOwner        = "dpop_nonce"
+	dpopCurrentNonceOwner = "dpop_current_nonce"
+	dpopJtiOwner          = "dpop_jti"
+)
+
+type DpopProofClaims struct {
+	Htm   string `json:"htm"`
+	Htu   string `json:"htu"`
+	Ath   string `json:"ath,omitempty"`
+	Nonce string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GetDpopNonce returns the current server-provided nonce, which is shared by the Casdoor instances. Every issued nonce
+// stays valid for one more rotation after it's replaced
+func GetDpopNonce() (string, error) {
+	nonce := ""
+	ok, err := getSharedEntry(dpopCurrentNonceOwner, "current", &nonce)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return nonce, nil
+	}
+
+	now := time.Now()
+	nonce = util.GenerateId()
+	_, err = addSharedEntry(dpopNonceOwner, nonce, "", now.Add(2*dpopNonceRotateSeconds*time.Second))
+	if err != nil {
+		return "", err
+	}
+
+	// if another instance has rotated the nonce at the same time, both nonces are valid
+	_, err = addSharedEntry(dpopCurrentNonceOwner, "current", nonce, now.Add(dpopNonceRotateSeconds*time.Second))
+	if err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+func isDpopNonceValid(nonce string) (bool, error) {
+	if nonce == "" {
+		return false, nil
+	}
+
+	value := ""
+	return getSharedEntry(dpopNonceOwner, nonce, &value)
+}
+
+// checkDpopJtiReplay records the jti and reports whether it has already been used with the same key
+func checkDpopJtiReplay(jkt string, jti string) (bool, error) {
+	sum := sha256.Sum256([]byte(jkt + "/" + jti))
+	expireTime := time.Now().Add((dpopProofMaxAgeSeconds + dpopProofMaxSkewSeconds) * time.Second)
+	added, err := addSharedEntry(dpopJtiOwner, hex.EncodeToString(sum[:]), "", expireTime)
+	if err != nil {
+		return false, err
+	}
+	return !added, nil
+}
+
+func getDpopAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func getDpopHtu(host string, path string) string {
+	_, originBackend := getOriginFromHost(host)
+	return originBackend + path
+}
+
+// ValidateDpopProof checks a DPoP proof JWT (RFC 9449 section 4.3) sent to the given method and path,
+// accessToken is empty at the token endpoint and set at the protected resources. It returns the JWK
+// thumbprint of the proof key on success
+func ValidateDpopProof(proof string, method string, path string, host string, accessToken string) (string, *TokenError, error) {
+	if proof == "" {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the DPoP proof is missing",
+		}, nil
+	}
+
+	var jwk jose.JSONWebKey
+	claims := DpopProofClaims{}
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(proof, &claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != "dpop+jwt" {
+			return nil, fmt.Errorf("the typ of the DPoP proof should be dpop+jwt")
+		}
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		jwkBytes, err := json.Marshal(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+		err = jwk.UnmarshalJSON(jwkBytes)
+		if err != nil {
+			return nil, fmt.Errorf("the jwk of the DPoP proof is invalid: %s", err.Error())
+		}
+		if !jwk.IsPublic() {
+			return nil, fmt.Errorf("the jwk of the DPoP proof should be a public key")
+		}
+
+		return jwk.Key, nil
+	})
+	if err != nil {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: err.Error(),
+		}, nil
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the jti and iat of the DPoP proof should not be empty",
+		}, nil
+	}
+
+	if !strings.EqualFold(claims.Htm, method) {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: fmt.Sprintf("the htm of the DPoP proof should be: %s", method),
+		}, nil
+	}
+
+	// the htu is compared without its query and fragment, per RFC 9449 section 4.3
+	htu := claims.Htu
+	if i := strings.IndexAny(htu, "?#"); i != -1 {
+		htu = htu[:i]
+	}
+	if htu != getDpopHtu(host, path) {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: fmt.Sprintf("the htu of the DPoP proof should be: %s", getDpopHtu(host, path)),
+		}, nil
+	}
+
+	age := time.Since(claims.IssuedAt.Time)
+	if age > dpopProofMaxAgeSeconds*time.Second || age < -dpopProofMaxSkewSeconds*time.Second {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the iat of the DPoP proof is out of the acceptable window",
+		}, nil
+	}
+
+	if accessToken != "" && claims.Ath != getDpopAccessTokenHash(accessToken) {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the ath of the DPoP proof doesn't match the access token",
+		}, nil
+	}
+
+	isNonceValid, err := isDpopNonceValid(claims.Nonce)
+	if err != nil {
+		return "", nil, err
+	}
+	if !isNonceValid {
+		return "", &TokenError{
+			Error:            UseDpopNonce,
+			ErrorDescription: "the DPoP proof should contain the nonce provided by the server",
+		}, nil
+	}
+
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: err.Error(),
+		}, nil
+	}
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	isReplayed, err := checkDpopJtiReplay(jkt, claims.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if isReplayed {
+		return "", &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the DPoP proof has already been used",
+		}, nil
+	}
+
+	return jkt, nil, nil
+}
+
+// bindTokenToKey re-issues the JWTs of a token with the cnf claim for the DPoP key and/or the TLS client certificate,
+// keeping its name, nonce, audience, actor and authorization details
+func bindTokenToKey(application *Application, token *Token, jkt string, x5tS256 string, host string) error {
+	// an opaque token carries no claims, the binding is only recorded for the introspection
+	if token.IsOpaque {
+		if jkt != "" {
+			token.TokenType = DpopTokenType
+		}
+		token.DpopJkt = jkt
+		token.CertThumbprint = x5tS256
+
+		_, err := UpdateToken(token.GetId(), token)
+		return err
+	}
+
+	claims, err := ParseJwtTokenByApplication(token.AccessToken, application)
+	if err != nil {
+		return err
+	}
+
+	user, err := getUser(token.Organization, token.User)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		// the client credentials grant issues tokens to the application itself
+		user = &User{
+			Owner: application.Owner,
+			Id:    application.GetId(),
+			Name:  application.Name,
+			Type:  "application",
+		}
+	} else {
+		err = ExtendUserWithRolesAndPermissions(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	options := &jwtTokenOptions{
+		Name:     token.Name,
+		Audience: claims.Audience,
+		Act:      claims.Act,
+		Jkt:      jkt,
+		X5tS256:  x5tS256,
+		Sid:      token.Sid,
+
+		AuthContext:          token.getAuthContext(),
+		AuthorizationDetails: token.AuthorizationDetails,
+	}
+	accessToken, refreshToken, _, err := generateJwtTokenWithOptions(application, user, claims.Nonce, token.Scope, host, options)
+	if err != nil {
+		return err
+	}
+
+	token.AccessToken = accessToken
+	if token.RefreshToken != "" {
+		token.RefreshToken = refreshToken
+	}
+	if jkt != "" {
+		token.TokenType = DpopTokenType
+	}
+	token.DpopJkt = jkt
+	token.CertThumbprint = x5tS256
+
+	_, err = UpdateToken(token.GetId(), token)
+	return err
+}
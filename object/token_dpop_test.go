@@ -0,0 +1,98 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func newDpopProof(t *testing.T, key *ecdsa.PrivateKey, htm string, htu string, nonce string, accessToken string) string {
+	claims := DpopProofClaims{
+		Htm:   htm,
+		Htu:   htu,
+		Nonce: nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       util.GenerateId(),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if accessToken != "" {
+		claims.Ath = getDpopAccessTokenHash(accessToken)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jose.JSONWebKey{Key: &key.PublicKey}
+
+	proof, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestValidateDpopProof(t *testing.T) {
+	initTestOrmer(t, &SharedEntry{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host := "door.casdoor.com"
+	path := "/api/userinfo"
+	htu := getDpopHtu(host, path)
+
+	_, tokenError, _ := ValidateDpopProof(newDpopProof(t, key, "GET", htu, "", ""), "GET", path, host, "")
+	if tokenError == nil || tokenError.Error != UseDpopNonce {
+		t.Fatalf("a proof without the server nonce should be rejected with %s, got: %v", UseDpopNonce, tokenError)
+	}
+
+	nonce, err := GetDpopNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := newDpopProof(t, key, "GET", htu+"?foo=bar", nonce, "access-token")
+	jkt, tokenError, err := ValidateDpopProof(proof, "GET", path, host, "access-token")
+	if err != nil || tokenError != nil {
+		t.Fatalf("a valid proof is rejected: %v, %v", tokenError, err)
+	}
+	if jkt == "" {
+		t.Fatal("the jkt of a valid proof should not be empty")
+	}
+
+	_, tokenError, _ = ValidateDpopProof(proof, "GET", path, host, "access-token")
+	if tokenError == nil {
+		t.Fatal("a replayed proof should be rejected")
+	}
+
+	_, tokenError, _ = ValidateDpopProof(newDpopProof(t, key, "POST", htu, nonce, "access-token"), "GET", path, host, "access-token")
+	if tokenError == nil {
+		t.Fatal("a proof for another method should be rejected")
+	}
+
+	_, tokenError, _ = ValidateDpopProof(newDpopProof(t, key, "GET", htu, nonce, "another-token"), "GET", path, host, "access-token")
+	if tokenError == nil {
+		t.Fatal("a proof for another access token should be rejected")
+	}
+}
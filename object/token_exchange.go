@@ -0,0 +1,324 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+	JwtTokenType    = "urn:ietf:params:oauth:token-type:jwt"
+	IdTokenType     = "urn:ietf:params:oauth:token-type:id_token"
+
+	InvalidTarget = "invalid_target"
+)
+
+func isTokenTypeExchangeable(tokenType string) bool {
+	return tokenType == AccessTokenType || tokenType == JwtTokenType || tokenType == IdTokenType
+}
+
+// parseExchangeToken validates a subject or actor token: it must have been issued by this server,
+// must not be expired or revoked, and its signature is checked with the cert of the issuing application
+func parseExchangeToken(tokenString string, tokenType string, name string) (*Token, *Claims, *TokenError, error) {
+	if tokenString == "" {
+		return nil, nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: fmt.Sprintf("%s should not be empty", name),
+		}, nil
+	}
+
+	if !isTokenTypeExchangeable(tokenType) {
+		return nil, nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: fmt.Sprintf("%s_type: %s is not supported", name, tokenType),
+		}, nil
+	}
+
+	if tokenType == IdTokenType {
+		return parseExchangeIdToken(tokenString, name)
+	}
+
+	token, err := GetTokenByAccessToken(tokenString)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if token == nil || token.ExpiresIn <= 0 {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid or has been revoked", name),
+		}, nil
+	}
+
+	application, err := getApplication(token.Owner, token.Application)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if application == nil {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the application of %s does not exist", name),
+		}, nil
+	}
+
+	claims, err := ParseAccessToken(tokenString, token, application)
+	if err != nil {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid: %s", name, err.Error()),
+		}, nil
+	}
+
+	return token, claims, nil, nil
+}
+
+// parseExchangeIdToken validates an ID token as a JWT signed by the cert of the application it's issued to, the token
+// it's issued with must still exist and not be revoked
+func parseExchangeIdToken(tokenString string, name string) (*Token, *Claims, *TokenError, error) {
+	unverifiedClaims := Claims{}
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, &unverifiedClaims)
+	if err != nil {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is not a valid JWT: %s", name, err.Error()),
+		}, nil
+	}
+
+	application, err := getApplicationByAudience(unverifiedClaims.Audience)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if application == nil {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the audience of %s doesn't match any application", name),
+		}, nil
+	}
+
+	claims, err := ParseJwtTokenByApplication(tokenString, application)
+	if err != nil {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid: %s", name, err.Error()),
+		}, nil
+	}
+
+	// the jti of the ID token is the id of the token it's issued with
+	owner, tokenName := util.GetOwnerAndNameFromIdNoCheck(claims.ID)
+	token, err := getToken(owner, tokenName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if token == nil || token.ExpiresIn <= 0 || token.GetIdToken() != tokenString {
+		return nil, nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid or has been revoked", name),
+		}, nil
+	}
+
+	return token, claims, nil, nil
+}
+
+// checkExchangeTokenBinding requires the proof of possession of a sender-constrained subject or actor token, so a
+// stolen DPoP or certificate-bound token can't be exchanged for a bearer token
+func checkExchangeTokenBinding(token *Token, dpopJkt string, certThumbprint string, name string) *TokenError {
+	if token.DpopJkt != "" && token.DpopJkt != dpopJkt {
+		return &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is bound to a DPoP key, the request should carry a DPoP proof of the same key", name),
+		}
+	}
+	if token.CertThumbprint != "" && token.CertThumbprint != certThumbprint {
+		return &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is bound to a client certificate, the request should be sent with the same certificate", name),
+		}
+	}
+	return nil
+}
+
+// isTokenIssuedToApplication reports whether the token has been issued to the application, including the tokens of
+// the client credentials grant that the application gets for itself
+func isTokenIssuedToApplication(token *Token, application *Application) bool {
+	return token.Owner == application.Owner && token.Application == application.Name
+}
+
+// canExchangeSubjectToken reports whether the application can exchange the subject token: the token must have been
+// issued to the application itself, or to an application that lists its client id in TokenExchangeClients
+func canExchangeSubjectToken(subject *Token, application *Application) (bool, error) {
+	if isTokenIssuedToApplication(subject, application) {
+		return true, nil
+	}
+
+	subjectApplication, err := getApplication(subject.Owner, subject.Application)
+	if err != nil {
+		return false, err
+	}
+	return subjectApplication != nil && util.InSlice(subjectApplication.TokenExchangeClients, application.ClientId), nil
+}
+
+// isScopeSubset reports whether every scope in the requested scope is also granted to the subject token
+func isScopeSubset(scope string, grantedScope string) bool {
+	granted := strings.Fields(strings.ReplaceAll(grantedScope, ",", " "))
+	for _, item := range strings.Fields(strings.ReplaceAll(scope, ",", " ")) {
+		if !util.InSlice(granted, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTokenExchangeToken
+// Token Exchange, per RFC 8693 section 2
+func GetTokenExchangeToken(application *Application, req *TokenRequest) (*Token, *TokenError, error) {
+	if application.ClientSecret != req.ClientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	subject, subjectClaims, tokenError, err := parseExchangeToken(req.SubjectToken, req.SubjectTokenType, "subject_token")
+	if err != nil || tokenError != nil {
+		return nil, tokenError, err
+	}
+
+	tokenError = checkExchangeTokenBinding(subject, req.DpopJkt, req.CertThumbprint, "subject_token")
+	if tokenError != nil {
+		return nil, tokenError, nil
+	}
+
+	canExchange, err := canExchangeSubjectToken(subject, application)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !canExchange {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "subject_token is issued to another application that doesn't allow this application to exchange it",
+		}, nil
+	}
+
+	if subject.Organization != application.Organization && !application.IsShared {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "subject_token does not belong to the organization of this application",
+		}, nil
+	}
+
+	audience := req.Audience
+	if audience == "" {
+		audience = application.ClientId
+	} else if audience != application.ClientId && !util.InSlice(application.TokenExchangeAudiences, audience) {
+		return nil, &TokenError{
+			Error:            InvalidTarget,
+			ErrorDescription: fmt.Sprintf("audience: %s is not allowed for this application", audience),
+		}, nil
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = subject.Scope
+	} else if !isScopeSubset(scope, subject.Scope) {
+		return nil, &TokenError{
+			Error:            InvalidScope,
+			ErrorDescription: "the requested scope exceeds the scope of subject_token",
+		}, nil
+	}
+
+	// without an actor token the client impersonates the subject, and any prior delegation chain is kept
+	act := subjectClaims.Act
+	if req.ActorToken != "" {
+		actor, actorClaims, tokenError, err := parseExchangeToken(req.ActorToken, req.ActorTokenType, "actor_token")
+		if err != nil || tokenError != nil {
+			return nil, tokenError, err
+		}
+
+		tokenError = checkExchangeTokenBinding(actor, req.DpopJkt, req.CertThumbprint, "actor_token")
+		if tokenError != nil {
+			return nil, tokenError, nil
+		}
+
+		// the actor is the calling client or the user acting through it, not a party the client has no relation to
+		if !isTokenIssuedToApplication(actor, application) {
+			return nil, &TokenError{
+				Error:            InvalidGrant,
+				ErrorDescription: "actor_token should be issued to this application",
+			}, nil
+		}
+
+		act = &ActClaim{
+			Subject: actorClaims.Subject,
+			Act:     subjectClaims.Act,
+		}
+	}
+
+	user, err := GetUser(util.GetId(subject.Organization, subject.User))
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user of subject_token does not exist",
+		}, nil
+	}
+	if user.IsForbidden {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user is forbidden to sign in, please contact the administrator",
+		}, nil
+	}
+
+	err = ExtendUserWithRolesAndPermissions(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, _, tokenName, err := generateJwtTokenWithOptions(application, user, "", scope, req.Host, &jwtTokenOptions{Audience: []string{audience}, Act: act})
+	if err != nil {
+		return nil, &TokenError{
+			Error:            EndpointError,
+			ErrorDescription: fmt.Sprintf("generate jwt token error: %s", err.Error()),
+		}, nil
+	}
+
+	token := &Token{
+		Owner:        application.Owner,
+		Name:         tokenName,
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  application.Name,
+		Organization: user.Owner,
+		User:         user.Name,
+		Code:         util.GenerateClientId(),
+		AccessToken:  accessToken,
+		ExpiresIn:    application.ExpireInHours * hourSeconds,
+		Scope:        scope,
+		TokenType:    "Bearer",
+		CodeIsUsed:   true,
+	}
+	_, err = AddToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, nil, nil
+}
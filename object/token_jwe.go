@@ -0,0 +1,180 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// the default content encryption of OIDC Core 1.0 section 10.2 and OIDC Registration 1.0 section 2
+const defaultEncryptionEnc = "A128CBC-HS256"
+
+func getEncryptionAlgValuesSupported() []string {
+	return []string{"RSA-OAEP", "RSA-OAEP-256", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW"}
+}
+
+func getEncryptionEncValuesSupported() []string {
+	return []string{"A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"}
+}
+
+func getUserinfoSigningAlgValuesSupported() []string {
+	return []string{"RS256", "RS512", "ES256", "ES384", "ES512"}
+}
+
+// getJwksEncryptionKey returns the first public key of the JWKS that can be used with the key management algorithm
+func getJwksEncryptionKey(jwks *jose.JSONWebKeySet, alg string) *jose.JSONWebKey {
+	for i := range jwks.Keys {
+		key := &jwks.Keys[i]
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RSA-") {
+				return key
+			}
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(alg, "ECDH-ES") {
+				return key
+			}
+		}
+	}
+	return nil
+}
+
+// encryptToApplication encrypts the payload to the registered JWKS of the application, the content type is "JWT"
+// for a nested JWT (sign-then-encrypt)
+func encryptToApplication(application *Application, payload string, alg string, enc string, isNested bool) (string, error) {
+	if !util.InSlice(getEncryptionAlgValuesSupported(), alg) {
+		return "", fmt.Errorf("the encryption algorithm: %s is not supported", alg)
+	}
+	if enc == "" {
+		enc = defaultEncryptionEnc
+	}
+	if !util.InSlice(getEncryptionEncValuesSupported(), enc) {
+		return "", fmt.Errorf("the content encryption algorithm: %s is not supported", enc)
+	}
+
+	jwks, err := getApplicationJwks(application)
+	if err != nil {
+		return "", err
+	}
+
+	key := getJwksEncryptionKey(jwks, alg)
+	if key == nil {
+		return "", fmt.Errorf("the JWKS of the application: %s has no encryption key for: %s", application.GetId(), alg)
+	}
+
+	options := &jose.EncrypterOptions{}
+	if isNested {
+		options = options.WithContentType("JWT")
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.ContentEncryption(enc), jose.Recipient{Algorithm: jose.KeyAlgorithm(alg), Key: key.Key, KeyID: key.KeyID}, options)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := encrypter.Encrypt([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+
+	return object.CompactSerialize()
+}
+
+// GetIdTokenResponse returns the ID token as sent to the client, it's encrypted to the key of the client if
+// id_token_encrypted_response_alg is registered
+func GetIdTokenResponse(application *Application, token *Token) (string, error) {
+	idToken := token.GetIdToken()
+	if application.IdTokenEncryptedResponseAlg == "" {
+		return idToken, nil
+	}
+
+	return encryptToApplication(application, idToken, application.IdTokenEncryptedResponseAlg, application.IdTokenEncryptedResponseEnc, true)
+}
+
+// GetUserInfoJwt returns the userinfo response as a signed and/or encrypted JWT (OIDC Core 1.0 section 5.3.2), or
+// an empty string if the client of the audience expects the plain JSON response
+func GetUserInfoJwt(userInfo *Userinfo, aud string) (string, error) {
+	if aud == "" {
+		return "", nil
+	}
+
+	application, err := getApplicationByAudience([]string{aud})
+	if err != nil {
+		return "", err
+	}
+	if application == nil || (application.UserinfoSignedResponseAlg == "" && application.UserinfoEncryptedResponseAlg == "") {
+		return "", nil
+	}
+
+	data, err := json.Marshal(userInfo)
+	if err != nil {
+		return "", err
+	}
+
+	res := string(data)
+	if application.UserinfoSignedResponseAlg != "" {
+		alg := application.UserinfoSignedResponseAlg
+		if !util.InSlice(getUserinfoSigningAlgValuesSupported(), alg) {
+			return "", fmt.Errorf("the userinfo signing algorithm: %s is not supported", alg)
+		}
+
+		cert, err := getCertByApplication(application)
+		if err != nil {
+			return "", err
+		}
+		if cert == nil {
+			return "", fmt.Errorf("the cert of the application: %s does not exist", application.GetId())
+		}
+
+		key, err := getCertPrivateKey(cert, alg)
+		if err != nil {
+			return "", err
+		}
+
+		claims := jwt.MapClaims{}
+		err = json.Unmarshal(data, &claims)
+		if err != nil {
+			return "", err
+		}
+
+		token := jwt.NewWithClaims(jwt.GetSigningMethod(alg), claims)
+		token.Header["kid"] = cert.GetKeyId()
+		res, err = token.SignedString(key)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if application.UserinfoEncryptedResponseAlg != "" {
+		return encryptToApplication(application, res, application.UserinfoEncryptedResponseAlg, application.UserinfoEncryptedResponseEnc, application.UserinfoSignedResponseAlg != "")
+	}
+
+	return res, nil
+}
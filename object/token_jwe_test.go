@@ -0,0 +1,67 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/casdoor/casdoor/util"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestEncryptToApplication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: "sig-key", Use: "sig"},
+		{Key: &key.PublicKey, KeyID: "enc-key", Use: "enc"},
+	}}
+	application := &Application{Owner: "admin", Name: "app-test", Jwks: util.StructToJson(jwks)}
+
+	payload := "header.payload.signature"
+	res, err := encryptToApplication(application, payload, "RSA-OAEP-256", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := jose.ParseEncrypted(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Header.KeyID != "enc-key" {
+		t.Errorf("kid = %s, want enc-key", object.Header.KeyID)
+	}
+	if cty := object.Header.ExtraHeaders[jose.HeaderContentType]; cty != "JWT" {
+		t.Errorf("cty = %v, want JWT", cty)
+	}
+
+	plaintext, err := object.Decrypt(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != payload {
+		t.Errorf("plaintext = %s, want %s", plaintext, payload)
+	}
+
+	_, err = encryptToApplication(application, payload, "ECDH-ES", "", true)
+	if err == nil {
+		t.Errorf("an ECDH-ES encryption should fail without an EC key")
+	}
+}
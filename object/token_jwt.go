@@ -15,6 +15,7 @@
 package object
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -26,13 +27,29 @@ import (
 
 type Claims struct {
 	*User
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Tag       string `json:"tag"`
-	Scope     string `json:"scope,omitempty"`
+	TokenType string    `json:"tokenType,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+	Tag       string    `json:"tag"`
+	Scope     string    `json:"scope,omitempty"`
+	Act       *ActClaim `json:"act,omitempty"`
+	Cnf       *CnfClaim `json:"cnf,omitempty"`
+	Sid       string    `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// CnfClaim is the "cnf" (confirmation) claim, jkt is the thumbprint of the DPoP key the token is bound to (RFC 9449 section 6)
+// and x5t#S256 is the thumbprint of the TLS client certificate (RFC 8705 section 3.1)
+type CnfClaim struct {
+	Jkt     string `json:"jkt,omitempty"`
+	X5tS256 string `json:"x5t#S256,omitempty"`
+}
+
+// ActClaim is the "act" (actor) claim of RFC 8693 section 4.1, nested actors are the prior ones in the delegation chain
+type ActClaim struct {
+	Subject string    `json:"sub"`
+	Act     *ActClaim `json:"act,omitempty"`
+}
+
 type UserShort struct {
 	Owner string `xorm:"varchar(100) notnull pk" json:"owner"`
 	Name  string `xorm:"varchar(100) notnull pk" json:"name"`
@@ -134,9 +151,12 @@ type UserWithoutThirdIdp struct {
 
 type ClaimsShort struct {
 	*UserShort
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Scope     string `json:"scope,omitempty"`
+	TokenType string    `json:"tokenType,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	Act       *ActClaim `json:"act,omitempty"`
+	Cnf       *CnfClaim `json:"cnf,omitempty"`
+	Sid       string    `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -151,10 +171,13 @@ type OIDCAddress struct {
 
 type ClaimsWithoutThirdIdp struct {
 	*UserWithoutThirdIdp
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Tag       string `json:"tag"`
-	Scope     string `json:"scope,omitempty"`
+	TokenType string    `json:"tokenType,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+	Tag       string    `json:"tag"`
+	Scope     string    `json:"scope,omitempty"`
+	Act       *ActClaim `json:"act,omitempty"`
+	Cnf       *CnfClaim `json:"cnf,omitempty"`
+	Sid       string    `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -268,6 +291,9 @@ func getShortClaims(claims Claims) ClaimsShort {
 		TokenType:        claims.TokenType,
 		Nonce:            claims.Nonce,
 		Scope:            claims.Scope,
+		Act:              claims.Act,
+		Cnf:              claims.Cnf,
+		Sid:              claims.Sid,
 		RegisteredClaims: claims.RegisteredClaims,
 	}
 	return res
@@ -280,6 +306,9 @@ func getClaimsWithoutThirdIdp(claims Claims) ClaimsWithoutThirdIdp {
 		Nonce:               claims.Nonce,
 		Tag:                 claims.Tag,
 		Scope:               claims.Scope,
+		Act:                 claims.Act,
+		Cnf:                 claims.Cnf,
+		Sid:                 claims.Sid,
 		RegisteredClaims:    claims.RegisteredClaims,
 	}
 	return res
@@ -301,6 +330,15 @@ func getClaimsCustom(claims Claims, tokenField []string) jwt.MapClaims {
 	res["nonce"] = claims.Nonce
 	res["tag"] = claims.Tag
 	res["scope"] = claims.Scope
+	if claims.Act != nil {
+		res["act"] = claims.Act
+	}
+	if claims.Cnf != nil {
+		res["cnf"] = claims.Cnf
+	}
+	if claims.Sid != "" {
+		res["sid"] = claims.Sid
+	}
 
 	for _, field := range tokenField {
 		userField := userValue.FieldByName(field)
@@ -335,7 +373,91 @@ func refineUser(user *User) *User {
 	return user
 }
 
+// jwtTokenOptions carries the optional parts of an issued token, the zero value gives a plain token
+type jwtTokenOptions struct {
+	// Name reuses an existing token name (and so its jti) instead of generating a new one
+	Name string
+	// Audience replaces the default audience (the client id of the application)
+	Audience []string
+	// Act is set for tokens obtained via token exchange on behalf of the user
+	Act *ActClaim
+	// Jkt binds the token to a DPoP key and is emitted as cnf.jkt
+	Jkt string
+	// X5tS256 binds the token to a TLS client certificate and is emitted as cnf.x5t#S256
+	X5tS256 string
+	// Sid is the session id of the user at Casdoor, used by the RPs to correlate the logout tokens
+	Sid string
+	// AuthContext is emitted as the acr, amr and auth_time claims along with the claims requested for the ID token
+	AuthContext *AuthContext
+	// AuthorizationDetails is the JSON of the granted authorization details (RFC 9396 section 9.1)
+	AuthorizationDetails string
+}
+
+func getJwtSigningMethod(application *Application) jwt.SigningMethod {
+	if application.TokenSigningMethod == "RS256" {
+		return jwt.SigningMethodRS256
+	} else if application.TokenSigningMethod == "RS512" {
+		return jwt.SigningMethodRS512
+	} else if application.TokenSigningMethod == "ES256" {
+		return jwt.SigningMethodES256
+	} else if application.TokenSigningMethod == "ES512" {
+		return jwt.SigningMethodES512
+	} else if application.TokenSigningMethod == "ES384" {
+		return jwt.SigningMethodES384
+	} else {
+		return jwt.SigningMethodRS256
+	}
+}
+
+// getApplicationSigningKey returns the cert of the application and its private key for signing tokens
+func getApplicationSigningKey(application *Application) (*Cert, interface{}, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cert == nil {
+		if application.Cert == "" {
+			return nil, nil, fmt.Errorf("The cert field of the application \"%s\" should not be empty", application.GetId())
+		} else {
+			return nil, nil, fmt.Errorf("The cert \"%s\" does not exist", application.Cert)
+		}
+	}
+
+	key, err := getCertPrivateKey(cert, application.TokenSigningMethod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+// getCertPrivateKey returns the private key of the cert for the signing method
+func getCertPrivateKey(cert *Cert, signingMethod string) (interface{}, error) {
+	var key interface{}
+	var err error
+	if strings.Contains(signingMethod, "RS") || signingMethod == "" {
+		// RSA private key
+		key, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	} else if strings.Contains(signingMethod, "ES") {
+		// ES private key
+		key, err = jwt.ParseECPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	} else if strings.Contains(signingMethod, "Ed") {
+		// Ed private key
+		key, err = jwt.ParseEdPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
 func generateJwtToken(application *Application, user *User, nonce string, scope string, host string) (string, string, string, error) {
+	return generateJwtTokenWithOptions(application, user, nonce, scope, host, &jwtTokenOptions{})
+}
+
+func generateJwtTokenWithOptions(application *Application, user *User, nonce string, scope string, host string, options *jwtTokenOptions) (string, string, string, error) {
 	nowTime := time.Now()
 	expireTime := nowTime.Add(time.Duration(application.ExpireInHours) * time.Hour)
 	refreshExpireTime := nowTime.Add(time.Duration(application.RefreshExpireInHours) * time.Hour)
@@ -347,7 +469,10 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 
 	_, originBackend := getOriginFromHost(host)
 
-	name := util.GenerateId()
+	name := options.Name
+	if name == "" {
+		name = util.GenerateId()
+	}
 	jti := util.GetId(application.Owner, name)
 
 	claims := Claims{
@@ -357,6 +482,8 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 		// FIXME: A workaround for custom claim by reusing `tag` in user info
 		Tag:   user.Tag,
 		Scope: scope,
+		Act:   options.Act,
+		Sid:   options.Sid,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    originBackend,
 			Subject:   user.Id,
@@ -371,6 +498,12 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 	if application.IsShared {
 		claims.Audience = []string{application.ClientId + "-org-" + user.Owner}
 	}
+	if len(options.Audience) != 0 {
+		claims.Audience = options.Audience
+	}
+	if options.Jkt != "" || options.X5tS256 != "" {
+		claims.Cnf = &CnfClaim{Jkt: options.Jkt, X5tS256: options.X5tS256}
+	}
 
 	var token *jwt.Token
 	var refreshToken *jwt.Token
@@ -379,24 +512,11 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 		application.TokenFormat = "JWT"
 	}
 
-	var jwtMethod jwt.SigningMethod
-
-	if application.TokenSigningMethod == "RS256" {
-		jwtMethod = jwt.SigningMethodRS256
-	} else if application.TokenSigningMethod == "RS512" {
-		jwtMethod = jwt.SigningMethodRS512
-	} else if application.TokenSigningMethod == "ES256" {
-		jwtMethod = jwt.SigningMethodES256
-	} else if application.TokenSigningMethod == "ES512" {
-		jwtMethod = jwt.SigningMethodES512
-	} else if application.TokenSigningMethod == "ES384" {
-		jwtMethod = jwt.SigningMethodES384
-	} else {
-		jwtMethod = jwt.SigningMethodRS256
-	}
+	jwtMethod := getJwtSigningMethod(application)
 
 	// the JWT token length in "JWT-Empty" mode will be very short, as User object only has two properties: owner and name
-	if application.TokenFormat == "JWT" {
+	// the ID token of the "Opaque" mode is the same as the access token of the "JWT" mode
+	if application.TokenFormat == "JWT" || application.TokenFormat == TokenFormatOpaque {
 		claimsWithoutThirdIdp := getClaimsWithoutThirdIdp(claims)
 
 		token = jwt.NewWithClaims(jwtMethod, claimsWithoutThirdIdp)
@@ -429,40 +549,43 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 		return "", "", "", fmt.Errorf("unknown application TokenFormat: %s", application.TokenFormat)
 	}
 
-	cert, err := getCertByApplication(application)
+	// the claims of the requested custom scopes are added to the access token
+	customClaims, err := getCustomScopeClaims(application, user, scope)
 	if err != nil {
 		return "", "", "", err
 	}
-
-	if cert == nil {
-		if application.Cert == "" {
-			return "", "", "", fmt.Errorf("The cert field of the application \"%s\" should not be empty", application.GetId())
-		} else {
-			return "", "", "", fmt.Errorf("The cert \"%s\" does not exist", application.Cert)
+	// the claims of the authentication and the requested claims are added unless the token already has them
+	authClaims, err := options.AuthContext.getClaims(user, scope)
+	if err != nil {
+		return "", "", "", err
+	}
+	if options.AuthorizationDetails != "" {
+		authClaims["authorization_details"] = json.RawMessage(options.AuthorizationDetails)
+	}
+	if len(customClaims) != 0 || len(authClaims) != 0 {
+		claimsWithCustomClaims, err := addCustomClaims(token.Claims, customClaims)
+		if err != nil {
+			return "", "", "", err
+		}
+		for name, value := range authClaims {
+			if _, ok := claimsWithCustomClaims[name]; !ok {
+				claimsWithCustomClaims[name] = value
+			}
 		}
+		token.Claims = jwt.MapClaims(claimsWithCustomClaims)
+	}
+
+	cert, key, err := getApplicationSigningKey(application)
+	if err != nil {
+		return "", "", "", err
 	}
 
 	var (
 		tokenString        string
 		refreshTokenString string
-		key                interface{}
 	)
 
-	if strings.Contains(application.TokenSigningMethod, "RS") || application.TokenSigningMethod == "" {
-		// RSA private key
-		key, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
-	} else if strings.Contains(application.TokenSigningMethod, "ES") {
-		// ES private key
-		key, err = jwt.ParseECPrivateKeyFromPEM([]byte(cert.PrivateKey))
-	} else if strings.Contains(application.TokenSigningMethod, "Ed") {
-		// Ed private key
-		key, err = jwt.ParseEdPrivateKeyFromPEM([]byte(cert.PrivateKey))
-	}
-	if err != nil {
-		return "", "", "", err
-	}
-
-	token.Header["kid"] = cert.Name
+	token.Header["kid"] = cert.GetKeyId()
 	tokenString, err = token.SignedString(key)
 	if err != nil {
 		return "", "", "", err
@@ -472,8 +595,9 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 	return tokenString, refreshTokenString, name, err
 }
 
-func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
-	t, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// getCertPublicKeyFunc returns the key function that verifies a JWT with the public key of the cert
+func getCertPublicKeyFunc(cert *Cert) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
 		var (
 			certificate interface{}
 			err         error
@@ -483,12 +607,19 @@ func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
 			return nil, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 		}
 
+		// the token may be signed with the next key or a recently retired key of the cert
+		kid, _ := token.Header["kid"].(string)
+		publicCertificate, err := cert.getPublicCertificate(kid)
+		if err != nil {
+			return nil, err
+		}
+
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
 			// RSA certificate
-			certificate, err = jwt.ParseRSAPublicKeyFromPEM([]byte(cert.Certificate))
+			certificate, err = jwt.ParseRSAPublicKeyFromPEM([]byte(publicCertificate))
 		} else if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
 			// ES certificate
-			certificate, err = jwt.ParseECPublicKeyFromPEM([]byte(cert.Certificate))
+			certificate, err = jwt.ParseECPublicKeyFromPEM([]byte(publicCertificate))
 		} else {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -498,7 +629,11 @@ func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
 		}
 
 		return certificate, nil
-	})
+	}
+}
+
+func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
+	t, err := jwt.ParseWithClaims(token, &Claims{}, getCertPublicKeyFunc(cert))
 
 	if t != nil {
 		if claims, ok := t.Claims.(*Claims); ok && t.Valid {
@@ -509,6 +644,27 @@ func ParseJwtToken(token string, cert *Cert) (*Claims, error) {
 	return nil, err
 }
 
+// parseJwtTokenWithoutClaimsValidation verifies the signature of a token of the application but accepts it
+// even if it has expired
+func parseJwtTokenWithoutClaimsValidation(token string, application *Application) (*Claims, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("the cert of the application: %s does not exist", application.GetId())
+	}
+
+	claims := Claims{}
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	_, err = parser.ParseWithClaims(token, &claims, getCertPublicKeyFunc(cert))
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
 func ParseJwtTokenByApplication(token string, application *Application) (*Claims, error) {
 	cert, err := getCertByApplication(application)
 	if err != nil {
@@ -0,0 +1,360 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const JwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// TrustedIssuer is an external issuer whose signed JWTs can be exchanged for tokens of the organization
+// with the JWT bearer grant (RFC 7523 section 2.1). The value of SubjectClaim in the assertion is looked up
+// in the UserField of the users, and a missing user is created when CreateUser is set
+type TrustedIssuer struct {
+	Issuer       string   `json:"issuer"`
+	Jwks         string   `json:"jwks"`
+	JwksUri      string   `json:"jwksUri"`
+	Audiences    []string `json:"audiences"`
+	SubjectClaim string   `json:"subjectClaim"`
+	UserField    string   `json:"userField"`
+	CreateUser   bool     `json:"createUser"`
+}
+
+func getTrustedIssuer(organization *Organization, issuer string) *TrustedIssuer {
+	for _, trustedIssuer := range organization.TrustedIssuers {
+		if trustedIssuer.Issuer == issuer {
+			return trustedIssuer
+		}
+	}
+	return nil
+}
+
+func (trustedIssuer *TrustedIssuer) getJwk(kid string) (interface{}, error) {
+	var jwks *jose.JSONWebKeySet
+	if trustedIssuer.Jwks != "" {
+		jwks = &jose.JSONWebKeySet{}
+		err := json.Unmarshal([]byte(trustedIssuer.Jwks), jwks)
+		if err != nil {
+			return nil, fmt.Errorf("the JWKS of the trusted issuer: %s is invalid: %s", trustedIssuer.Issuer, err.Error())
+		}
+	} else if trustedIssuer.JwksUri != "" {
+		var err error
+		jwks, err = fetchJwks(trustedIssuer.JwksUri)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("the trusted issuer: %s has no JWKS", trustedIssuer.Issuer)
+	}
+
+	key := getJwksSigningKey(jwks, kid)
+	if key == nil {
+		return nil, fmt.Errorf("no signing key with kid: %s is found in the JWKS of the trusted issuer: %s", kid, trustedIssuer.Issuer)
+	}
+	return key, nil
+}
+
+func (trustedIssuer *TrustedIssuer) getUserField() string {
+	switch trustedIssuer.UserField {
+	case "Id", "Email", "Phone":
+		return trustedIssuer.UserField
+	default:
+		return "Name"
+	}
+}
+
+// getJwtBearerAudiences returns the audiences that an assertion may be issued for, the issuer and the token
+// endpoint of Casdoor and the client id of the application are accepted unless the trusted issuer restricts them
+func getJwtBearerAudiences(application *Application, trustedIssuer *TrustedIssuer, host string) []string {
+	if len(trustedIssuer.Audiences) != 0 {
+		return trustedIssuer.Audiences
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	return []string{originBackend, fmt.Sprintf("%s/api/login/oauth/access_token", originBackend), application.ClientId}
+}
+
+// getJwtBearerUser returns the user that the subject of the assertion is mapped onto, creating it if allowed
+func getJwtBearerUser(application *Application, trustedIssuer *TrustedIssuer, subject string, claims jwt.MapClaims, lang string) (*User, *TokenError, error) {
+	userField := trustedIssuer.getUserField()
+	user, err := GetUserByField(application.Organization, userField, subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user != nil {
+		return user, nil, nil
+	}
+
+	if !trustedIssuer.CreateUser {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("no user with %s: %s exists in the organization: %s", strings.ToLower(userField), subject, application.Organization),
+		}, nil
+	}
+
+	name := subject
+	if userField != "Name" || CheckUsername(subject, lang) != "" {
+		name = util.GenerateId()
+	}
+
+	user = &User{
+		Owner:             application.Organization,
+		Id:                util.GenerateId(),
+		Name:              name,
+		SignupApplication: application.Name,
+		Type:              "normal-user",
+		CreatedTime:       util.GetCurrentTime(),
+		IsAdmin:           false,
+		IsForbidden:       false,
+		IsDeleted:         false,
+		Properties: map[string]string{
+			"jwtBearerIssuer": trustedIssuer.Issuer,
+		},
+	}
+
+	switch userField {
+	case "Id":
+		user.Id = subject
+	case "Email":
+		user.Email = subject
+	case "Phone":
+		user.Phone = subject
+	}
+	if displayName, ok := claims["name"].(string); ok {
+		user.DisplayName = displayName
+	}
+	if email, ok := claims["email"].(string); ok && user.Email == "" {
+		user.Email = email
+	}
+
+	_, err = AddUser(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, nil, nil
+}
+
+// GetJwtBearerToken
+// JWT Bearer Grant, per RFC 7523 section 2.1 and 3. The assertion must be signed by one of the trusted issuers of
+// the organization of the application, and its subject is mapped onto a user of the organization
+func GetJwtBearerToken(application *Application, clientSecret string, assertion string, scope string, host string, lang string) (*Token, *TokenError, error) {
+	if application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if assertion == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "assertion should not be empty",
+		}, nil
+	}
+
+	unverifiedClaims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(assertion, unverifiedClaims)
+	if err != nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the assertion is not a valid JWT: %s", err.Error()),
+		}, nil
+	}
+
+	issuer, _ := unverifiedClaims["iss"].(string)
+	if issuer == "" {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the iss of the assertion should not be empty",
+		}, nil
+	}
+
+	organization, err := getOrganization("admin", application.Organization)
+	if err != nil {
+		return nil, nil, err
+	}
+	if organization == nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the organization: %s does not exist", application.Organization),
+		}, nil
+	}
+
+	trustedIssuer := getTrustedIssuer(organization, issuer)
+	if trustedIssuer == nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the issuer: %s is not trusted by the organization: %s", issuer, organization.Name),
+		}, nil
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (interface{}, error) {
+		if !isClientAssertionSigningMethod(token.Method) {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return trustedIssuer.getJwk(kid)
+	})
+	if err != nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the assertion is invalid: %s", err.Error()),
+		}, nil
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the exp of the assertion should not be empty",
+		}, nil
+	}
+
+	// the assertion is short-lived like a client assertion, so its replay key doesn't have to be kept for long
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the iat of the assertion should not be empty",
+		}, nil
+	}
+	issuedAt := time.Unix(int64(iat), 0)
+	expireTime := time.Unix(int64(exp), 0)
+	if time.Since(issuedAt) > clientAssertionMaxAgeSeconds*time.Second {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the assertion should not be issued more than %d seconds ago", clientAssertionMaxAgeSeconds),
+		}, nil
+	}
+	if expireTime.Sub(issuedAt) > clientAssertionMaxAgeSeconds*time.Second {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the lifetime of the assertion should not be longer than %d seconds", clientAssertionMaxAgeSeconds),
+		}, nil
+	}
+
+	audiences := getJwtBearerAudiences(application, trustedIssuer, host)
+	isAudienceValid := false
+	for _, audience := range audiences {
+		if claims.VerifyAudience(audience, true) {
+			isAudienceValid = true
+			break
+		}
+	}
+	if !isAudienceValid {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the aud of the assertion should be one of: %s", strings.Join(audiences, ", ")),
+		}, nil
+	}
+
+	subjectClaim := trustedIssuer.SubjectClaim
+	if subjectClaim == "" {
+		subjectClaim = "sub"
+	}
+	subject, _ := claims[subjectClaim].(string)
+	if subject == "" {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the %s of the assertion should not be empty", subjectClaim),
+		}, nil
+	}
+
+	// an assertion without jti is identified by its subject and validity, so it can't be replayed either
+	replayKey, _ := claims["jti"].(string)
+	if replayKey == "" {
+		replayKey = fmt.Sprintf("%s:%s/%d/%d", subjectClaim, subject, int64(iat), int64(exp))
+	}
+	isReplayed, err := checkClientAssertionJtiReplay(trustedIssuer.Issuer, replayKey, expireTime)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isReplayed {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the assertion has already been used",
+		}, nil
+	}
+
+	user, tokenError, err := getJwtBearerUser(application, trustedIssuer, subject, claims, lang)
+	if err != nil || tokenError != nil {
+		return nil, tokenError, err
+	}
+
+	if user.IsForbidden {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user is forbidden to sign in, please contact the administrator",
+		}, nil
+	}
+
+	allowed, err := CheckLoginPermission(user.GetId(), application)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user is not allowed to sign in to the application",
+		}, nil
+	}
+
+	err = ExtendUserWithRolesAndPermissions(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, "", scope, host)
+	if err != nil {
+		return nil, &TokenError{
+			Error:            EndpointError,
+			ErrorDescription: fmt.Sprintf("generate jwt token error: %s", err.Error()),
+		}, nil
+	}
+
+	token := &Token{
+		Owner:        application.Owner,
+		Name:         tokenName,
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  application.Name,
+		Organization: user.Owner,
+		User:         user.Name,
+		Code:         util.GenerateClientId(),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    application.ExpireInHours * hourSeconds,
+		Scope:        scope,
+		TokenType:    "Bearer",
+		CodeIsUsed:   true,
+	}
+	_, err = AddToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, nil, nil
+}
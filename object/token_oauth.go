@@ -35,6 +35,10 @@ const (
 	UnsupportedGrantType = "unsupported_grant_type"
 	InvalidScope         = "invalid_scope"
 	EndpointError        = "endpoint_error"
+	AuthorizationPending = "authorization_pending"
+	SlowDown             = "slow_down"
+	AccessDenied         = "access_denied"
+	ExpiredToken         = "expired_token"
 )
 
 type Code struct {
@@ -43,12 +47,47 @@ type Code struct {
 }
 
 type TokenWrapper struct {
-	AccessToken  string `json:"access_token"`
-	IdToken      string `json:"id_token"`
-	RefreshToken string `json:"refresh_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
-	Scope        string `json:"scope"`
+	AccessToken     string `json:"access_token"`
+	IdToken         string `json:"id_token"`
+	RefreshToken    string `json:"refresh_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+
+	AuthorizationDetails []AuthorizationDetail `json:"authorization_details,omitempty"`
+}
+
+// TokenRequest is a request to the token endpoint of an authenticated client. The client id and secret are the ones
+// returned by the client authentication, the DPoP key thumbprint and the certificate thumbprint are empty when the
+// request has no DPoP proof or client certificate
+type TokenRequest struct {
+	GrantType            string
+	ClientId             string
+	ClientSecret         string
+	Code                 string
+	Verifier             string
+	Scope                string
+	Nonce                string
+	Username             string
+	Password             string
+	Tag                  string
+	Avatar               string
+	RefreshToken         string
+	DeviceCode           string
+	AuthReqId            string
+	SubjectToken         string
+	SubjectTokenType     string
+	ActorToken           string
+	ActorTokenType       string
+	Audience             string
+	Assertion            string
+	AuthorizationDetails string
+
+	Host           string
+	Lang           string
+	DpopJkt        string
+	CertThumbprint string
 }
 
 type TokenError struct {
@@ -57,18 +96,21 @@ type TokenError struct {
 }
 
 type IntrospectionResponse struct {
-	Active    bool     `json:"active"`
-	Scope     string   `json:"scope,omitempty"`
-	ClientId  string   `json:"client_id,omitempty"`
-	Username  string   `json:"username,omitempty"`
-	TokenType string   `json:"token_type,omitempty"`
-	Exp       int64    `json:"exp,omitempty"`
-	Iat       int64    `json:"iat,omitempty"`
-	Nbf       int64    `json:"nbf,omitempty"`
-	Sub       string   `json:"sub,omitempty"`
-	Aud       []string `json:"aud,omitempty"`
-	Iss       string   `json:"iss,omitempty"`
-	Jti       string   `json:"jti,omitempty"`
+	Active    bool      `json:"active"`
+	Scope     string    `json:"scope,omitempty"`
+	ClientId  string    `json:"client_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	TokenType string    `json:"token_type,omitempty"`
+	Exp       int64     `json:"exp,omitempty"`
+	Iat       int64     `json:"iat,omitempty"`
+	Nbf       int64     `json:"nbf,omitempty"`
+	Sub       string    `json:"sub,omitempty"`
+	Aud       []string  `json:"aud,omitempty"`
+	Iss       string    `json:"iss,omitempty"`
+	Jti       string    `json:"jti,omitempty"`
+	Cnf       *CnfClaim `json:"cnf,omitempty"`
+
+	AuthorizationDetails []AuthorizationDetail `json:"authorization_details,omitempty"`
 }
 
 func ExpireTokenByAccessToken(accessToken string) (bool, *Application, *Token, error) {
@@ -94,7 +136,26 @@ func ExpireTokenByAccessToken(accessToken string) (bool, *Application, *Token, e
 	return affected != 0, application, token, nil
 }
 
-func CheckOAuthLogin(clientId string, responseType string, redirectUri string, scope string, state string, lang string) (string, *Application, error) {
+func CheckOAuthLogin(clientId string, responseType string, redirectUri string, scope string, state string, lang string, requestUri string) (string, *Application, error) {
+	isPushed := false
+	if requestUri != "" {
+		authRequest, err := GetAuthorizationRequest(clientId, requestUri)
+		if err != nil {
+			return "", nil, err
+		}
+		if authRequest == nil {
+			return i18n.Translate(lang, "token:The request_uri is invalid or has expired"), nil, nil
+		}
+		responseType, redirectUri = authRequest.ResponseType, authRequest.RedirectUri
+		isPushed = authRequest.IsPushed
+	}
+
+	return checkOAuthLogin(clientId, responseType, redirectUri, lang, isPushed)
+}
+
+// checkOAuthLogin checks the parameters of the authorization request, isPushed is true when they come from a request
+// pushed to the PAR endpoint
+func checkOAuthLogin(clientId string, responseType string, redirectUri string, lang string, isPushed bool) (string, *Application, error) {
 	if responseType != "code" && responseType != "token" && responseType != "id_token" {
 		return fmt.Sprintf(i18n.Translate(lang, "token:Grant_type: %s is not supported in this application"), responseType), nil, nil
 	}
@@ -112,12 +173,16 @@ func CheckOAuthLogin(clientId string, responseType string, redirectUri string, s
 		return fmt.Sprintf(i18n.Translate(lang, "token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), redirectUri), application, nil
 	}
 
+	if application.RequirePar && !isPushed {
+		return i18n.Translate(lang, "token:The application requires pushed authorization requests"), application, nil
+	}
+
 	// Mask application for /api/get-app-login
 	application.ClientSecret = ""
 	return "", application, nil
 }
 
-func GetOAuthCode(userId string, clientId string, responseType string, redirectUri string, scope string, state string, nonce string, challenge string, host string, lang string) (*Code, error) {
+func GetOAuthCode(userId string, clientId string, responseType string, redirectUri string, scope string, state string, nonce string, challenge string, host string, lang string, requestUri string, sid string, authContext *AuthContext, authorizationDetails string) (*Code, error) {
 	user, err := GetUser(userId)
 	if err != nil {
 		return nil, err
@@ -136,7 +201,28 @@ func GetOAuthCode(userId string, clientId string, responseType string, redirectU
 		}, nil
 	}
 
-	msg, application, err := CheckOAuthLogin(clientId, responseType, redirectUri, scope, state, lang)
+	// the parameters pushed by the client take precedence over the ones passed through the browser, the request_uri
+	// is consumed before the code is issued, so that only one code can be issued for it
+	isPushed := false
+	if requestUri != "" {
+		authRequest, err := ConsumeAuthorizationRequest(clientId, requestUri)
+		if err != nil {
+			return nil, err
+		}
+		if authRequest == nil {
+			return &Code{
+				Message: i18n.Translate(lang, "token:The request_uri is invalid or has expired"),
+				Code:    "",
+			}, nil
+		}
+
+		responseType, redirectUri, scope = authRequest.ResponseType, authRequest.RedirectUri, authRequest.Scope
+		nonce, challenge = authRequest.Nonce, authRequest.CodeChallenge
+		authorizationDetails = authRequest.AuthorizationDetails
+		isPushed = authRequest.IsPushed
+	}
+
+	msg, application, err := checkOAuthLogin(clientId, responseType, redirectUri, lang, isPushed)
 	if err != nil {
 		return nil, err
 	}
@@ -148,11 +234,20 @@ func GetOAuthCode(userId string, clientId string, responseType string, redirectU
 		}, nil
 	}
 
+	details, err := ParseAuthorizationDetails(application, authorizationDetails)
+	if err != nil {
+		return &Code{
+			Message: fmt.Sprintf("%s: %s", InvalidAuthorizationDetails, err.Error()),
+			Code:    "",
+		}, nil
+	}
+	authorizationDetails = getAuthorizationDetailsString(details)
+
 	err = ExtendUserWithRolesAndPermissions(user)
 	if err != nil {
 		return nil, err
 	}
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, nonce, scope, host)
+	accessToken, refreshToken, tokenName, err := generateJwtTokenWithOptions(application, user, nonce, scope, host, &jwtTokenOptions{Sid: sid, AuthContext: authContext, AuthorizationDetails: authorizationDetails})
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +272,11 @@ func GetOAuthCode(userId string, clientId string, responseType string, redirectU
 		CodeChallenge: challenge,
 		CodeIsUsed:    false,
 		CodeExpireIn:  time.Now().Add(time.Minute * 5).Unix(),
+		Sid:           sid,
+
+		AuthorizationDetails: authorizationDetails,
 	}
+	token.setAuthContext(authContext)
 	_, err = AddToken(token)
 	if err != nil {
 		return nil, err
@@ -189,8 +288,8 @@ func GetOAuthCode(userId string, clientId string, responseType string, redirectU
 	}, nil
 }
 
-func GetOAuthToken(grantType string, clientId string, clientSecret string, code string, verifier string, scope string, nonce string, username string, password string, host string, refreshToken string, tag string, avatar string, lang string) (interface{}, error) {
-	application, err := GetApplicationByClientId(clientId)
+func GetOAuthToken(req *TokenRequest) (interface{}, error) {
+	application, err := GetApplicationByClientId(req.ClientId)
 	if err != nil {
 		return nil, err
 	}
@@ -204,26 +303,41 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 
 	// Check if grantType is allowed in the current application
 
-	if !IsGrantTypeValid(grantType, application.GrantTypes) && tag == "" {
+	if !IsGrantTypeValid(req.GrantType, application.GrantTypes) && req.Tag == "" {
 		return &TokenError{
 			Error:            UnsupportedGrantType,
-			ErrorDescription: fmt.Sprintf("grant_type: %s is not supported in this application", grantType),
+			ErrorDescription: fmt.Sprintf("grant_type: %s is not supported in this application", req.GrantType),
+		}, nil
+	}
+
+	if application.EnableDpop && req.DpopJkt == "" {
+		return &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the application requires a DPoP proof",
 		}, nil
 	}
 
 	var token *Token
 	var tokenError *TokenError
-	switch grantType {
+	switch req.GrantType {
 	case "authorization_code": // Authorization Code Grant
-		token, tokenError, err = GetAuthorizationCodeToken(application, clientSecret, code, verifier)
+		token, tokenError, err = GetAuthorizationCodeToken(application, req.ClientSecret, req.Code, req.Verifier)
 	case "password": //	Resource Owner Password Credentials Grant
-		token, tokenError, err = GetPasswordToken(application, username, password, scope, host)
+		token, tokenError, err = GetPasswordToken(application, req.Username, req.Password, req.Scope, req.Host)
 	case "client_credentials": // Client Credentials Grant
-		token, tokenError, err = GetClientCredentialsToken(application, clientSecret, scope, host)
+		token, tokenError, err = GetClientCredentialsToken(application, req.ClientSecret, req.Scope, req.Host)
 	case "token", "id_token": // Implicit Grant
-		token, tokenError, err = GetImplicitToken(application, username, scope, nonce, host)
+		token, tokenError, err = GetImplicitToken(application, req.Username, req.Scope, req.Nonce, req.Host)
+	case DeviceCodeGrantType: // Device Authorization Grant
+		token, tokenError, err = GetDeviceCodeToken(application, req.ClientSecret, req.DeviceCode, req.Host)
+	case CibaGrantType: // Client-Initiated Backchannel Authentication
+		token, tokenError, err = GetCibaToken(application, req.ClientSecret, req.AuthReqId, req.Host)
+	case TokenExchangeGrantType: // Token Exchange
+		token, tokenError, err = GetTokenExchangeToken(application, req)
+	case JwtBearerGrantType: // JWT Bearer Grant
+		token, tokenError, err = GetJwtBearerToken(application, req.ClientSecret, req.Assertion, req.Scope, req.Host, req.Lang)
 	case "refresh_token":
-		refreshToken2, err := RefreshToken(grantType, refreshToken, scope, clientId, clientSecret, host)
+		refreshToken2, err := RefreshToken(req)
 		if err != nil {
 			return nil, err
 		}
@@ -234,9 +348,9 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 		return nil, err
 	}
 
-	if tag == "wechat_miniprogram" {
+	if req.Tag == "wechat_miniprogram" {
 		// Wechat Mini Program
-		token, tokenError, err = GetWechatMiniProgramToken(application, code, host, username, avatar, lang)
+		token, tokenError, err = GetWechatMiniProgramToken(application, req.Code, req.Host, req.Username, req.Avatar, req.Lang)
 		if err != nil {
 			return nil, err
 		}
@@ -246,31 +360,70 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 		return tokenError, nil
 	}
 
+	// the token request may ask for authorization details (RFC 9396 section 6), the token is re-issued with them
+	isDetailsChanged := false
+	if req.AuthorizationDetails != "" {
+		isDetailsChanged, tokenError = applyAuthorizationDetails(application, token, req.GrantType, req.AuthorizationDetails)
+		if tokenError != nil {
+			return tokenError, nil
+		}
+	}
+
+	// the tokens of a client that authenticated with its TLS certificate are bound to it (RFC 8705 section 3)
+	if application.EnableDpop || req.CertThumbprint != "" || isDetailsChanged {
+		dpopJkt := req.DpopJkt
+		if !application.EnableDpop {
+			dpopJkt = ""
+		}
+
+		err = bindTokenToKey(application, token, dpopJkt, req.CertThumbprint, req.Host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = issueOpaqueToken(application, token)
+	if err != nil {
+		return nil, err
+	}
+
 	token.CodeIsUsed = true
 
 	go updateUsedByCode(token)
 
+	idToken, err := GetIdTokenResponse(application, token)
+	if err != nil {
+		return nil, err
+	}
+
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  token.AccessToken,
-		IdToken:      token.AccessToken,
+		IdToken:      idToken,
 		RefreshToken: token.RefreshToken,
 		TokenType:    token.TokenType,
 		ExpiresIn:    token.ExpiresIn,
 		Scope:        token.Scope,
+
+		AuthorizationDetails: token.GetAuthorizationDetails(),
+	}
+
+	if req.GrantType == TokenExchangeGrantType {
+		tokenWrapper.IdToken = ""
+		tokenWrapper.IssuedTokenType = AccessTokenType
 	}
 
 	return tokenWrapper, nil
 }
 
-func RefreshToken(grantType string, refreshToken string, scope string, clientId string, clientSecret string, host string) (interface{}, error) {
+func RefreshToken(req *TokenRequest) (interface{}, error) {
 	// check parameters
-	if grantType != "refresh_token" {
+	if req.GrantType != "refresh_token" {
 		return &TokenError{
 			Error:            UnsupportedGrantType,
 			ErrorDescription: "grant_type should be refresh_token",
 		}, nil
 	}
-	application, err := GetApplicationByClientId(clientId)
+	application, err := GetApplicationByClientId(req.ClientId)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +435,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		}, nil
 	}
 
-	if clientSecret != "" && application.ClientSecret != clientSecret {
+	if req.ClientSecret != "" && application.ClientSecret != req.ClientSecret {
 		return &TokenError{
 			Error:            InvalidClient,
 			ErrorDescription: "client_secret is invalid",
@@ -290,7 +443,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 	}
 
 	// check whether the refresh token is valid, and has not expired.
-	token, err := GetTokenByRefreshToken(refreshToken)
+	token, err := GetTokenByRefreshToken(req.RefreshToken)
 	if err != nil || token == nil {
 		return &TokenError{
 			Error:            InvalidGrant,
@@ -298,6 +451,39 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		}, nil
 	}
 
+	// a rotated refresh token presented again is treated as stolen, the whole family is revoked
+	if token.IsRotated {
+		return getRefreshTokenReuseError(token)
+	}
+
+	if token.ExpiresIn <= 0 {
+		return &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "refresh token is invalid, expired or revoked",
+		}, nil
+	}
+
+	if isTokenFamilyExpired(application, token) {
+		return &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "refresh token has exceeded the absolute lifetime, please sign in again",
+		}, nil
+	}
+
+	// a refresh token bound to a DPoP key can only be used with a proof of the same key
+	if token.DpopJkt != "" && token.DpopJkt != req.DpopJkt {
+		return &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the DPoP proof doesn't match the key the refresh token is bound to",
+		}, nil
+	}
+	if application.EnableDpop && req.DpopJkt == "" {
+		return &TokenError{
+			Error:            InvalidDpopProof,
+			ErrorDescription: "the application requires a DPoP proof",
+		}, nil
+	}
+
 	cert, err := getCertByApplication(application)
 	if err != nil {
 		return nil, err
@@ -309,8 +495,15 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		}, nil
 	}
 
-	if application.TokenFormat == "JWT-Standard" {
-		_, err = ParseStandardJwtToken(refreshToken, cert)
+	if token.IsOpaque {
+		if time.Now().After(getOpaqueRefreshTokenExpireTime(application, token)) {
+			return &TokenError{
+				Error:            InvalidGrant,
+				ErrorDescription: "refresh token is invalid, expired or revoked",
+			}, nil
+		}
+	} else if application.TokenFormat == "JWT-Standard" {
+		_, err = ParseStandardJwtToken(req.RefreshToken, cert)
 		if err != nil {
 			return &TokenError{
 				Error:            InvalidGrant,
@@ -318,7 +511,7 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 			}, nil
 		}
 	} else {
-		_, err = ParseJwtToken(refreshToken, cert)
+		_, err = ParseJwtToken(req.RefreshToken, cert)
 		if err != nil {
 			return &TokenError{
 				Error:            InvalidGrant,
@@ -345,7 +538,55 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		return nil, err
 	}
 
-	newAccessToken, newRefreshToken, tokenName, err := generateJwtToken(application, user, "", scope, host)
+	tokenType := "Bearer"
+	dpopJkt := req.DpopJkt
+	if !application.EnableDpop {
+		dpopJkt = ""
+	} else {
+		tokenType = DpopTokenType
+	}
+
+	// the refreshed tokens can be narrowed down to a subset of the granted authorization details
+	authorizationDetails, err := narrowAuthorizationDetails(application, token.AuthorizationDetails, req.AuthorizationDetails)
+	if err != nil {
+		return &TokenError{
+			Error:            InvalidAuthorizationDetails,
+			ErrorDescription: err.Error(),
+		}, nil
+	}
+
+	// the refresh token is invalidated before the new one is issued, only one of the concurrent requests
+	// presenting it can succeed, and the other ones are treated as a reuse
+	if application.RotateRefreshToken {
+		isRotated, err := markTokenRotated(token)
+		if err != nil {
+			return nil, err
+		}
+		if !isRotated {
+			return getRefreshTokenReuseError(token)
+		}
+	} else {
+		isDeleted, err := DeleteToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if !isDeleted {
+			return &TokenError{
+				Error:            InvalidGrant,
+				ErrorDescription: "refresh token is invalid, expired or revoked",
+			}, nil
+		}
+	}
+
+	options := &jwtTokenOptions{
+		Jkt:     dpopJkt,
+		X5tS256: req.CertThumbprint,
+		Sid:     token.Sid,
+
+		AuthContext:          token.getAuthContext(),
+		AuthorizationDetails: authorizationDetails,
+	}
+	newAccessToken, newRefreshToken, tokenName, err := generateJwtTokenWithOptions(application, user, "", req.Scope, req.Host, options)
 	if err != nil {
 		return &TokenError{
 			Error:            EndpointError,
@@ -364,26 +605,43 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
 		ExpiresIn:    application.ExpireInHours * hourSeconds,
-		Scope:        scope,
-		TokenType:    "Bearer",
+		Scope:        req.Scope,
+		TokenType:    tokenType,
+		DpopJkt:      dpopJkt,
+
+		CertThumbprint:    req.CertThumbprint,
+		Sid:               token.Sid,
+		FamilyId:          token.getFamilyId(),
+		FamilyCreatedTime: token.getFamilyCreatedTime(),
+
+		AuthorizationDetails: authorizationDetails,
+	}
+	newToken.setAuthContext(token.getAuthContext())
+	if application.isOpaqueTokenAudience(application.ClientId) {
+		err = makeTokenOpaque(newToken)
+		if err != nil {
+			return nil, err
+		}
 	}
 	_, err = AddToken(newToken)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = DeleteToken(token)
+	idToken, err := GetIdTokenResponse(application, newToken)
 	if err != nil {
 		return nil, err
 	}
 
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  newToken.AccessToken,
-		IdToken:      newToken.AccessToken,
+		IdToken:      idToken,
 		RefreshToken: newToken.RefreshToken,
 		TokenType:    newToken.TokenType,
 		ExpiresIn:    newToken.ExpiresIn,
 		Scope:        newToken.Scope,
+
+		AuthorizationDetails: newToken.GetAuthorizationDetails(),
 	}
 	return tokenWrapper, nil
 }
@@ -619,7 +877,7 @@ func GetImplicitToken(application *Application, username string, scope string, n
 		}, nil
 	}
 
-	token, err := GetTokenByUser(application, user, scope, nonce, host)
+	token, err := GetTokenByUser(application, user, scope, nonce, host, "", nil, "")
 	if err != nil {
 		return nil, nil, err
 	}
@@ -628,13 +886,19 @@ func GetImplicitToken(application *Application, username string, scope string, n
 
 // GetTokenByUser
 // Implicit flow
-func GetTokenByUser(application *Application, user *User, scope string, nonce string, host string) (*Token, error) {
-	err := ExtendUserWithRolesAndPermissions(user)
+func GetTokenByUser(application *Application, user *User, scope string, nonce string, host string, sid string, authContext *AuthContext, authorizationDetails string) (*Token, error) {
+	details, err := ParseAuthorizationDetails(application, authorizationDetails)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", InvalidAuthorizationDetails, err.Error())
+	}
+	authorizationDetails = getAuthorizationDetailsString(details)
+
+	err = ExtendUserWithRolesAndPermissions(user)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, nonce, scope, host)
+	accessToken, refreshToken, tokenName, err := generateJwtTokenWithOptions(application, user, nonce, scope, host, &jwtTokenOptions{Sid: sid, AuthContext: authContext, AuthorizationDetails: authorizationDetails})
 	if err != nil {
 		return nil, err
 	}
@@ -653,6 +917,16 @@ func GetTokenByUser(application *Application, user *User, scope string, nonce st
 		Scope:        scope,
 		TokenType:    "Bearer",
 		CodeIsUsed:   true,
+		Sid:          sid,
+
+		AuthorizationDetails: authorizationDetails,
+	}
+	token.setAuthContext(authContext)
+	if application.isOpaqueTokenAudience(application.ClientId) {
+		err = makeTokenOpaque(token)
+		if err != nil {
+			return nil, err
+		}
 	}
 	_, err = AddToken(token)
 	if err != nil {
@@ -780,7 +1054,7 @@ func GetAccessTokenByUser(user *User, host string) (string, error) {
 		return "", fmt.Errorf("the application for user %s is not found", user.Id)
 	}
 
-	token, err := GetTokenByUser(application, user, "profile", "", host)
+	token, err := GetTokenByUser(application, user, "profile", "", host, "", nil, "")
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,211 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenFormatOpaque issues random reference access tokens, which can only be resolved by the introspection and
+// userinfo endpoints, the JWT is still issued as the ID token
+const TokenFormatOpaque = "Opaque"
+
+const opaqueTokenLength = 32
+
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// isOpaqueTokenAudience reports whether the access tokens for the audience are opaque, the resource servers
+// listed in the JWT audiences of an opaque application still get JWT access tokens. The grants that issue tokens
+// to the application itself check its client id, the others go through issueOpaqueToken with the issued audience
+func (application *Application) isOpaqueTokenAudience(audience string) bool {
+	if application.TokenFormat != TokenFormatOpaque {
+		return false
+	}
+	return !util.InSlice(application.JwtAudiences, audience)
+}
+
+// makeTokenOpaque replaces the JWT access and refresh tokens with random reference tokens, the JWT access token is
+// kept as the ID token. Only the hashes of the reference tokens are persisted by AddToken and UpdateToken
+func makeTokenOpaque(token *Token) error {
+	accessToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	token.IdToken = token.AccessToken
+	token.AccessToken = accessToken
+	token.AccessTokenHash = getTokenHash(accessToken)
+
+	if token.RefreshToken != "" {
+		refreshToken, err := generateOpaqueToken()
+		if err != nil {
+			return err
+		}
+
+		token.RefreshToken = refreshToken
+		token.RefreshTokenHash = getTokenHash(refreshToken)
+	}
+
+	token.IsOpaque = true
+	return nil
+}
+
+// issueOpaqueToken turns an issued token into an opaque one if the application requires it for the audience of the
+// token, it stays a JWT when one of its audiences is listed in the JWT audiences
+func issueOpaqueToken(application *Application, token *Token) error {
+	if token.IsOpaque {
+		return nil
+	}
+
+	// the token has just been signed by Casdoor, only its audience is read here
+	claims := Claims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token.AccessToken, &claims)
+	if err != nil {
+		return err
+	}
+
+	audiences := []string(claims.Audience)
+	if len(audiences) == 0 {
+		audiences = []string{application.ClientId}
+	}
+	for _, audience := range audiences {
+		if !application.isOpaqueTokenAudience(audience) {
+			return nil
+		}
+	}
+
+	err = makeTokenOpaque(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = UpdateToken(token.GetId(), token)
+	return err
+}
+
+// getPersistedToken returns the token as stored in the database, without the values of the opaque tokens
+func (token *Token) getPersistedToken() *Token {
+	if !token.IsOpaque {
+		return token
+	}
+
+	res := *token
+	res.AccessToken = ""
+	res.RefreshToken = ""
+	return &res
+}
+
+// GetIdToken returns the ID token issued with the access token
+func (token *Token) GetIdToken() string {
+	if token.IsOpaque {
+		return token.IdToken
+	}
+	return token.AccessToken
+}
+
+// getOpaqueRefreshTokenExpireTime returns when an opaque refresh token expires, as there's no JWT to carry it
+func getOpaqueRefreshTokenExpireTime(application *Application, token *Token) time.Time {
+	refreshExpireInHours := application.RefreshExpireInHours
+	if refreshExpireInHours == 0 {
+		refreshExpireInHours = application.ExpireInHours
+	}
+
+	createdTime, _ := time.Parse(time.RFC3339, token.CreatedTime)
+	return createdTime.Add(time.Duration(refreshExpireInHours) * time.Hour)
+}
+
+// getNumericDateUnix returns the Unix time of an optional JWT date, 0 is omitted from the introspection response
+func getNumericDateUnix(date *jwt.NumericDate) int64 {
+	if date == nil {
+		return 0
+	}
+	return date.Unix()
+}
+
+// ParseAccessToken returns the claims of an access token of the application, the claims of an opaque access token
+// are the ones of the ID token issued with it
+func ParseAccessToken(tokenString string, token *Token, application *Application) (*Claims, error) {
+	if token != nil && token.IsOpaque {
+		if token.AccessTokenHash != getTokenHash(tokenString) {
+			return nil, fmt.Errorf("the token is not the access token of: %s", token.GetId())
+		}
+		tokenString = token.IdToken
+	}
+
+	return ParseJwtTokenByApplication(tokenString, application)
+}
+
+// GetOpaqueTokenIntrospection resolves an opaque access or refresh token, per RFC 7662 section 2.2
+func GetOpaqueTokenIntrospection(application *Application, token *Token, tokenValue string, clientId string) *IntrospectionResponse {
+	var claims *Claims
+	var err error
+	tokenHash := getTokenHash(tokenValue)
+	if tokenHash == token.AccessTokenHash {
+		claims, err = ParseJwtTokenByApplication(token.IdToken, application)
+		if err != nil {
+			return &IntrospectionResponse{Active: false}
+		}
+	} else if token.RefreshTokenHash != "" && tokenHash == token.RefreshTokenHash {
+		// the ID token expires with the access token, the refresh token outlives it
+		expireTime := getOpaqueRefreshTokenExpireTime(application, token)
+		if time.Now().After(expireTime) {
+			return &IntrospectionResponse{Active: false}
+		}
+
+		claims, err = parseJwtTokenWithoutClaimsValidation(token.IdToken, application)
+		if err != nil {
+			return &IntrospectionResponse{Active: false}
+		}
+		claims.ExpiresAt = jwt.NewNumericDate(expireTime)
+	} else {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	var cnf *CnfClaim
+	if token.DpopJkt != "" || token.CertThumbprint != "" {
+		cnf = &CnfClaim{Jkt: token.DpopJkt, X5tS256: token.CertThumbprint}
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     token.Scope,
+		ClientId:  clientId,
+		Username:  token.User,
+		TokenType: token.TokenType,
+		Exp:       getNumericDateUnix(claims.ExpiresAt),
+		Iat:       getNumericDateUnix(claims.IssuedAt),
+		Nbf:       getNumericDateUnix(claims.NotBefore),
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Cnf:       cnf,
+
+		AuthorizationDetails: token.GetAuthorizationDetails(),
+	}
+}
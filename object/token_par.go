@@ -0,0 +1,322 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	RequestUriPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	InvalidRequestObject = "invalid_request_object"
+
+	// the request_uri is kept until the code is issued, so it must outlive a slow sign-in
+	parExpireInSeconds = 600
+
+	// a request object can't be valid for longer than this, its jti is remembered until it expires
+	requestObjectMaxLifetime = time.Hour
+
+	parOwner              = "par"
+	requestObjectJtiOwner = "request_object_jti"
+)
+
+type ParResponse struct {
+	RequestUri string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// AuthorizationRequest holds the authorization parameters pushed by the client or carried by a request object,
+// the json names follow the oAuthParams of the login page
+type AuthorizationRequest struct {
+	ClientId            string `json:"clientId"`
+	ResponseType        string `json:"responseType"`
+	RedirectUri         string `json:"redirectUri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"codeChallenge"`
+	CodeChallengeMethod string `json:"challengeMethod"`
+	AcrValues           string `json:"acrValues"`
+	MaxAge              string `json:"maxAge"`
+	Claims              string `json:"claims"`
+
+	AuthorizationDetails string `json:"authorizationDetails"`
+
+	// IsPushed is true when the request has been pushed to the PAR endpoint by the authenticated client, not passed
+	// by value to the authorization endpoint, only such a request satisfies RequirePar
+	IsPushed bool `json:"isPushed"`
+}
+
+type RequestObjectClaims struct {
+	ClientId            string `json:"client_id"`
+	ResponseType        string `json:"response_type"`
+	RedirectUri         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+
+	AcrValues            string          `json:"acr_values"`
+	MaxAge               *int64          `json:"max_age"`
+	Claims               json.RawMessage `json:"claims"`
+	AuthorizationDetails json.RawMessage `json:"authorization_details"`
+	jwt.RegisteredClaims
+}
+
+// getApplicationJwk returns the public key in the JWKS (or JWKS URI) of the application for the given key id
+func getApplicationJwk(application *Application, kid string) (interface{}, error) {
+	jwks, err := getApplicationJwks(application)
+	if err != nil {
+		return nil, err
+	}
+
+	key := getJwksSigningKey(jwks, kid)
+	if key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("no signing key with kid: %s is found in the JWKS of the application: %s", kid, application.GetId())
+}
+
+// parseRequestObject verifies a request object (RFC 9101): asymmetric algorithms are checked against
+// the registered JWKS of the application, HMAC algorithms against its client secret
+func parseRequestObject(application *Application, request string, host string) (*AuthorizationRequest, error) {
+	claims := RequestObjectClaims{}
+	_, err := jwt.ParseWithClaims(request, &claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			return []byte(application.ClientSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+			kid, _ := token.Header["kid"].(string)
+			return getApplicationJwk(application, kid)
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the exp is required, so that a captured request object can't be replayed indefinitely
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("the exp of the request object should not be empty")
+	}
+	if time.Until(claims.ExpiresAt.Time) > requestObjectMaxLifetime {
+		return nil, fmt.Errorf("the exp of the request object should be within %s", requestObjectMaxLifetime)
+	}
+
+	if claims.ClientId != application.ClientId {
+		return nil, fmt.Errorf("the client_id of the request object doesn't match")
+	}
+	if claims.Issuer != "" && claims.Issuer != application.ClientId {
+		return nil, fmt.Errorf("the iss of the request object should be the client_id")
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	if len(claims.Audience) != 0 && !claims.VerifyAudience(originBackend, true) {
+		return nil, fmt.Errorf("the aud of the request object should be: %s", originBackend)
+	}
+
+	if claims.ID != "" {
+		sum := sha256.Sum256([]byte(application.ClientId + "/" + claims.ID))
+		added, err := addSharedEntry(requestObjectJtiOwner, hex.EncodeToString(sum[:]), "", claims.ExpiresAt.Time)
+		if err != nil {
+			return nil, err
+		}
+		if !added {
+			return nil, fmt.Errorf("the request object has already been used")
+		}
+	}
+
+	maxAge := ""
+	if claims.MaxAge != nil {
+		maxAge = strconv.FormatInt(*claims.MaxAge, 10)
+	}
+
+	return &AuthorizationRequest{
+		ClientId:            claims.ClientId,
+		ResponseType:        claims.ResponseType,
+		RedirectUri:         claims.RedirectUri,
+		Scope:               claims.Scope,
+		State:               claims.State,
+		Nonce:               claims.Nonce,
+		CodeChallenge:       claims.CodeChallenge,
+		CodeChallengeMethod: claims.CodeChallengeMethod,
+		AcrValues:           claims.AcrValues,
+		MaxAge:              maxAge,
+		Claims:              string(claims.Claims),
+
+		AuthorizationDetails: string(claims.AuthorizationDetails),
+	}, nil
+}
+
+func checkAuthorizationRequest(application *Application, authRequest *AuthorizationRequest) string {
+	if authRequest.ResponseType != "code" && authRequest.ResponseType != "token" && authRequest.ResponseType != "id_token" {
+		return fmt.Sprintf("response_type: %s is not supported", authRequest.ResponseType)
+	}
+	if !application.IsRedirectUriValid(authRequest.RedirectUri) {
+		return fmt.Sprintf("redirect_uri: %s doesn't exist in the allowed Redirect URI list", authRequest.RedirectUri)
+	}
+	if authRequest.CodeChallengeMethod != "S256" && authRequest.CodeChallengeMethod != "" {
+		return "code_challenge_method should be S256"
+	}
+	if _, err := ParseClaimsRequest(authRequest.Claims); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func storeAuthorizationRequest(authRequest *AuthorizationRequest) (string, error) {
+	requestUri := RequestUriPrefix + util.GenerateId()
+	_, err := addSharedEntry(parOwner, requestUri, authRequest, time.Now().Add(parExpireInSeconds*time.Second))
+	if err != nil {
+		return "", err
+	}
+	return requestUri, nil
+}
+
+// PushAuthorizationRequest
+// Pushed Authorization Request, per RFC 9126 section 2
+func PushAuthorizationRequest(clientId string, clientSecret string, authRequest *AuthorizationRequest, request string, host string) (*ParResponse, *TokenError, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id is invalid",
+		}, nil
+	}
+
+	// public clients authenticate with their client_id only, like at the token endpoint
+	isPublicClient := application.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone && clientSecret == ""
+	if application.ClientSecret != clientSecret && !isPublicClient {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	// when a request object is used, the parameters outside of it are ignored
+	if request != "" {
+		authRequest, err = parseRequestObject(application, request, host)
+		if err != nil {
+			return nil, &TokenError{
+				Error:            InvalidRequestObject,
+				ErrorDescription: err.Error(),
+			}, nil
+		}
+	}
+	authRequest.ClientId = application.ClientId
+	authRequest.IsPushed = true
+
+	msg := checkAuthorizationRequest(application, authRequest)
+	if msg == "" && isPublicClient && authRequest.ResponseType == "code" && authRequest.CodeChallenge == "" {
+		msg = "code_challenge is required for public clients"
+	}
+	if msg != "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: msg,
+		}, nil
+	}
+
+	_, err = ParseAuthorizationDetails(application, authRequest.AuthorizationDetails)
+	if err != nil {
+		return nil, &TokenError{
+			Error:            InvalidAuthorizationDetails,
+			ErrorDescription: err.Error(),
+		}, nil
+	}
+
+	requestUri, err := storeAuthorizationRequest(authRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ParResponse{
+		RequestUri: requestUri,
+		ExpiresIn:  parExpireInSeconds,
+	}, nil, nil
+}
+
+// PushRequestObject verifies a request object passed by value to the authorization endpoint and
+// stores it like a pushed request, so the rest of the sign-in only needs to carry the request_uri. It is not
+// marked as pushed, since nothing has authenticated the client that sent it
+func PushRequestObject(clientId string, request string, host string) (string, *AuthorizationRequest, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return "", nil, err
+	}
+	if application == nil {
+		return "", nil, fmt.Errorf("client_id is invalid")
+	}
+
+	authRequest, err := parseRequestObject(application, request, host)
+	if err != nil {
+		return "", nil, err
+	}
+
+	msg := checkAuthorizationRequest(application, authRequest)
+	if msg != "" {
+		return "", nil, errors.New(msg)
+	}
+
+	_, err = ParseAuthorizationDetails(application, authRequest.AuthorizationDetails)
+	if err != nil {
+		return "", nil, err
+	}
+
+	requestUri, err := storeAuthorizationRequest(authRequest)
+	if err != nil {
+		return "", nil, err
+	}
+	return requestUri, authRequest, nil
+}
+
+// GetAuthorizationRequest returns the authorization request referenced by the request_uri, nil is returned if it
+// doesn't exist, has expired or has been pushed by another client
+func GetAuthorizationRequest(clientId string, requestUri string) (*AuthorizationRequest, error) {
+	authRequest := AuthorizationRequest{}
+	ok, err := getSharedEntry(parOwner, requestUri, &authRequest)
+	if err != nil || !ok || authRequest.ClientId != clientId {
+		return nil, err
+	}
+	return &authRequest, nil
+}
+
+// ConsumeAuthorizationRequest returns the authorization request referenced by the request_uri and removes it, so
+// that only one code or token can be issued for it
+func ConsumeAuthorizationRequest(clientId string, requestUri string) (*AuthorizationRequest, error) {
+	authRequest := AuthorizationRequest{}
+	ok, err := consumeSharedEntry(parOwner, requestUri, &authRequest)
+	if err != nil || !ok || authRequest.ClientId != clientId {
+		return nil, err
+	}
+	return &authRequest, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestRequestObject(t *testing.T, application *Application, jti string, expiresAt *jwt.NumericDate) string {
+	claims := RequestObjectClaims{
+		ClientId:     application.ClientId,
+		ResponseType: "code",
+		RedirectUri:  "https://app.example.com/callback",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: expiresAt,
+		},
+	}
+
+	request, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(application.ClientSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request
+}
+
+func TestParseRequestObject(t *testing.T) {
+	initTestOrmer(t, &SharedEntry{})
+	application := &Application{Owner: "admin", Name: "app-jar", ClientId: "client-jar", ClientSecret: "secret-jar"}
+	host := "door.example.com"
+	expiresAt := jwt.NewNumericDate(time.Now().Add(time.Minute))
+
+	if _, err := parseRequestObject(application, newTestRequestObject(t, application, "jti-1", nil), host); err == nil {
+		t.Errorf("a request object without exp should be rejected")
+	}
+	if _, err := parseRequestObject(application, newTestRequestObject(t, application, "jti-1", jwt.NewNumericDate(time.Now().Add(2*time.Hour))), host); err == nil {
+		t.Errorf("a request object valid for too long should be rejected")
+	}
+
+	request := newTestRequestObject(t, application, "jti-1", expiresAt)
+	authRequest, err := parseRequestObject(application, request, host)
+	if err != nil || authRequest.RedirectUri != "https://app.example.com/callback" {
+		t.Fatalf("a valid request object is rejected: %v", err)
+	}
+	if _, err = parseRequestObject(application, request, host); err == nil {
+		t.Errorf("a request object with a used jti should be rejected")
+	}
+
+	requestUri, err := storeAuthorizationRequest(authRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := GetAuthorizationRequest("another-client", requestUri); res != nil {
+		t.Errorf("the request_uri should not be read by another client")
+	}
+	if res, _ := ConsumeAuthorizationRequest(application.ClientId, requestUri); res == nil || res.RedirectUri != authRequest.RedirectUri {
+		t.Fatalf("the request_uri should be consumed: %v", res)
+	}
+	if res, _ := ConsumeAuthorizationRequest(application.ClientId, requestUri); res != nil {
+		t.Errorf("the request_uri should only be consumed once")
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+// InvalidAuthorizationDetails is the error of RFC 9396 section 5
+const InvalidAuthorizationDetails = "invalid_authorization_details"
+
+// AuthorizationDetailsType is a type of authorization details (RFC 9396) that the application accepts, the details
+// of the type are validated against its JSON schema
+type AuthorizationDetailsType struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Schema      string `json:"schema"`
+}
+
+// AuthorizationDetail is an object of the authorization_details parameter, its "type" field is required and the
+// other fields are defined by the type
+type AuthorizationDetail map[string]interface{}
+
+func (detail AuthorizationDetail) getType() string {
+	res, _ := detail["type"].(string)
+	return res
+}
+
+func (application *Application) getAuthorizationDetailsType(typ string) *AuthorizationDetailsType {
+	for _, detailsType := range application.AuthorizationDetailsTypes {
+		if detailsType.Type == typ {
+			return detailsType
+		}
+	}
+	return nil
+}
+
+// ParseAuthorizationDetails parses and validates the authorization_details parameter against the types registered
+// by the application, an empty parameter gives no details
+func ParseAuthorizationDetails(application *Application, authorizationDetails string) ([]AuthorizationDetail, error) {
+	res := []AuthorizationDetail{}
+	if authorizationDetails == "" {
+		return res, nil
+	}
+
+	err := json.Unmarshal([]byte(authorizationDetails), &res)
+	if err != nil {
+		return nil, fmt.Errorf("authorization_details should be a JSON array of objects: %s", err.Error())
+	}
+
+	for i, detail := range res {
+		if detail == nil || detail.getType() == "" {
+			return nil, fmt.Errorf("the authorization detail: %d has no type", i)
+		}
+
+		detailsType := application.getAuthorizationDetailsType(detail.getType())
+		if detailsType == nil {
+			return nil, fmt.Errorf("the authorization details type: %s is not allowed for the application: %s", detail.getType(), application.Name)
+		}
+
+		if detailsType.Schema != "" {
+			schema := map[string]interface{}{}
+			err = json.Unmarshal([]byte(detailsType.Schema), &schema)
+			if err != nil {
+				return nil, fmt.Errorf("the schema of the authorization details type: %s is invalid: %s", detailsType.Type, err.Error())
+			}
+
+			err = validateJsonSchema(schema, map[string]interface{}(detail), detailsType.Type)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// getAuthorizationDetailsString returns the JSON of the authorization details, or an empty string for no details
+func getAuthorizationDetailsString(authorizationDetails []AuthorizationDetail) string {
+	if len(authorizationDetails) == 0 {
+		return ""
+	}
+	return util.StructToJson(authorizationDetails)
+}
+
+// narrowAuthorizationDetails returns the requested authorization details if all of them have been granted, a token
+// request can only ask for a subset of the granted details (RFC 9396 section 6.1)
+func narrowAuthorizationDetails(application *Application, granted string, requested string) (string, error) {
+	if requested == "" {
+		return granted, nil
+	}
+
+	requestedDetails, err := ParseAuthorizationDetails(application, requested)
+	if err != nil {
+		return "", err
+	}
+	grantedDetails, err := ParseAuthorizationDetails(application, granted)
+	if err != nil {
+		return "", err
+	}
+
+	for _, requestedDetail := range requestedDetails {
+		isGranted := false
+		for _, grantedDetail := range grantedDetails {
+			if reflect.DeepEqual(requestedDetail, grantedDetail) {
+				isGranted = true
+				break
+			}
+		}
+		if !isGranted {
+			return "", fmt.Errorf("the authorization detail of type: %s has not been granted", requestedDetail.getType())
+		}
+	}
+
+	return getAuthorizationDetailsString(requestedDetails), nil
+}
+
+func checkAuthorizationDetailsTypes(application *Application) error {
+	types := map[string]bool{}
+	for _, detailsType := range application.AuthorizationDetailsTypes {
+		if detailsType.Type == "" {
+			return fmt.Errorf("the authorization details type should not be empty")
+		}
+		if types[detailsType.Type] {
+			return fmt.Errorf("the authorization details type: %s is duplicated", detailsType.Type)
+		}
+		types[detailsType.Type] = true
+
+		if detailsType.Schema != "" {
+			schema := map[string]interface{}{}
+			err := json.Unmarshal([]byte(detailsType.Schema), &schema)
+			if err != nil {
+				return fmt.Errorf("the schema of the authorization details type: %s is invalid: %s", detailsType.Type, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func getJsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return ""
+	}
+}
+
+func isJsonTypeOf(value interface{}, typ string) bool {
+	jsonType := getJsonType(value)
+	return jsonType == typ || (typ == "number" && jsonType == "integer")
+}
+
+// validateJsonSchema validates the value against the commonly used keywords of JSON Schema: type, enum, const,
+// required, properties, additionalProperties, items, minimum, maximum, minLength, maxLength and pattern
+func validateJsonSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if typ, ok := schema["type"]; ok {
+		types := []string{}
+		switch t := typ.(type) {
+		case string:
+			types = append(types, t)
+		case []interface{}:
+			for _, item := range t {
+				if s, ok := item.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+
+		isValid := false
+		for _, t := range types {
+			if isJsonTypeOf(value, t) {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			return fmt.Errorf("%s should be of type: %s", path, strings.Join(types, ", "))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		isValid := false
+		for _, item := range enum {
+			if reflect.DeepEqual(item, value) {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			return fmt.Errorf("%s should be one of: %s", path, util.StructToJson(enum))
+		}
+	}
+
+	if constValue, ok := schema["const"]; ok && !reflect.DeepEqual(constValue, value) {
+		return fmt.Errorf("%s should be: %s", path, util.StructToJson(constValue))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, item := range required {
+				if name, ok := item.(string); ok {
+					if _, ok = v[name]; !ok {
+						return fmt.Errorf("%s.%s is required", path, name)
+					}
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, propertyValue := range v {
+			if propertySchema, ok := properties[name].(map[string]interface{}); ok {
+				err := validateJsonSchema(propertySchema, propertyValue, path+"."+name)
+				if err != nil {
+					return err
+				}
+			} else if additionalProperties, ok := schema["additionalProperties"].(bool); ok && !additionalProperties && name != "type" {
+				return fmt.Errorf("%s.%s is not allowed", path, name)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				err := validateJsonSchema(items, item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && v < minimum {
+			return fmt.Errorf("%s should be at least: %v", path, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && v > maximum {
+			return fmt.Errorf("%s should be at most: %v", path, maximum)
+		}
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(len([]rune(v))) < minLength {
+			return fmt.Errorf("%s should have at least %v characters", path, minLength)
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && float64(len([]rune(v))) > maxLength {
+			return fmt.Errorf("%s should have at most %v characters", path, maxLength)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("the pattern of %s is invalid: %s", path, err.Error())
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s should match the pattern: %s", path, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetAuthorizationDetails returns the authorization details granted to the token
+func (token *Token) GetAuthorizationDetails() []AuthorizationDetail {
+	if token.AuthorizationDetails == "" {
+		return nil
+	}
+
+	res := []AuthorizationDetail{}
+	err := json.Unmarshal([]byte(token.AuthorizationDetails), &res)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+// applyAuthorizationDetails sets the authorization details of the token request on the token, the client
+// credentials grant is authorized for the details directly while the other grants can only narrow down the granted
+// ones. It returns whether the details of the token have changed
+func applyAuthorizationDetails(application *Application, token *Token, grantType string, authorizationDetails string) (bool, *TokenError) {
+	res := authorizationDetails
+	var err error
+	if grantType == "client_credentials" {
+		var details []AuthorizationDetail
+		details, err = ParseAuthorizationDetails(application, authorizationDetails)
+		res = getAuthorizationDetailsString(details)
+	} else {
+		res, err = narrowAuthorizationDetails(application, token.AuthorizationDetails, authorizationDetails)
+	}
+	if err != nil {
+		return false, &TokenError{
+			Error:            InvalidAuthorizationDetails,
+			ErrorDescription: err.Error(),
+		}
+	}
+
+	if res == token.AuthorizationDetails {
+		return false, nil
+	}
+	token.AuthorizationDetails = res
+	return true, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import "testing"
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	application := &Application{
+		Name: "app-built-in",
+		AuthorizationDetailsTypes: []*AuthorizationDetailsType{
+			{
+				Type:   "payment_initiation",
+				Schema: `{"type": "object", "required": ["actions"], "additionalProperties": false, "properties": {"actions": {"type": "array", "items": {"enum": ["initiate", "status"]}}, "amount": {"type": "number", "minimum": 0}}}`,
+			},
+		},
+	}
+
+	details, err := ParseAuthorizationDetails(application, `[{"type": "payment_initiation", "actions": ["initiate"], "amount": 123.5}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details) != 1 || details[0].getType() != "payment_initiation" {
+		t.Errorf("ParseAuthorizationDetails() = %v", details)
+	}
+
+	invalidDetails := []string{
+		`{"type": "payment_initiation"}`,
+		`[{"type": "account_information"}]`,
+		`[{"type": "payment_initiation"}]`,
+		`[{"type": "payment_initiation", "actions": ["cancel"]}]`,
+		`[{"type": "payment_initiation", "actions": [], "amount": -1}]`,
+		`[{"type": "payment_initiation", "actions": [], "creditor": "Merchant A"}]`,
+	}
+	for _, authorizationDetails := range invalidDetails {
+		_, err = ParseAuthorizationDetails(application, authorizationDetails)
+		if err == nil {
+			t.Errorf("ParseAuthorizationDetails(%s) should fail", authorizationDetails)
+		}
+	}
+
+	granted := `[{"type": "payment_initiation", "actions": ["initiate"]}, {"type": "payment_initiation", "actions": ["status"]}]`
+	res, err := narrowAuthorizationDetails(application, granted, `[{"type": "payment_initiation", "actions": ["status"]}]`)
+	if err != nil || res != `[{"actions":["status"],"type":"payment_initiation"}]` {
+		t.Errorf("narrowAuthorizationDetails() = %s, %v", res, err)
+	}
+	_, err = narrowAuthorizationDetails(application, granted, `[{"type": "payment_initiation", "actions": ["initiate", "status"]}]`)
+	if err == nil {
+		t.Errorf("narrowAuthorizationDetails() should fail for the details that have not been granted")
+	}
+}
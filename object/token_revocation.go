@@ -0,0 +1,221 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/casvisor/casvisor-go-sdk/casvisorsdk"
+	"github.com/xorm-io/core"
+)
+
+const UnsupportedTokenType = "unsupported_token_type"
+
+// getTokenForRevocation looks the token up by the hinted type first, and falls back to the other type
+// because the hint is only advisory, per RFC 7009 section 2.1
+func getTokenForRevocation(tokenValue string, tokenTypeHint string) (*Token, bool, error) {
+	if tokenTypeHint == "refresh_token" {
+		token, err := GetTokenByRefreshToken(tokenValue)
+		if err != nil || token != nil {
+			return token, true, err
+		}
+
+		token, err = GetTokenByAccessToken(tokenValue)
+		return token, false, err
+	}
+
+	token, err := GetTokenByAccessToken(tokenValue)
+	if err != nil || token != nil {
+		return token, false, err
+	}
+
+	token, err = GetTokenByRefreshToken(tokenValue)
+	return token, true, err
+}
+
+// getFamilyId returns the lineage id shared by all tokens refreshed from the same grant, which is the name of the first token
+func (token *Token) getFamilyId() string {
+	if token.FamilyId != "" {
+		return token.FamilyId
+	}
+	return token.Name
+}
+
+func (token *Token) getFamilyCreatedTime() string {
+	if token.FamilyCreatedTime != "" {
+		return token.FamilyCreatedTime
+	}
+	return token.CreatedTime
+}
+
+// isTokenFamilyExpired checks the absolute lifetime of the family, which isn't extended by refreshing
+func isTokenFamilyExpired(application *Application, token *Token) bool {
+	if application.RefreshTokenFamilyExpireInHours <= 0 {
+		return false
+	}
+
+	createdTime, err := time.Parse(time.RFC3339, token.getFamilyCreatedTime())
+	if err != nil {
+		return false
+	}
+	return time.Now().After(createdTime.Add(time.Duration(application.RefreshTokenFamilyExpireInHours) * time.Hour))
+}
+
+// markTokenRotated keeps a refreshed token as rotated instead of deleting it, so that its reuse can be detected. The
+// update is conditional, it returns false if the token has already been rotated by a concurrent request
+func markTokenRotated(token *Token) (bool, error) {
+	token.FamilyId = token.getFamilyId()
+	token.FamilyCreatedTime = token.getFamilyCreatedTime()
+	token.IsRotated = true
+	token.ExpiresIn = 0
+	affected, err := ormer.Engine.ID(core.PK{token.Owner, token.Name}).Where("is_rotated = ?", false).Cols("family_id", "family_created_time", "is_rotated", "expires_in").Update(token)
+	if err != nil {
+		return false, err
+	}
+	return affected != 0, nil
+}
+
+// getRefreshTokenReuseError revokes the family of a rotated refresh token that is presented again, as it's treated
+// as stolen
+func getRefreshTokenReuseError(token *Token) (*TokenError, error) {
+	tokens, err := revokeTokenFamily(token.getFamilyId())
+	if err != nil {
+		return nil, err
+	}
+	addRefreshTokenReuseRecord(token, tokens)
+
+	return &TokenError{
+		Error:            InvalidGrant,
+		ErrorDescription: "refresh token has already been used, all tokens issued from it have been revoked",
+	}, nil
+}
+
+func revokeTokenFamily(familyId string) ([]*Token, error) {
+	tokens := []*Token{}
+	err := ormer.Engine.Where("(family_id = ? or name = ?) and expires_in > 0", familyId, familyId).Find(&tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	err = expireTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// addRefreshTokenReuseRecord adds a "reuse-refresh-token" record for the revoked family, so that webhooks can react
+func addRefreshTokenReuseRecord(token *Token, tokens []*Token) {
+	record := &casvisorsdk.Record{
+		Name:         util.GenerateId(),
+		CreatedTime:  util.GetCurrentTime(),
+		Organization: token.Organization,
+		User:         token.User,
+		Method:       "POST",
+		RequestUri:   "/api/login/oauth/refresh_token",
+		Action:       "reuse-refresh-token",
+		Object:       util.StructToJson(GetMaskedTokens(append([]*Token{token}, tokens...))),
+		StatusCode:   400,
+	}
+
+	util.SafeGoroutine(func() {
+		AddRecord(record)
+	})
+}
+
+// expireTokens sets expires_in of the tokens to 0, so that they are rejected everywhere an expired token is
+func expireTokens(tokens []*Token) error {
+	for _, token := range tokens {
+		token.ExpiresIn = 0
+		_, err := ormer.Engine.ID(core.PK{token.Owner, token.Name}).Cols("expires_in").Update(token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeToken
+// Token Revocation, per RFC 7009 section 2. Revoking a refresh token also revokes every access token
+// issued with it, i.e. its whole token family. It returns the revoked tokens, an unknown token is not an error
+func RevokeToken(clientId string, clientSecret string, tokenValue string, tokenTypeHint string) ([]*Token, *TokenError, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// public clients authenticate with their client_id only
+	isPublicClient := application != nil && application.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone && clientSecret == ""
+	if application == nil || (application.ClientSecret != clientSecret && !isPublicClient) {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id or client_secret is invalid",
+		}, nil
+	}
+
+	if tokenValue == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "token should not be empty",
+		}, nil
+	}
+	if tokenTypeHint != "" && tokenTypeHint != "access_token" && tokenTypeHint != "refresh_token" {
+		return nil, &TokenError{
+			Error:            UnsupportedTokenType,
+			ErrorDescription: "token_type_hint should be access_token or refresh_token",
+		}, nil
+	}
+
+	token, isRefreshToken, err := getTokenForRevocation(tokenValue, tokenTypeHint)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil || token.ExpiresIn <= 0 {
+		return nil, nil, nil
+	}
+
+	if token.Owner != application.Owner || token.Application != application.Name {
+		return nil, &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: "the token was not issued to this client",
+		}, nil
+	}
+
+	if isRefreshToken {
+		tokens, err := revokeTokenFamily(token.getFamilyId())
+		return tokens, nil, err
+	}
+
+	tokens := []*Token{token}
+	err = expireTokens(tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tokens, nil, nil
+}
+
+// GetMaskedTokens hides the token values, for the tokens to be put into records and webhooks
+func GetMaskedTokens(tokens []*Token) []*Token {
+	res := []*Token{}
+	for _, token := range tokens {
+		maskedToken := *token
+		maskedToken.Code = "***"
+		maskedToken.AccessToken = "***"
+		maskedToken.RefreshToken = "***"
+		res = append(res, &maskedToken)
+	}
+	return res
+}
@@ -32,6 +32,9 @@ type ClaimsStandard struct {
 	Nonce               string      `json:"nonce,omitempty"`
 	Scope               string      `json:"scope,omitempty"`
 	Address             OIDCAddress `json:"address,omitempty"`
+	Act                 *ActClaim   `json:"act,omitempty"`
+	Cnf                 *CnfClaim   `json:"cnf,omitempty"`
+	Sid                 string      `json:"sid,omitempty"`
 
 	jwt.RegisteredClaims
 }
@@ -51,6 +54,9 @@ func getStandardClaims(claims Claims) ClaimsStandard {
 		TokenType:        claims.TokenType,
 		Nonce:            claims.Nonce,
 		Scope:            claims.Scope,
+		Act:              claims.Act,
+		Cnf:              claims.Cnf,
+		Sid:              claims.Sid,
 		RegisteredClaims: claims.RegisteredClaims,
 	}
 
@@ -93,8 +99,14 @@ func ParseStandardJwtToken(token string, cert *Cert) (*ClaimsStandard, error) {
 			return nil, fmt.Errorf("the certificate field should not be empty for the cert: %v", cert)
 		}
 
+		kid, _ := token.Header["kid"].(string)
+		publicCertificate, err := cert.getPublicCertificate(kid)
+		if err != nil {
+			return nil, err
+		}
+
 		// RSA certificate
-		certificate, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cert.Certificate))
+		certificate, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicCertificate))
 		if err != nil {
 			return nil, err
 		}
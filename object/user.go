@@ -222,6 +222,27 @@ type Userinfo struct {
 	Groups        []string `json:"groups,omitempty"`
 	Roles         []string `json:"roles,omitempty"`
 	Permissions   []string `json:"permissions,omitempty"`
+
+	CustomClaims map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON adds the claims of the custom scopes to the userinfo
+func (userinfo Userinfo) MarshalJSON() ([]byte, error) {
+	type userinfoAlias Userinfo
+	data, err := json.Marshal(userinfoAlias(userinfo))
+	if err != nil || len(userinfo.CustomClaims) == 0 {
+		return data, err
+	}
+
+	res := map[string]interface{}{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range userinfo.CustomClaims {
+		res[name] = value
+	}
+	return json.Marshal(res)
 }
 
 type ManagedAccount struct {
@@ -963,7 +984,7 @@ func DeleteUser(user *User) (bool, error) {
 	}
 }
 
-func GetUserInfo(user *User, scope string, aud string, host string) (*Userinfo, error) {
+func GetUserInfo(user *User, scope string, aud string, host string, claims string) (*Userinfo, error) {
 	_, originBackend := getOriginFromHost(host)
 
 	resp := Userinfo{
@@ -1008,6 +1029,33 @@ func GetUserInfo(user *User, scope string, aud string, host string) (*Userinfo,
 		resp.Phone = user.Phone
 	}
 
+	if aud != "" {
+		application, err := getApplicationByAudience([]string{aud})
+		if err != nil {
+			return nil, err
+		}
+		if application != nil {
+			resp.CustomClaims, err = getCustomScopeClaims(application, user, scope)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the claims requested for the userinfo by the "claims" parameter are returned if their scope has been granted
+	claimsRequest, err := ParseClaimsRequest(claims)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range getRequestedClaims(user, claimsRequest.Userinfo, scope) {
+		if resp.CustomClaims == nil {
+			resp.CustomClaims = map[string]interface{}{}
+		}
+		if _, ok := resp.CustomClaims[name]; !ok {
+			resp.CustomClaims[name] = value
+		}
+	}
+
 	return &resp, nil
 }
 
@@ -0,0 +1,401 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/casdoor/casdoor/util"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	WsFedActionSignIn         = "wsignin1.0"
+	WsFedActionSignOut        = "wsignout1.0"
+	WsFedActionSignOutCleanup = "wsignoutcleanup1.0"
+
+	WsFedTokenTypeSaml11 = "SAML 1.1"
+	WsFedTokenTypeSaml20 = "SAML 2.0"
+)
+
+const (
+	wsTrustNamespace      = "http://schemas.xmlsoap.org/ws/2005/02/trust"
+	wsPolicyNamespace     = "http://schemas.xmlsoap.org/ws/2004/09/policy"
+	wsAddressingNamespace = "http://www.w3.org/2005/08/addressing"
+	wsUtilityNamespace    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	wsFedNamespace        = "http://docs.oasis-open.org/wsfed/federation/200706"
+	wsFedAuthNamespace    = "http://docs.oasis-open.org/wsfed/authorization/200706"
+
+	wsFedClaimName           = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name"
+	wsFedClaimEmailAddress   = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"
+	wsFedClaimGivenName      = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname"
+	wsFedClaimSurname        = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname"
+	wsFedClaimNameIdentifier = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/nameidentifier"
+	wsFedClaimRole           = "http://schemas.microsoft.com/ws/2008/06/identity/claims/role"
+
+	wsFedSaml11TokenType = "urn:oasis:names:tc:SAML:1.0:assertion"
+	wsFedSaml20TokenType = "urn:oasis:names:tc:SAML:2.0:assertion"
+)
+
+type wsFedClaim struct {
+	Type   string
+	Values []string
+}
+
+func getWsFedPassiveLocation(application *Application, host string) string {
+	_, originBackend := getOriginFromHost(host)
+	return fmt.Sprintf("%s/api/wsfed/%s/%s", originBackend, application.Owner, application.Name)
+}
+
+// GetWsFedReply checks the realm of the relying party and returns the URL the token is posted to, which is the
+// wreply of the request if it's an allowed redirect URI, or the SAML reply URL of the application
+func GetWsFedReply(application *Application, realm string, reply string) (string, error) {
+	if !application.EnableWsFed {
+		return "", fmt.Errorf("WS-Federation is not enabled for the application: %s", application.Name)
+	}
+
+	if realm == "" || !isSamlIssuerValid(application, realm) {
+		return "", fmt.Errorf("the realm: %s doesn't exist in the allowed Redirect URI list", realm)
+	}
+
+	if reply != "" {
+		if !application.IsRedirectUriValid(reply) {
+			return "", fmt.Errorf("the reply URL: %s doesn't exist in the allowed Redirect URI list", reply)
+		}
+		return reply, nil
+	}
+
+	if application.SamlReplyUrl != "" {
+		return application.SamlReplyUrl, nil
+	}
+	if strings.HasPrefix(realm, "https://") || strings.HasPrefix(realm, "http://") {
+		return realm, nil
+	}
+	return "", errors.New("the reply URL of WS-Federation should not be empty")
+}
+
+// GetWsFedSignOutReply returns the URL the user is sent back to after the sign-out, which is the wreply of the
+// request if it's an allowed redirect URI, or the home page of Casdoor
+func GetWsFedSignOutReply(application *Application, reply string, host string) (string, error) {
+	if !application.EnableWsFed {
+		return "", fmt.Errorf("WS-Federation is not enabled for the application: %s", application.Name)
+	}
+
+	if reply == "" {
+		originFrontend, _ := getOriginFromHost(host)
+		return originFrontend, nil
+	}
+	if !application.IsRedirectUriValid(reply) {
+		return "", fmt.Errorf("the reply URL: %s doesn't exist in the allowed Redirect URI list", reply)
+	}
+	return reply, nil
+}
+
+// getWsFedSignOutCleanupUrl returns the URL that clears the session of the relying party, it's loaded in the
+// front-channel logout of the other applications
+func getWsFedSignOutCleanupUrl(application *Application) string {
+	if !application.EnableWsFed || application.SamlReplyUrl == "" {
+		return ""
+	}
+
+	separator := "?"
+	if strings.Contains(application.SamlReplyUrl, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%swa=%s", application.SamlReplyUrl, separator, WsFedActionSignOutCleanup)
+}
+
+// GetWsFedSignInAddress returns the login page of the WS-Federation sign-in, the parameters of the request are
+// passed on to it
+func GetWsFedSignInAddress(owner string, application string, realm string, reply string, context string, host string) string {
+	originF, _ := getOriginFromHost(host)
+	query := url.Values{}
+	query.Set("wa", WsFedActionSignIn)
+	query.Set("wtrealm", realm)
+	query.Set("wreply", reply)
+	query.Set("wctx", context)
+	return fmt.Sprintf("%s/login/saml/authorize/%s/%s?%s", originF, owner, application, query.Encode())
+}
+
+// getWsFedClaims returns the claims of the token: the name, email, given name, surname and roles of the user, and
+// the SAML attributes of the application whose names are the claim types
+func getWsFedClaims(application *Application, user *User) ([]*wsFedClaim, error) {
+	parameters, err := getClaimParameters(user)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := []*wsFedClaim{
+		{Type: wsFedClaimNameIdentifier, Values: []string{getSamlNameId(application, user)}},
+		{Type: wsFedClaimName, Values: []string{user.Name}},
+	}
+	if user.Email != "" {
+		claims = append(claims, &wsFedClaim{Type: wsFedClaimEmailAddress, Values: []string{user.Email}})
+	}
+	if user.FirstName != "" {
+		claims = append(claims, &wsFedClaim{Type: wsFedClaimGivenName, Values: []string{user.FirstName}})
+	}
+	if user.LastName != "" {
+		claims = append(claims, &wsFedClaim{Type: wsFedClaimSurname, Values: []string{user.LastName}})
+	}
+	if roles := getSamlAttributeValues(parameters, samlAttributeUserPrefix+"roles"); len(roles) != 0 {
+		claims = append(claims, &wsFedClaim{Type: wsFedClaimRole, Values: roles})
+	}
+
+	for _, item := range application.SamlAttributes {
+		values := getSamlAttributeValues(parameters, item.Value)
+		if item.Name != "" && len(values) != 0 {
+			claims = append(claims, &wsFedClaim{Type: item.Name, Values: values})
+		}
+	}
+	return claims, nil
+}
+
+// newWsFedAssertion11 returns the SAML 1.1 assertion of the token, the claim types are split into the namespace and
+// name of the attributes
+func newWsFedAssertion11(application *Application, user *User, issuer string, realm string, claims []*wsFedClaim, now time.Time, expireTime time.Time) *etree.Element {
+	assertion := &etree.Element{Space: "saml", Tag: "Assertion"}
+	assertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:1.0:assertion")
+	assertion.CreateAttr("MajorVersion", "1")
+	assertion.CreateAttr("MinorVersion", "1")
+	assertion.CreateAttr("AssertionID", fmt.Sprintf("_%s", uuid.New()))
+	assertion.CreateAttr("Issuer", issuer)
+	assertion.CreateAttr("IssueInstant", now.Format(time.RFC3339))
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Format(time.RFC3339))
+	conditions.CreateAttr("NotOnOrAfter", expireTime.Format(time.RFC3339))
+	conditions.CreateElement("saml:AudienceRestrictionCondition").CreateElement("saml:Audience").SetText(realm)
+
+	createSubject := func(parent *etree.Element) {
+		subject := parent.CreateElement("saml:Subject")
+		nameIdentifier := subject.CreateElement("saml:NameIdentifier")
+		nameIdentifier.CreateAttr("Format", getSamlNameIdFormat(application))
+		nameIdentifier.SetText(getSamlNameId(application, user))
+		subject.CreateElement("saml:SubjectConfirmation").CreateElement("saml:ConfirmationMethod").SetText("urn:oasis:names:tc:SAML:1.0:cm:bearer")
+	}
+
+	attributeStatement := assertion.CreateElement("saml:AttributeStatement")
+	createSubject(attributeStatement)
+	for _, claim := range claims {
+		namespace, name := claim.Type, claim.Type
+		if i := strings.LastIndex(claim.Type, "/"); i != -1 {
+			namespace, name = claim.Type[:i], claim.Type[i+1:]
+		}
+
+		attribute := attributeStatement.CreateElement("saml:Attribute")
+		attribute.CreateAttr("AttributeName", name)
+		attribute.CreateAttr("AttributeNamespace", namespace)
+		for _, value := range claim.Values {
+			attribute.CreateElement("saml:AttributeValue").SetText(value)
+		}
+	}
+
+	authenticationStatement := assertion.CreateElement("saml:AuthenticationStatement")
+	authenticationStatement.CreateAttr("AuthenticationMethod", "urn:oasis:names:tc:SAML:1.0:am:password")
+	authenticationStatement.CreateAttr("AuthenticationInstant", now.Format(time.RFC3339))
+	createSubject(authenticationStatement)
+	return assertion
+}
+
+// newWsFedAssertion20 returns the SAML 2.0 assertion of the token, the claim types are the names of the attributes
+func newWsFedAssertion20(application *Application, user *User, issuer string, realm string, reply string, sid string, claims []*wsFedClaim, now time.Time, expireTime time.Time) *etree.Element {
+	assertion := &etree.Element{Space: "saml", Tag: "Assertion"}
+	assertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	assertion.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	assertion.CreateAttr("Version", "2.0")
+	assertion.CreateAttr("IssueInstant", now.Format(time.RFC3339))
+	assertion.CreateElement("saml:Issuer").SetText(issuer)
+
+	subject := assertion.CreateElement("saml:Subject")
+	nameId := subject.CreateElement("saml:NameID")
+	nameId.CreateAttr("Format", getSamlNameIdFormat(application))
+	nameId.SetText(getSamlNameId(application, user))
+	subjectConfirmation := subject.CreateElement("saml:SubjectConfirmation")
+	subjectConfirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
+	subjectConfirmationData := subjectConfirmation.CreateElement("saml:SubjectConfirmationData")
+	subjectConfirmationData.CreateAttr("Recipient", reply)
+	subjectConfirmationData.CreateAttr("NotOnOrAfter", expireTime.Format(time.RFC3339))
+
+	conditions := assertion.CreateElement("saml:Conditions")
+	conditions.CreateAttr("NotBefore", now.Format(time.RFC3339))
+	conditions.CreateAttr("NotOnOrAfter", expireTime.Format(time.RFC3339))
+	conditions.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(realm)
+
+	attributeStatement := assertion.CreateElement("saml:AttributeStatement")
+	for _, claim := range claims {
+		attribute := attributeStatement.CreateElement("saml:Attribute")
+		attribute.CreateAttr("Name", claim.Type)
+		for _, value := range claim.Values {
+			attribute.CreateElement("saml:AttributeValue").SetText(value)
+		}
+	}
+
+	authnStatement := assertion.CreateElement("saml:AuthnStatement")
+	authnStatement.CreateAttr("AuthnInstant", now.Format(time.RFC3339))
+	authnStatement.CreateAttr("SessionIndex", getSamlSessionIndex(sid))
+	authnStatement.CreateElement("saml:AuthnContext").CreateElement("saml:AuthnContextClassRef").SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport")
+	return assertion
+}
+
+// signWsFedElement signs the element with the cert of the application, the signature is inserted at the index or
+// appended if it's negative. The exclusive canonicalization is used as the element is embedded in another document
+func signWsFedElement(cert *Cert, certificate string, element *etree.Element, idAttribute string, index int) error {
+	ctx := dsig.NewDefaultSigningContext(&X509Key{
+		PrivateKey:      cert.PrivateKey,
+		X509Certificate: certificate,
+	})
+	ctx.Hash = crypto.SHA256
+	ctx.IdAttribute = idAttribute
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	sig, err := ctx.ConstructSignature(element, true)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 {
+		element.AddChild(sig)
+	} else {
+		element.InsertChildAt(index, sig)
+	}
+	return nil
+}
+
+// GetWsFedResponse issues the signed SAML token of the user for the relying party and returns the
+// RequestSecurityTokenResponse to be posted as the wresult, and the URL it's posted to
+func GetWsFedResponse(application *Application, user *User, realm string, reply string, host string, sid string) (string, string, error) {
+	reply, err := GetWsFedReply(application, realm, reply)
+	if err != nil {
+		return "", "", err
+	}
+
+	cert, certificate, err := getSamlCert(application)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims, err := getWsFedClaims(application, user)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	now := time.Now().UTC()
+	expireTime := now.Add(time.Hour * 24)
+
+	var assertion *etree.Element
+	tokenType := wsFedSaml11TokenType
+	if application.WsFedTokenType == WsFedTokenTypeSaml20 {
+		tokenType = wsFedSaml20TokenType
+		assertion = newWsFedAssertion20(application, user, originBackend, realm, reply, sid, claims, now, expireTime)
+		err = signWsFedElement(cert, certificate, assertion, "ID", 1)
+	} else {
+		assertion = newWsFedAssertion11(application, user, originBackend, realm, claims, now, expireTime)
+		err = signWsFedElement(cert, certificate, assertion, "AssertionID", -1)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign the WS-Federation token: %s", err.Error())
+	}
+
+	rstr := &etree.Element{Space: "t", Tag: "RequestSecurityTokenResponse"}
+	rstr.CreateAttr("xmlns:t", wsTrustNamespace)
+	lifetime := rstr.CreateElement("t:Lifetime")
+	lifetime.CreateAttr("xmlns:wsu", wsUtilityNamespace)
+	lifetime.CreateElement("wsu:Created").SetText(now.Format(time.RFC3339))
+	lifetime.CreateElement("wsu:Expires").SetText(expireTime.Format(time.RFC3339))
+	appliesTo := rstr.CreateElement("wsp:AppliesTo")
+	appliesTo.CreateAttr("xmlns:wsp", wsPolicyNamespace)
+	endpointReference := appliesTo.CreateElement("wsa:EndpointReference")
+	endpointReference.CreateAttr("xmlns:wsa", wsAddressingNamespace)
+	endpointReference.CreateElement("wsa:Address").SetText(realm)
+	rstr.CreateElement("t:RequestedSecurityToken").AddChild(assertion)
+	rstr.CreateElement("t:TokenType").SetText(tokenType)
+	rstr.CreateElement("t:RequestType").SetText("http://schemas.xmlsoap.org/ws/2005/02/trust/Issue")
+	rstr.CreateElement("t:KeyType").SetText("http://schemas.xmlsoap.org/ws/2005/05/identity/NoProofKey")
+
+	doc := etree.NewDocument()
+	doc.SetRoot(rstr)
+	res, err := doc.WriteToString()
+	if err != nil {
+		return "", "", err
+	}
+	return res, reply, nil
+}
+
+// GetWsFedMetadata returns the signed federation metadata of the application, which describes the passive requestor
+// endpoint, the signing certificate and the offered claim types
+func GetWsFedMetadata(application *Application, host string) (string, error) {
+	cert, certificate, err := getSamlCert(application)
+	if err != nil {
+		return "", err
+	}
+
+	_, originBackend := getOriginFromHost(host)
+	entityDescriptor := &etree.Element{Tag: "EntityDescriptor"}
+	entityDescriptor.CreateAttr("xmlns", "urn:oasis:names:tc:SAML:2.0:metadata")
+	entityDescriptor.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	entityDescriptor.CreateAttr("entityID", originBackend)
+
+	roleDescriptor := entityDescriptor.CreateElement("RoleDescriptor")
+	roleDescriptor.CreateAttr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	roleDescriptor.CreateAttr("xmlns:fed", wsFedNamespace)
+	roleDescriptor.CreateAttr("xsi:type", "fed:SecurityTokenServiceType")
+	roleDescriptor.CreateAttr("protocolSupportEnumeration", wsFedNamespace)
+
+	keyDescriptor := roleDescriptor.CreateElement("KeyDescriptor")
+	keyDescriptor.CreateAttr("use", "signing")
+	keyInfo := keyDescriptor.CreateElement("KeyInfo")
+	keyInfo.CreateAttr("xmlns", "http://www.w3.org/2000/09/xmldsig#")
+	keyInfo.CreateElement("X509Data").CreateElement("X509Certificate").SetText(certificate)
+
+	claimTypes := []string{wsFedClaimNameIdentifier, wsFedClaimName, wsFedClaimEmailAddress, wsFedClaimGivenName, wsFedClaimSurname, wsFedClaimRole}
+	for _, item := range application.SamlAttributes {
+		if item.Name != "" && !util.InSlice(claimTypes, item.Name) {
+			claimTypes = append(claimTypes, item.Name)
+		}
+	}
+
+	claimTypesOffered := roleDescriptor.CreateElement("fed:ClaimTypesOffered")
+	for _, claimType := range claimTypes {
+		element := claimTypesOffered.CreateElement("auth:ClaimType")
+		element.CreateAttr("xmlns:auth", wsFedAuthNamespace)
+		element.CreateAttr("Uri", claimType)
+		element.CreateAttr("Optional", "true")
+	}
+
+	for _, tag := range []string{"fed:SecurityTokenServiceEndpoint", "fed:PassiveRequestorEndpoint"} {
+		endpointReference := roleDescriptor.CreateElement(tag).CreateElement("wsa:EndpointReference")
+		endpointReference.CreateAttr("xmlns:wsa", wsAddressingNamespace)
+		endpointReference.CreateElement("wsa:Address").SetText(getWsFedPassiveLocation(application, host))
+	}
+
+	err = signWsFedElement(cert, certificate, entityDescriptor, "ID", 0)
+	if err != nil {
+		return "", err
+	}
+
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	doc.SetRoot(entityDescriptor)
+	return doc.WriteToString()
+}
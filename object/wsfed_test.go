@@ -0,0 +1,94 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+func TestWsFedReply(t *testing.T) {
+	application := &Application{Owner: "admin", Name: "app-wsfed", EnableWsFed: true, RedirectUris: []string{"https://rp.example.com"}}
+
+	if _, err := GetWsFedReply(application, "urn:other", ""); err == nil {
+		t.Error("an unknown realm should be rejected")
+	}
+	if _, err := GetWsFedReply(application, "https://rp.example.com", "https://evil.example.com"); err == nil {
+		t.Error("a reply URL out of the redirect URIs should be rejected")
+	}
+
+	reply, err := GetWsFedReply(application, "https://rp.example.com", "")
+	if err != nil || reply != "https://rp.example.com" {
+		t.Errorf("the realm should be the reply URL: %s, %v", reply, err)
+	}
+
+	application.SamlReplyUrl = "https://rp.example.com/signin"
+	if url := getWsFedSignOutCleanupUrl(application); url != "https://rp.example.com/signin?wa=wsignoutcleanup1.0" {
+		t.Errorf("unexpected sign-out cleanup URL: %s", url)
+	}
+}
+
+func TestWsFedAssertion11(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "door.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &Cert{PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}))}
+
+	application := &Application{Owner: "admin", Name: "app-wsfed"}
+	user := &User{Owner: "built-in", Name: "alice"}
+	claims := []*wsFedClaim{{Type: wsFedClaimRole, Values: []string{"admin", "editor"}}}
+	now := time.Now().UTC()
+	assertion := newWsFedAssertion11(application, user, "https://door.example.com", "urn:rp", claims, now, now.Add(time.Hour))
+	err = signWsFedElement(cert, base64.StdEncoding.EncodeToString(der), assertion, "AssertionID", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role := assertion.FindElement("./saml:AttributeStatement/saml:Attribute[@AttributeName='role']")
+	if role == nil || role.SelectAttrValue("AttributeNamespace", "") != "http://schemas.microsoft.com/ws/2008/06/identity/claims" || len(role.ChildElements()) != 2 {
+		t.Errorf("the role claim should be split into the namespace and name")
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+	ctx.IdAttribute = "AssertionID"
+	_, err = ctx.Validate(assertion)
+	if err != nil {
+		t.Errorf("failed to validate the signature of the assertion: %s", err.Error())
+	}
+}
@@ -173,6 +173,22 @@ func getUrlPath(urlPath string) string {
 		return "/api/saml/redirect"
 	}
 
+	if strings.HasPrefix(urlPath, "/api/saml/slo") {
+		return "/api/saml/slo"
+	}
+
+	if strings.HasPrefix(urlPath, "/api/saml/idp-initiated") {
+		return "/api/saml/idp-initiated"
+	}
+
+	if strings.HasPrefix(urlPath, "/api/saml/artifact") {
+		return "/api/saml/artifact"
+	}
+
+	if strings.HasPrefix(urlPath, "/api/wsfed") {
+		return "/api/wsfed"
+	}
+
 	return urlPath
 }
 
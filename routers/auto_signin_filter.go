@@ -28,6 +28,10 @@ func AutoSigninFilter(ctx *context.Context) {
 	if strings.HasPrefix(urlPath, "/api/login/oauth/access_token") {
 		return
 	}
+	// the bearer tokens of client registration are initial and registration access tokens
+	if strings.HasPrefix(urlPath, "/api/login/oauth/register") {
+		return
+	}
 	//if getSessionUser(ctx) != "" {
 	//	return
 	//}
@@ -60,6 +64,13 @@ func AutoSigninFilter(ctx *context.Context) {
 			return
 		}
 
+		if !checkDpopBinding(ctx, token, accessToken) {
+			return
+		}
+		if !checkCertBinding(ctx, token) {
+			return
+		}
+
 		userId := util.GetId(token.Organization, token.User)
 		application, err := object.GetApplicationByUserId(fmt.Sprintf("app/%s", token.Application))
 		if err != nil {
@@ -68,7 +79,7 @@ func AutoSigninFilter(ctx *context.Context) {
 		}
 
 		setSessionUser(ctx, userId)
-		setSessionOidc(ctx, token.Scope, application.ClientId)
+		setSessionOidc(ctx, token.Scope, application.ClientId, token.Claims)
 		return
 	}
 
@@ -118,6 +118,13 @@ func getSessionUser(ctx *context.Context) string {
 	return user.(string)
 }
 
+// getSessionAuthContext returns the authentication of the user signed in to the session
+func getSessionAuthContext(ctx *context.Context) *object.AuthContext {
+	amr, _ := ctx.Input.CruSession.Get("amr").(string)
+	authTime, _ := ctx.Input.CruSession.Get("authTime").(int64)
+	return &object.AuthContext{AuthTime: authTime, Amr: strings.Fields(amr)}
+}
+
 func setSessionUser(ctx *context.Context, user string) {
 	err := ctx.Input.CruSession.Set("username", user)
 	if err != nil {
@@ -137,7 +144,7 @@ func setSessionExpire(ctx *context.Context, ExpireTime int64) {
 	ctx.Input.CruSession.SessionRelease(ctx.ResponseWriter)
 }
 
-func setSessionOidc(ctx *context.Context, scope string, aud string) {
+func setSessionOidc(ctx *context.Context, scope string, aud string, claims string) {
 	err := ctx.Input.CruSession.Set("scope", scope)
 	if err != nil {
 		panic(err)
@@ -146,6 +153,10 @@ func setSessionOidc(ctx *context.Context, scope string, aud string) {
 	if err != nil {
 		panic(err)
 	}
+	err = ctx.Input.CruSession.Set("claims", claims)
+	if err != nil {
+		panic(err)
+	}
 	ctx.Input.CruSession.SessionRelease(ctx.ResponseWriter)
 }
 
@@ -157,13 +168,73 @@ func parseBearerToken(ctx *context.Context) string {
 	}
 
 	prefix := tokens[0]
-	if prefix != "Bearer" {
+	if prefix != "Bearer" && prefix != "DPoP" {
 		return ""
 	}
 
 	return tokens[1]
 }
 
+func responseDpopError(ctx *context.Context, errorCode string, description string) {
+	ctx.Output.Header("WWW-Authenticate", fmt.Sprintf("DPoP error=\"%s\", error_description=\"%s\"", errorCode, description))
+	if nonce, err := object.GetDpopNonce(); err == nil {
+		ctx.Output.Header("DPoP-Nonce", nonce)
+	}
+	ctx.Output.SetStatus(401)
+	responseError(ctx, description)
+}
+
+// checkDpopBinding requires a valid DPoP proof for an access token bound to a key, per RFC 9449 section 7
+func checkDpopBinding(ctx *context.Context, token *object.Token, accessToken string) bool {
+	isDpopScheme := strings.HasPrefix(ctx.Request.Header.Get("Authorization"), "DPoP ")
+	if token.DpopJkt == "" {
+		if isDpopScheme {
+			responseDpopError(ctx, "invalid_token", "the access token is not bound to a DPoP key")
+			return false
+		}
+		return true
+	}
+
+	if !isDpopScheme {
+		responseDpopError(ctx, "invalid_token", "the access token is bound to a DPoP key and should be sent with the DPoP scheme")
+		return false
+	}
+
+	jkt, tokenError, err := object.ValidateDpopProof(ctx.Request.Header.Get("DPoP"), ctx.Request.Method, ctx.Request.URL.Path, ctx.Request.Host, accessToken)
+	if err != nil {
+		responseError(ctx, err.Error())
+		return false
+	}
+	if tokenError != nil {
+		responseDpopError(ctx, tokenError.Error, tokenError.ErrorDescription)
+		return false
+	}
+
+	if jkt != token.DpopJkt {
+		responseDpopError(ctx, "invalid_token", "the DPoP proof doesn't match the key the access token is bound to")
+		return false
+	}
+
+	return true
+}
+
+// checkCertBinding requires the TLS client certificate that a certificate-bound access token is bound to, per RFC 8705 section 3
+func checkCertBinding(ctx *context.Context, token *object.Token) bool {
+	if token.CertThumbprint == "" {
+		return true
+	}
+
+	if object.GetCertThumbprint(object.GetClientCertificate(ctx.Request)) != token.CertThumbprint {
+		description := "the access token is bound to another client certificate"
+		ctx.Output.Header("WWW-Authenticate", fmt.Sprintf("Bearer error=\"invalid_token\", error_description=\"%s\"", description))
+		ctx.Output.SetStatus(401)
+		responseError(ctx, description)
+		return false
+	}
+
+	return true
+}
+
 func getHostname(s string) string {
 	if s == "" {
 		return ""
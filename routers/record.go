@@ -101,6 +101,14 @@ func AfterRecordMessage(ctx *context.Context) {
 		record2.Object = util.StructToJson(user)
 	}
 
+	recordRevokeToken := ctx.Input.Params()["recordRevokeToken"]
+	if recordRevokeToken != "" {
+		record2 = object.CopyRecord(record)
+		record2.Action = "revoke-token"
+		record2.Organization, record2.User = util.GetOwnerAndNameFromId(ctx.Input.Params()["recordRevokeTokenUserId"])
+		record2.Object = recordRevokeToken
+	}
+
 	util.SafeGoroutine(func() {
 		object.AddRecord(record)
 
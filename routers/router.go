@@ -61,6 +61,12 @@ func initAPI() {
 	beego.Router("/api/acs", &controllers.ApiController{}, "POST:HandleSamlLogin")
 	beego.Router("/api/saml/metadata", &controllers.ApiController{}, "GET:GetSamlMeta")
 	beego.Router("/api/saml/redirect/:owner/:application", &controllers.ApiController{}, "*:HandleSamlRedirect")
+	beego.Router("/api/saml/slo/:owner/:application", &controllers.ApiController{}, "GET,POST:HandleSamlLogout")
+	beego.Router("/api/saml/idp-initiated/:owner/:application", &controllers.ApiController{}, "GET:HandleSamlIdpInitiatedLogin")
+	beego.Router("/api/saml/artifact/:owner/:application", &controllers.ApiController{}, "POST:HandleSamlArtifactResolve")
+	beego.Router("/api/import-saml-metadata", &controllers.ApiController{}, "POST:ImportSamlMetadata")
+	beego.Router("/api/wsfed/metadata/:owner/:application", &controllers.ApiController{}, "GET:GetWsFedMetadata")
+	beego.Router("/api/wsfed/:owner/:application", &controllers.ApiController{}, "GET,POST:HandleWsFed")
 	beego.Router("/api/webhook", &controllers.ApiController{}, "*:HandleOfficialAccountEvent")
 	beego.Router("/api/get-qrcode", &controllers.ApiController{}, "GET:GetQRCode")
 	beego.Router("/api/get-webhook-event", &controllers.ApiController{}, "GET:GetWebhookEventType")
@@ -129,6 +135,8 @@ func initAPI() {
 	beego.Router("/api/update-cert", &controllers.ApiController{}, "POST:UpdateCert")
 	beego.Router("/api/add-cert", &controllers.ApiController{}, "POST:AddCert")
 	beego.Router("/api/delete-cert", &controllers.ApiController{}, "POST:DeleteCert")
+	beego.Router("/api/rotate-cert", &controllers.ApiController{}, "POST:RotateCert")
+	beego.Router("/api/get-cert-rotations", &controllers.ApiController{}, "GET:GetCertRotations")
 
 	beego.Router("/api/get-roles", &controllers.ApiController{}, "GET:GetRoles")
 	beego.Router("/api/get-role", &controllers.ApiController{}, "GET:GetRole")
@@ -268,6 +276,21 @@ func initAPI() {
 	beego.Router("/api/login/oauth/access_token", &controllers.ApiController{}, "POST:GetOAuthToken")
 	beego.Router("/api/login/oauth/refresh_token", &controllers.ApiController{}, "POST:RefreshToken")
 	beego.Router("/api/login/oauth/introspect", &controllers.ApiController{}, "POST:IntrospectToken")
+	beego.Router("/api/login/oauth/revoke", &controllers.ApiController{}, "POST:RevokeToken")
+	beego.Router("/api/login/oauth/device_authorization", &controllers.ApiController{}, "POST:DeviceAuthorization")
+	beego.Router("/api/login/oauth/bc-authorize", &controllers.ApiController{}, "POST:BackchannelAuthentication")
+	beego.Router("/api/login/oauth/par", &controllers.ApiController{}, "POST:PushAuthorizationRequest")
+	beego.Router("/api/login/oauth/check-session", &controllers.ApiController{}, "GET:CheckSessionIframe")
+	beego.Router("/api/login/oauth/register", &controllers.ApiController{}, "POST:RegisterClient")
+	beego.Router("/api/login/oauth/register/:clientId", &controllers.ApiController{}, "GET:GetRegisteredClient;PUT:UpdateRegisteredClient;DELETE:DeleteRegisteredClient")
+	beego.Router("/api/get-device-auth", &controllers.ApiController{}, "GET:GetDeviceAuth")
+	beego.Router("/api/approve-device-auth", &controllers.ApiController{}, "POST:ApproveDeviceAuth")
+	beego.Router("/api/get-ciba-auths", &controllers.ApiController{}, "GET:GetCibaAuths")
+	beego.Router("/api/approve-ciba-auth", &controllers.ApiController{}, "POST:ApproveCibaAuth")
+
+	beego.Router("/api/get-user-consents", &controllers.ApiController{}, "GET:GetUserConsents")
+	beego.Router("/api/grant-consent", &controllers.ApiController{}, "POST:GrantConsent")
+	beego.Router("/api/revoke-consent", &controllers.ApiController{}, "POST:RevokeConsent")
 
 	beego.Router("/api/get-records", &controllers.ApiController{}, "GET:GetRecords")
 	beego.Router("/api/get-records-filter", &controllers.ApiController{}, "POST:GetRecordsByFilter")
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -63,6 +64,18 @@ func fastAutoSignin(ctx *context.Context) (string, error) {
 	if clientId == "" || responseType != "code" || redirectUri == "" {
 		return "", nil
 	}
+	// pushed or signed requests are resolved by the login page, the plain query can't be trusted for them
+	if ctx.Input.Query("request_uri") != "" || ctx.Input.Query("request") != "" {
+		return "", nil
+	}
+	// the login page checks max_age and the requested acr values, the user may have to sign in again or pass MFA
+	if ctx.Input.Query("max_age") != "" || ctx.Input.Query("acr_values") != "" || ctx.Input.Query("claims") != "" {
+		return "", nil
+	}
+	// the authorization details are shown to the user on the login page
+	if ctx.Input.Query("authorization_details") != "" {
+		return "", nil
+	}
 
 	application, err := object.GetApplicationByClientId(clientId)
 	if err != nil {
@@ -76,18 +89,58 @@ func fastAutoSignin(ctx *context.Context) (string, error) {
 		return "", nil
 	}
 
-	code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, ctx.Request.Host, getAcceptLanguage(ctx))
+	// the login page shows the consent screen when the user hasn't consented yet
+	if application.IsThirdParty {
+		user, err := object.GetUser(userId)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			return "", nil
+		}
+
+		required, err := object.IsConsentRequired(application, user, scope, ctx.Input.Query("prompt"), "")
+		if err != nil {
+			return "", err
+		}
+		if required {
+			return "", nil
+		}
+	}
+
+	sessionId := ctx.Input.CruSession.SessionID()
+	code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, ctx.Request.Host, getAcceptLanguage(ctx), "", object.GetOidcSid(sessionId), getSessionAuthContext(ctx), "")
 	if err != nil {
 		return "", err
 	} else if code.Message != "" {
 		return "", fmt.Errorf(code.Message)
 	}
 
+	// the application is notified when the user logs out of this session
+	owner, name := util.GetOwnerAndNameFromId(userId)
+	_, err = object.AddSession(&object.Session{
+		Owner:       owner,
+		Name:        name,
+		Application: application.Name,
+		SessionId:   []string{sessionId},
+	})
+	if err != nil {
+		return "", err
+	}
+
 	sep := "?"
 	if strings.Contains(redirectUri, "?") {
 		sep = "&"
 	}
 	res := fmt.Sprintf("%s%scode=%s&state=%s", redirectUri, sep, code.Code, state)
+
+	// the session_state of OIDC session management, the browser state is created for sessions that predate it
+	opbs := ctx.GetCookie(object.OpbsCookieName)
+	if opbs == "" {
+		opbs = util.GenerateId()
+		ctx.SetCookie(object.OpbsCookieName, opbs, 0, "/")
+	}
+	res = fmt.Sprintf("%s&session_state=%s", res, url.QueryEscape(object.GetSessionState(clientId, redirectUri, opbs)))
 	return res, nil
 }
 
@@ -30,6 +30,8 @@ import ProviderTable from "./table/ProviderTable";
 import SigninMethodTable from "./table/SigninMethodTable";
 import SignupTable from "./table/SignupTable";
 import SamlAttributeTable from "./table/SamlAttributeTable";
+import CustomScopeTable from "./table/CustomScopeTable";
+import AuthorizationDetailsTypeTable from "./table/AuthorizationDetailsTypeTable";
 import PromptPage from "./auth/PromptPage";
 import copy from "copy-to-clipboard";
 import ThemeEditor from "./common/theme/ThemeEditor";
@@ -42,6 +44,7 @@ require("codemirror/theme/material-darker.css");
 require("codemirror/mode/htmlmixed/htmlmixed");
 require("codemirror/mode/xml/xml");
 require("codemirror/mode/css/css");
+require("codemirror/mode/javascript/javascript");
 
 const {Option} = Select;
 
@@ -109,6 +112,7 @@ class ApplicationEditPage extends React.Component {
       mode: props.location.mode !== undefined ? props.location.mode : "edit",
       samlAttributes: [],
       samlMetadata: null,
+      samlSpMetadata: "",
       isAuthorized: true,
     };
   }
@@ -206,6 +210,25 @@ class ApplicationEditPage extends React.Component {
       });
   }
 
+  importSamlMetadata() {
+    ApplicationBackend.importSamlMetadata(this.state.application.owner, this.state.applicationName, this.state.application.samlSpMetadataUrl ?? "", this.state.samlSpMetadata)
+      .then((res) => {
+        if (res.status === "ok") {
+          const application = this.state.application;
+          ["samlSpMetadataUrl", "samlSpEntityId", "samlSpCertificate", "samlSpEncryptionCertificate", "samlNameIdFormats", "enableSamlAssertionEncryption",
+            "samlAuthnRequestsSigned", "useEmailAsSamlNameId", "samlReplyUrl", "samlLogoutUrl", "redirectUris"].forEach((key) => {
+            application[key] = res.data[key];
+          });
+          this.setState({
+            application: application,
+          });
+          Setting.showMessage("success", i18next.t("application:SAML metadata imported, please save the application"));
+        } else {
+          Setting.showMessage("error", `${i18next.t("application:Failed to import SAML metadata")}: ${res.msg}`);
+        }
+      });
+  }
+
   parseApplicationField(key, value) {
     if (["offset"].includes(key)) {
       value = Setting.myParseInt(value);
@@ -403,10 +426,18 @@ class ApplicationEditPage extends React.Component {
           </Col>
           <Col span={22} >
             <Select virtual={false} style={{width: "100%"}} value={this.state.application.tokenFormat} onChange={(value => {this.updateApplicationField("tokenFormat", value);})}
-              options={["JWT", "JWT-Empty", "JWT-Custom", "JWT-Standard"].map((item) => Setting.getOption(item, item))}
+              options={["JWT", "JWT-Empty", "JWT-Custom", "JWT-Standard", "Opaque"].map((item) => Setting.getOption(item, item))}
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:JWT audiences"), i18next.t("application:JWT audiences - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} disabled={this.state.application.tokenFormat !== "Opaque"} mode="tags" style={{width: "100%"}} value={this.state.application.jwtAudiences} onChange={(value => {this.updateApplicationField("jwtAudiences", value);})} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Token signing method"), i18next.t("application:Token signing method - Tooltip"))} :
@@ -429,6 +460,30 @@ class ApplicationEditPage extends React.Component {
             </Select>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Custom scopes"), i18next.t("application:Custom scopes - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <CustomScopeTable
+              title={i18next.t("application:Custom scopes")}
+              table={this.state.application.customScopes}
+              onUpdateTable={(value) => {this.updateApplicationField("customScopes", value);}}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Authorization details types"), i18next.t("application:Authorization details types - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <AuthorizationDetailsTypeTable
+              title={i18next.t("application:Authorization details types")}
+              table={this.state.application.authorizationDetailsTypes}
+              onUpdateTable={(value) => {this.updateApplicationField("authorizationDetailsTypes", value);}}
+            />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Token expire"), i18next.t("application:Token expire - Tooltip"))} :
@@ -449,6 +504,26 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Rotate refresh token"), i18next.t("application:Rotate refresh token - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.rotateRefreshToken} onChange={checked => {
+              this.updateApplicationField("rotateRefreshToken", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Refresh token family expire"), i18next.t("application:Refresh token family expire - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <InputNumber style={{width: "150px"}} value={this.state.application.refreshTokenFamilyExpireInHours} min={0} step={1} precision={0} addonAfter="Hours" onChange={value => {
+              this.updateApplicationField("refreshTokenFamilyExpireInHours", value);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Failed signin limit"), i18next.t("application:Failed signin limit - Tooltip"))} :
@@ -668,11 +743,239 @@ class ApplicationEditPage extends React.Component {
                   {id: "token", name: "Token"},
                   {id: "id_token", name: "ID Token"},
                   {id: "refresh_token", name: "Refresh Token"},
+                  {id: "urn:ietf:params:oauth:grant-type:device_code", name: "Device Code"},
+                  {id: "urn:ietf:params:oauth:grant-type:token-exchange", name: "Token Exchange"},
+                  {id: "urn:ietf:params:oauth:grant-type:jwt-bearer", name: "JWT Bearer"},
+                  {id: "urn:openid:params:grant-type:ciba", name: "CIBA"},
                 ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
               }
             </Select>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Token exchange audiences"), i18next.t("application:Token exchange audiences - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} disabled={!this.state.application.grantTypes?.includes("urn:ietf:params:oauth:grant-type:token-exchange")} mode="tags" style={{width: "100%"}} value={this.state.application.tokenExchangeAudiences} onChange={(value => {this.updateApplicationField("tokenExchangeAudiences", value);})} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Token exchange clients"), i18next.t("application:Token exchange clients - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="tags" style={{width: "100%"}} value={this.state.application.tokenExchangeClients} onChange={(value => {this.updateApplicationField("tokenExchangeClients", value);})} />
+          </Col>
+        </Row>
+        {
+          !this.state.application.grantTypes?.includes("urn:openid:params:grant-type:ciba") ? null : (
+            <React.Fragment>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("application:CIBA delivery mode"), i18next.t("application:CIBA delivery mode - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Select virtual={false} style={{width: "100%"}} value={this.state.application.backchannelTokenDeliveryMode === "" ? "poll" : this.state.application.backchannelTokenDeliveryMode} onChange={(value => {this.updateApplicationField("backchannelTokenDeliveryMode", value);})}
+                    options={["poll", "ping"].map((item) => Setting.getOption(item, item))}
+                  />
+                </Col>
+              </Row>
+              {
+                this.state.application.backchannelTokenDeliveryMode !== "ping" ? null : (
+                  <Row style={{marginTop: "20px"}} >
+                    <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                      {Setting.getLabel(i18next.t("application:Client notification endpoint"), i18next.t("application:Client notification endpoint - Tooltip"))} :
+                    </Col>
+                    <Col span={22} >
+                      <Input prefix={<LinkOutlined />} value={this.state.application.clientNotificationEndpoint} onChange={e => {
+                        this.updateApplicationField("clientNotificationEndpoint", e.target.value);
+                      }} />
+                    </Col>
+                  </Row>
+                )
+              }
+            </React.Fragment>
+          )
+        }
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Token endpoint auth method"), i18next.t("application:Token endpoint auth method - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.tokenEndpointAuthMethod} onChange={(value => {this.updateApplicationField("tokenEndpointAuthMethod", value);})}
+              options={["client_secret_basic", "client_secret_post", "none", "private_key_jwt", "tls_client_auth", "self_signed_tls_client_auth"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        {
+          this.state.application.tokenEndpointAuthMethod !== "tls_client_auth" ? null : (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("application:TLS client auth subject DN"), i18next.t("application:TLS client auth subject DN - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Input value={this.state.application.tlsClientAuthSubjectDn} placeholder={"CN=client,O=Example"} onChange={e => {
+                  this.updateApplicationField("tlsClientAuthSubjectDn", e.target.value);
+                }} />
+              </Col>
+            </Row>
+          )
+        }
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Is third-party"), i18next.t("application:Is third-party - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.isThirdParty} onChange={checked => {
+              this.updateApplicationField("isThirdParty", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Require PAR"), i18next.t("application:Require PAR - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.requirePar} onChange={checked => {
+              this.updateApplicationField("requirePar", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Enable DPoP"), i18next.t("application:Enable DPoP - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.enableDpop} onChange={checked => {
+              this.updateApplicationField("enableDpop", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Client JWKS"), i18next.t("application:Client JWKS - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <div style={{height: "300px"}}>
+              <CodeMirror
+                value={this.state.application.jwks}
+                options={{mode: "javascript", theme: "material-darker"}}
+                onBeforeChange={(editor, data, value) => {
+                  this.updateApplicationField("jwks", value);
+                }}
+              />
+            </div>
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Client JWKS URI"), i18next.t("application:Client JWKS URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.jwksUri} onChange={e => {
+              this.updateApplicationField("jwksUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:ID token encryption alg"), i18next.t("application:ID token encryption alg - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear style={{width: "100%"}} value={this.state.application.idTokenEncryptedResponseAlg === "" ? undefined : this.state.application.idTokenEncryptedResponseAlg} onChange={(value => {this.updateApplicationField("idTokenEncryptedResponseAlg", value ?? "");})}
+              options={["RSA-OAEP", "RSA-OAEP-256", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:ID token encryption enc"), i18next.t("application:ID token encryption enc - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear disabled={this.state.application.idTokenEncryptedResponseAlg === ""} style={{width: "100%"}} value={this.state.application.idTokenEncryptedResponseEnc === "" ? undefined : this.state.application.idTokenEncryptedResponseEnc} onChange={(value => {this.updateApplicationField("idTokenEncryptedResponseEnc", value ?? "");})}
+              options={["A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Userinfo signing alg"), i18next.t("application:Userinfo signing alg - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear style={{width: "100%"}} value={this.state.application.userinfoSignedResponseAlg === "" ? undefined : this.state.application.userinfoSignedResponseAlg} onChange={(value => {this.updateApplicationField("userinfoSignedResponseAlg", value ?? "");})}
+              options={["RS256", "RS512", "ES256", "ES384", "ES512"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Userinfo encryption alg"), i18next.t("application:Userinfo encryption alg - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear style={{width: "100%"}} value={this.state.application.userinfoEncryptedResponseAlg === "" ? undefined : this.state.application.userinfoEncryptedResponseAlg} onChange={(value => {this.updateApplicationField("userinfoEncryptedResponseAlg", value ?? "");})}
+              options={["RSA-OAEP", "RSA-OAEP-256", "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Userinfo encryption enc"), i18next.t("application:Userinfo encryption enc - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} allowClear disabled={this.state.application.userinfoEncryptedResponseAlg === ""} style={{width: "100%"}} value={this.state.application.userinfoEncryptedResponseEnc === "" ? undefined : this.state.application.userinfoEncryptedResponseEnc} onChange={(value => {this.updateApplicationField("userinfoEncryptedResponseEnc", value ?? "");})}
+              options={["A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512", "A128GCM", "A192GCM", "A256GCM"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Back-channel logout URI"), i18next.t("application:Back-channel logout URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.backchannelLogoutUri} onChange={e => {
+              this.updateApplicationField("backchannelLogoutUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Front-channel logout URI"), i18next.t("application:Front-channel logout URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.frontchannelLogoutUri} onChange={e => {
+              this.updateApplicationField("frontchannelLogoutUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Post logout redirect URLs"), i18next.t("application:Post logout redirect URLs - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <UrlTable
+              title={i18next.t("application:Post logout redirect URLs")}
+              table={this.state.application.postLogoutRedirectUris ?? []}
+              onUpdateTable={(value) => {this.updateApplicationField("postLogoutRedirectUris", value);}}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SP metadata"), i18next.t("application:SAML SP metadata - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} placeholder={i18next.t("application:SAML SP metadata URL")} value={this.state.application.samlSpMetadataUrl} onChange={e => {
+              this.updateApplicationField("samlSpMetadataUrl", e.target.value);
+            }} />
+            <Input.TextArea style={{marginTop: "10px"}} autoSize={{minRows: 2, maxRows: 10}} placeholder={i18next.t("application:SAML SP metadata XML")} value={this.state.samlSpMetadata} onChange={e => {
+              this.setState({samlSpMetadata: e.target.value});
+            }} />
+            <Button style={{marginTop: "10px"}} type="primary" disabled={(this.state.application.samlSpMetadataUrl ?? "") === "" && this.state.samlSpMetadata === ""} onClick={() => this.importSamlMetadata()}>
+              {i18next.t("application:Import SAML metadata")}
+            </Button>
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:SAML reply URL"), i18next.t("application:Redirect URL (Assertion Consumer Service POST Binding URL) - Tooltip"))} :
@@ -683,6 +986,74 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML logout URL"), i18next.t("application:SAML logout URL - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.samlLogoutUrl} onChange={e => {
+              this.updateApplicationField("samlLogoutUrl", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SP certificate"), i18next.t("application:SAML SP certificate - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input.TextArea autoSize={{minRows: 1, maxRows: 10}} value={this.state.application.samlSpCertificate} onChange={e => {
+              this.updateApplicationField("samlSpCertificate", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SP entity ID"), i18next.t("application:SAML SP entity ID - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input value={this.state.application.samlSpEntityId} onChange={e => {
+              this.updateApplicationField("samlSpEntityId", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SP encryption certificate"), i18next.t("application:SAML SP encryption certificate - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input.TextArea autoSize={{minRows: 1, maxRows: 10}} value={this.state.application.samlSpEncryptionCertificate} onChange={e => {
+              this.updateApplicationField("samlSpEncryptionCertificate", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML NameID formats"), i18next.t("application:SAML NameID formats - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="tags" style={{width: "100%"}} value={this.state.application.samlNameIdFormats} onChange={(value => {this.updateApplicationField("samlNameIdFormats", value);})} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Encrypt SAML assertion"), i18next.t("application:Encrypt SAML assertion - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch disabled={!this.state.application.samlSpEncryptionCertificate} checked={this.state.application.enableSamlAssertionEncryption} onChange={checked => {
+              this.updateApplicationField("enableSamlAssertionEncryption", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML AuthnRequests signed"), i18next.t("application:SAML AuthnRequests signed - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.samlAuthnRequestsSigned} onChange={checked => {
+              this.updateApplicationField("samlAuthnRequestsSigned", checked);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
             {Setting.getLabel(i18next.t("application:Enable SAML compression"), i18next.t("application:Enable SAML compression - Tooltip"))} :
@@ -724,6 +1095,33 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Enable SAML artifact binding"), i18next.t("application:Enable SAML artifact binding - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.enableSamlArtifactBinding} onChange={checked => {
+              this.updateApplicationField("enableSamlArtifactBinding", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:IdP-initiated RelayState"), i18next.t("application:IdP-initiated RelayState - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input value={this.state.application.samlIdpInitiatedRelayState} onChange={e => {
+              this.updateApplicationField("samlIdpInitiatedRelayState", e.target.value);
+            }} />
+            <Button style={{marginTop: "10px"}} type="primary" shape="round" icon={<CopyOutlined />} disabled={!this.state.application.samlReplyUrl} onClick={() => {
+              copy(`${window.location.origin}/api/saml/idp-initiated/${this.state.application.owner}/${encodeURIComponent(this.state.applicationName)}`);
+              Setting.showMessage("success", i18next.t("general:Copied to clipboard successfully"));
+            }}
+            >
+              {i18next.t("application:Copy IdP-initiated SSO URL")}
+            </Button>
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("general:SAML attributes"), i18next.t("general:SAML attributes - Tooltip"))} :
@@ -757,6 +1155,38 @@ class ApplicationEditPage extends React.Component {
             </Button>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Enable WS-Federation"), i18next.t("application:Enable WS-Federation - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.enableWsFed} onChange={checked => {
+              this.updateApplicationField("enableWsFed", checked);
+            }} />
+          </Col>
+        </Row>
+        {
+          !this.state.application.enableWsFed ? null : (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("application:WS-Federation token type"), i18next.t("application:WS-Federation token type - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Select virtual={false} style={{width: "100%"}} value={this.state.application.wsFedTokenType === "" ? "SAML 1.1" : this.state.application.wsFedTokenType}
+                  onChange={(value => {this.updateApplicationField("wsFedTokenType", value);})}
+                  options={["SAML 1.1", "SAML 2.0"].map((item) => Setting.getOption(item, item))}
+                />
+                <Button style={{marginTop: "10px"}} type="primary" shape="round" icon={<CopyOutlined />} onClick={() => {
+                  copy(`${window.location.origin}/api/wsfed/metadata/${this.state.application.owner}/${encodeURIComponent(this.state.applicationName)}`);
+                  Setting.showMessage("success", i18next.t("general:Copied to clipboard successfully"));
+                }}
+                >
+                  {i18next.t("application:Copy WS-Federation metadata URL")}
+                </Button>
+              </Col>
+            </Row>
+          )
+        }
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("general:Providers"), i18next.t("general:Providers - Tooltip"))} :
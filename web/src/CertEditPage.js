@@ -13,7 +13,7 @@
 // limitations under the License.
 
 import React from "react";
-import {Button, Card, Col, Input, InputNumber, Row, Select} from "antd";
+import {Button, Card, Col, Input, InputNumber, Row, Select, Switch, Table} from "antd";
 import * as CertBackend from "./backend/CertBackend";
 import * as OrganizationBackend from "./backend/OrganizationBackend";
 import * as Setting from "./Setting";
@@ -32,6 +32,7 @@ class CertEditPage extends React.Component {
       certName: props.match.params.certName,
       owner: props.match.params.organizationName,
       cert: null,
+      certRotations: [],
       organizations: [],
       mode: props.location.mode !== undefined ? props.location.mode : "edit",
     };
@@ -39,6 +40,7 @@ class CertEditPage extends React.Component {
 
   UNSAFE_componentWillMount() {
     this.getCert();
+    this.getCertRotations();
     this.getOrganizations();
   }
 
@@ -61,6 +63,61 @@ class CertEditPage extends React.Component {
       });
   }
 
+  getCertRotations() {
+    CertBackend.getCertRotations(this.state.owner, this.state.certName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            certRotations: res.data || [],
+          });
+        }
+      });
+  }
+
+  rotateCert() {
+    CertBackend.rotateCert(this.state.cert.owner, this.state.cert.name)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("cert:Successfully rotated"));
+          this.getCert();
+          this.getCertRotations();
+        } else {
+          Setting.showMessage("error", `${i18next.t("cert:Failed to rotate")}: ${res.msg}`);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  renderCertRotations() {
+    const columns = [
+      {
+        title: i18next.t("general:Created time"),
+        dataIndex: "createdTime",
+        key: "createdTime",
+        width: "200px",
+        render: (text, record, index) => {
+          return Setting.getFormattedDate(text);
+        },
+      },
+      {
+        title: i18next.t("cert:Retired key ID"),
+        dataIndex: "keyId",
+        key: "keyId",
+      },
+      {
+        title: i18next.t("cert:Key ID"),
+        dataIndex: "nextKeyId",
+        key: "nextKeyId",
+      },
+    ];
+
+    return (
+      <Table rowKey="name" columns={columns} dataSource={this.state.certRotations} size="middle" bordered pagination={{pageSize: 10}} />
+    );
+  }
+
   getOrganizations() {
     OrganizationBackend.getOrganizations("admin")
       .then((res) => {
@@ -229,6 +286,59 @@ class CertEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        {
+          this.state.cert.type !== "x509" ? null : (
+            <React.Fragment>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Enable rotation"), i18next.t("cert:Enable rotation - Tooltip"))} :
+                </Col>
+                <Col span={1} >
+                  <Switch checked={this.state.cert.enableRotation} onChange={checked => {
+                    this.updateCertField("enableRotation", checked);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Rotation interval"), i18next.t("cert:Rotation interval - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <InputNumber min={0} disabled={!this.state.cert.enableRotation} value={this.state.cert.rotationInterval} onChange={value => {
+                    this.updateCertField("rotationInterval", value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Key ID"), i18next.t("cert:Key ID - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input disabled value={this.state.cert.keyId === "" ? this.state.cert.name : this.state.cert.keyId} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Next key ID"), i18next.t("cert:Next key ID - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input disabled value={this.state.cert.nextKeyId} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("cert:Rotations"), i18next.t("cert:Rotations - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Button style={{marginBottom: "10px"}} disabled={this.state.mode === "add"} onClick={() => this.rotateCert()}>
+                    {i18next.t("cert:Rotate now")}
+                  </Button>
+                  {this.renderCertRotations()}
+                </Col>
+              </Row>
+            </React.Fragment>
+          )
+        }
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("cert:Certificate"), i18next.t("cert:Certificate - Tooltip"))} :
@@ -100,6 +100,12 @@ function ManagementPage(props) {
 
   function logout() {
     AuthBackend.logout()
+      .then((res) => {
+        if (res.status === "ok") {
+          return Setting.frontchannelLogout(res.data3).then(() => res);
+        }
+        return res;
+      })
       .then((res) => {
         if (res.status === "ok") {
           const owner = props.account.owner;
@@ -25,6 +25,7 @@ import LdapTable from "./table/LdapTable";
 import AccountTable from "./table/AccountTable";
 import ThemeEditor from "./common/theme/ThemeEditor";
 import MfaTable from "./table/MfaTable";
+import TrustedIssuerTable from "./table/TrustedIssuerTable";
 
 const {Option} = Select;
 
@@ -480,6 +481,18 @@ class OrganizationEditPage extends React.Component {
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("organization:Trusted issuers"), i18next.t("organization:Trusted issuers - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <TrustedIssuerTable
+              title={i18next.t("organization:Trusted issuers")}
+              table={this.state.organization.trustedIssuers ?? []}
+              onUpdateTable={(value) => {this.updateOrganizationField("trustedIssuers", value);}}
+            />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("theme:Theme"), i18next.t("theme:Theme - Tooltip"))} :
@@ -195,7 +195,7 @@ class RecordListPage extends BaseListPage {
         sorter: true,
         fixed: (Setting.isMobile()) ? "false" : "right",
         render: (text, record, index) => {
-          if (!["signup", "login", "logout", "update-user", "new-user"].includes(record.action)) {
+          if (!["signup", "login", "logout", "update-user", "new-user", "revoke-token", "reuse-refresh-token"].includes(record.action)) {
             return null;
           }
 
@@ -728,6 +728,24 @@ export function goToLink(link) {
   window.location.href = link;
 }
 
+// loads the front-channel logout URLs of the applications in hidden iframes, resolves when all of them are loaded
+export function frontchannelLogout(urls) {
+  if (!urls || urls.length === 0) {
+    return Promise.resolve();
+  }
+
+  const promises = urls.map((url) => new Promise((resolve) => {
+    const iframe = document.createElement("iframe");
+    iframe.style.display = "none";
+    iframe.src = url;
+    iframe.onload = resolve;
+    iframe.onerror = resolve;
+    document.body.appendChild(iframe);
+  }));
+  const timeout = new Promise((resolve) => setTimeout(resolve, 5000));
+  return Promise.race([Promise.all(promises), timeout]);
+}
+
 export function goToLinkSoft(ths, link) {
   if (link.startsWith("http")) {
     openLink(link);
@@ -33,6 +33,7 @@ import SamlWidget from "./common/SamlWidget";
 import RegionSelect from "./common/select/RegionSelect";
 import WebAuthnCredentialTable from "./table/WebauthnCredentialTable";
 import ManagedAccountTable from "./table/ManagedAccountTable";
+import ConsentTable from "./table/ConsentTable";
 import PropertyTable from "./table/propertyTable";
 import {CountryCodeSelect} from "./common/select/CountryCodeSelect";
 import PopconfirmModal from "./common/modal/PopconfirmModal";
@@ -1025,6 +1026,21 @@ class UserEditPage extends React.Component {
           </Col>
         </Row>
       );
+    } else if (accountItem.name === "Consents") {
+      if (!this.isSelf()) {
+        return null;
+      }
+
+      return (
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("user:Consents"), i18next.t("user:Consents - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <ConsentTable />
+          </Col>
+        </Row>
+      );
     } else if (accountItem.name === "Face ID") {
       return (
         <Row style={{marginTop: "20px"}} >
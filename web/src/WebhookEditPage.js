@@ -278,7 +278,7 @@ class WebhookEditPage extends React.Component {
               }} >
               {
                 (
-                  ["signup", "login", "logout", "new-user"].concat(this.getApiPaths()).map((option, index) => {
+                  ["signup", "login", "logout", "new-user", "revoke-token", "reuse-refresh-token"].concat(this.getApiPaths()).map((option, index) => {
                     return (
                       <Option key={option} value={option}>{option}</Option>
                     );
@@ -57,7 +57,7 @@ export function oAuthParamsToQuery(oAuthParams) {
   }
 
   // code
-  return `?clientId=${oAuthParams.clientId}&responseType=${oAuthParams.responseType}&redirectUri=${encodeURIComponent(oAuthParams.redirectUri)}&type=${oAuthParams.type}&scope=${oAuthParams.scope}&state=${oAuthParams.state}&nonce=${oAuthParams.nonce}&code_challenge_method=${oAuthParams.challengeMethod}&code_challenge=${oAuthParams.codeChallenge}`;
+  return `?clientId=${oAuthParams.clientId}&responseType=${oAuthParams.responseType}&redirectUri=${encodeURIComponent(oAuthParams.redirectUri)}&type=${oAuthParams.type}&scope=${oAuthParams.scope}&state=${oAuthParams.state}&nonce=${oAuthParams.nonce}&code_challenge_method=${oAuthParams.challengeMethod}&code_challenge=${oAuthParams.codeChallenge}&requestUri=${encodeURIComponent(oAuthParams.requestUri ?? "")}&request=${oAuthParams.request ?? ""}&prompt=${oAuthParams.prompt ?? ""}&acr_values=${encodeURIComponent(oAuthParams.acrValues ?? "")}&max_age=${oAuthParams.maxAge ?? ""}&claims=${encodeURIComponent(oAuthParams.claims ?? "")}&authorization_details=${encodeURIComponent(oAuthParams.authorizationDetails ?? "")}`;
 }
 
 export function getApplicationLogin(params) {
@@ -83,7 +83,8 @@ export function login(values, oAuthParams) {
 }
 
 export function loginCas(values, params) {
-  return fetch(`${authConfig.serverUrl}/api/login?service=${params.service}`, {
+  const renew = params.renew ? `&renew=${encodeURIComponent(params.renew)}` : "";
+  return fetch(`${authConfig.serverUrl}/api/login?service=${params.service}${renew}`, {
     method: "POST",
     credentials: "include",
     body: JSON.stringify(values),
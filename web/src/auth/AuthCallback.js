@@ -31,6 +31,7 @@ class AuthCallback extends React.Component {
       samlResponse: "",
       relayState: "",
       redirectUrl: "",
+      redirectFields: null,
     };
   }
 
@@ -56,8 +57,10 @@ class AuthCallback extends React.Component {
         const samlRequest = innerParams.get("SAMLRequest");
         // cas don't use 'redirect_url', it is called 'service'
         const casService = innerParams.get("service");
-        if (samlRequest !== null && samlRequest !== undefined && samlRequest !== "") {
+        if ((samlRequest !== null && samlRequest !== undefined && samlRequest !== "") || innerParams.get("idpInitiated") !== null) {
           return "saml";
+        } else if (innerParams.get("wa") === "wsignin1.0") {
+          return "wsfed";
         } else if (casService !== null && casService !== undefined && casService !== "") {
           return "cas";
         }
@@ -121,6 +124,12 @@ class AuthCallback extends React.Component {
       provider: providerName,
       code: code,
       samlRequest: samlRequest,
+      samlQuery: innerParams.toString(),
+      idpInitiated: innerParams.get("idpInitiated") !== null,
+      relayState: innerParams.get("RelayState") ?? "",
+      wsFedRealm: innerParams.get("wtrealm") ?? "",
+      wsFedReply: innerParams.get("wreply") ?? "",
+      wsFedContext: innerParams.get("wctx") ?? "",
       // state: innerParams.get("state"),
       state: applicationName,
       redirectUri: redirectUri,
@@ -176,7 +185,8 @@ class AuthCallback extends React.Component {
               return;
             }
             const code = res.data;
-            Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}code=${code}&state=${oAuthParams.state}`);
+            const sessionState = res.data3 ? `&session_state=${encodeURIComponent(res.data3)}` : "";
+            Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}code=${code}&state=${oAuthParams.state}${sessionState}`);
             // Setting.showMessage("success", `Authorization code: ${res.data}`);
           } else if (responseType === "token" || responseType === "id_token") {
             if (res.data2) {
@@ -185,16 +195,18 @@ class AuthCallback extends React.Component {
               return;
             }
             const token = res.data;
-            Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}${responseType}=${token}&state=${oAuthParams.state}&token_type=bearer`);
+            const sessionState = res.data3 ? `&session_state=${encodeURIComponent(res.data3)}` : "";
+            Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}${responseType}=${token}&state=${oAuthParams.state}&token_type=bearer${sessionState}`);
           } else if (responseType === "link") {
             const from = innerParams.get("from");
             Setting.goToLinkSoftOrJumpSelf(this, from);
           } else if (responseType === "saml") {
+            const relayState = res.data2.relayState ?? oAuthParams.relayState;
             if (res.data2.method === "POST") {
               this.setState({
                 samlResponse: res.data,
                 redirectUrl: res.data2.redirectUrl,
-                relayState: oAuthParams.relayState,
+                relayState: relayState,
               });
             } else {
               if (res.data2.needUpdatePassword) {
@@ -202,10 +214,18 @@ class AuthCallback extends React.Component {
                 Setting.goToLinkSoft(this, `/forget/${applicationName}`);
                 return;
               }
-              const SAMLResponse = res.data;
-              const redirectUri = res.data2.redirectUrl;
-              Setting.goToLink(`${redirectUri}?SAMLResponse=${encodeURIComponent(SAMLResponse)}&RelayState=${oAuthParams.relayState}`);
+              Setting.goToLink(Util.getSamlResponseUrl(res, relayState));
             }
+          } else if (responseType === "wsfed") {
+            if (res.data2.needUpdatePassword) {
+              sessionStorage.setItem("signinUrl", signinUrl);
+              Setting.goToLinkSoft(this, `/forget/${applicationName}`);
+              return;
+            }
+            this.setState({
+              redirectUrl: res.data2.redirectUrl,
+              redirectFields: Util.getWsFedFields(res),
+            });
           }
         } else {
           this.setState({
@@ -220,6 +240,10 @@ class AuthCallback extends React.Component {
       return <RedirectForm samlResponse={this.state.samlResponse} redirectUrl={this.state.redirectUrl} relayState={this.state.relayState} />;
     }
 
+    if (this.state.redirectFields !== null) {
+      return <RedirectForm redirectUrl={this.state.redirectUrl} fields={this.state.redirectFields} />;
+    }
+
     return (
       <div style={{display: "flex", justifyContent: "center", alignItems: "center"}}>
         {
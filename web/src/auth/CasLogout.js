@@ -65,7 +65,7 @@ class CasLogout extends React.Component {
     AuthBackend.logout()
       .then((res) => {
         if (res.status === "ok") {
-          logoutTimeOut(res.data2);
+          Setting.frontchannelLogout(res.data3).then(() => logoutTimeOut(res.data2));
         } else {
           Setting.showMessage("error", `${i18next.t("login:Failed to log out")}: ${res.msg}`);
         }
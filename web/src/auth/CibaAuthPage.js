@@ -0,0 +1,150 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Empty, Result, Space} from "antd";
+import i18next from "i18next";
+import * as TokenBackend from "../backend/TokenBackend";
+import * as Setting from "../Setting";
+
+class CibaAuthPage extends React.Component {
+  constructor(props) {
+    super(props);
+    const params = new URLSearchParams(window.location.search);
+    this.state = {
+      classes: props,
+      id: params.get("id") ?? "",
+      cibaAuths: null,
+      applications: {},
+      result: "",
+    };
+  }
+
+  componentDidMount() {
+    this.getCibaAuths();
+  }
+
+  getCibaAuths() {
+    TokenBackend.getCibaAuths()
+      .then((res) => {
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        // the request opened from the notification is shown alone
+        let cibaAuths = res.data;
+        if (this.state.id !== "" && cibaAuths.some(cibaAuth => cibaAuth.id === this.state.id)) {
+          cibaAuths = cibaAuths.filter(cibaAuth => cibaAuth.id === this.state.id);
+        }
+
+        if (cibaAuths.length === 1 && res.data2[cibaAuths[0].application]) {
+          this.props.onUpdateApplication(res.data2[cibaAuths[0].application]);
+        }
+        this.setState({
+          cibaAuths: cibaAuths,
+          applications: res.data2,
+        });
+      });
+  }
+
+  approveCibaAuth(id, approved) {
+    TokenBackend.approveCibaAuth(id, approved)
+      .then((res) => {
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        const cibaAuths = this.state.cibaAuths.filter(cibaAuth => cibaAuth.id !== id);
+        if (cibaAuths.length === 0) {
+          this.setState({
+            result: approved ? "success" : "warning",
+          });
+        } else {
+          Setting.showMessage("success", approved ? i18next.t("login:The sign-in request has been approved") : i18next.t("login:The sign-in request has been denied"));
+          this.setState({
+            cibaAuths: cibaAuths,
+          });
+        }
+      });
+  }
+
+  renderCibaAuth(cibaAuth) {
+    const application = this.state.applications[cibaAuth.application];
+    return (
+      <Card key={cibaAuth.id} style={{width: "400px", marginTop: "20px", textAlign: "center"}}>
+        <Space direction="vertical" style={{width: "100%"}}>
+          {
+            !application ? null : Setting.renderLogo(application)
+          }
+          <div>
+            {`${application?.displayName ?? cibaAuth.application} ${i18next.t("login:is requesting access to your account")}`}
+          </div>
+          {
+            cibaAuth.bindingMessage === "" ? null : (
+              <div>
+                {`${i18next.t("login:Binding message")}: ${cibaAuth.bindingMessage}`}
+              </div>
+            )
+          }
+          <div>
+            {`${i18next.t("general:Scope")}: ${cibaAuth.scope === "" ? "-" : cibaAuth.scope}`}
+          </div>
+          <div>
+            {`${i18next.t("general:Created time")}: ${Setting.getFormattedDate(cibaAuth.createdTime)}`}
+          </div>
+          <Space>
+            <Button type="primary" onClick={() => this.approveCibaAuth(cibaAuth.id, true)}>
+              {i18next.t("general:Confirm")}
+            </Button>
+            <Button onClick={() => this.approveCibaAuth(cibaAuth.id, false)}>
+              {i18next.t("general:Cancel")}
+            </Button>
+          </Space>
+        </Space>
+      </Card>
+    );
+  }
+
+  render() {
+    if (this.state.result !== "") {
+      return (
+        <Result
+          status={this.state.result}
+          title={this.state.result === "success" ? i18next.t("login:The sign-in request has been approved") : i18next.t("login:The sign-in request has been denied")}
+          subTitle={i18next.t("login:You can close this page now")}
+        />
+      );
+    }
+
+    if (this.state.cibaAuths === null) {
+      return null;
+    }
+
+    return (
+      <div style={{display: "flex", flex: "1", flexDirection: "column", alignItems: "center", marginTop: "10%"}}>
+        {
+          this.state.cibaAuths.length === 0 ? (
+            <Card style={{width: "400px", textAlign: "center"}}>
+              <Empty description={i18next.t("login:There is no pending sign-in request")} />
+            </Card>
+          ) : this.state.cibaAuths.map(cibaAuth => this.renderCibaAuth(cibaAuth))
+        }
+      </div>
+    );
+  }
+}
+
+export default CibaAuthPage;
@@ -0,0 +1,104 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, List, Space} from "antd";
+import i18next from "i18next";
+import * as ConsentBackend from "../backend/ConsentBackend";
+import * as Setting from "../Setting";
+
+export function getScopeDescription(scope, scopeDescriptions = {}) {
+  switch (scope) {
+  case "openid":
+    return i18next.t("consent:Sign you in with your account");
+  case "profile":
+    return i18next.t("consent:View your basic profile, such as name and avatar");
+  case "email":
+    return i18next.t("consent:View your email address");
+  case "phone":
+    return i18next.t("consent:View your phone number");
+  case "address":
+    return i18next.t("consent:View your address");
+  case "offline_access":
+    return i18next.t("consent:Keep access to your account when you are not using the application");
+  default:
+    return scopeDescriptions[scope] ?? scope;
+  }
+}
+
+export function getAuthorizationDetailDescription(detail, authorizationDetailsTypes = {}) {
+  const {type, ...fields} = detail;
+  const description = authorizationDetailsTypes[type] ? authorizationDetailsTypes[type] : type;
+  return (
+    <Space direction="vertical" size={0}>
+      <div>{description}</div>
+      {
+        Object.keys(fields).map((key) => <div key={key} style={{color: "grey"}}>{`${key}: ${typeof fields[key] === "object" ? JSON.stringify(fields[key]) : fields[key]}`}</div>)
+      }
+    </Space>
+  );
+}
+
+class ConsentForm extends React.Component {
+  grantConsent() {
+    const consentRequest = this.props.consentRequest;
+    ConsentBackend.grantConsent(consentRequest.application, consentRequest.scopes.join(" "))
+      .then((res) => {
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        this.props.onAllow();
+      });
+  }
+
+  render() {
+    const consentRequest = this.props.consentRequest;
+    return (
+      <Space direction="vertical" style={{width: "100%"}}>
+        <div style={{fontSize: 16}}>
+          {`${consentRequest.displayName !== "" ? consentRequest.displayName : consentRequest.application} ${i18next.t("login:is requesting access to your account")}`}
+        </div>
+        {
+          consentRequest.homepageUrl !== "" ? <a target="_blank" rel="noreferrer" href={consentRequest.homepageUrl}>{consentRequest.homepageUrl}</a> : null
+        }
+        <List size="small" bordered style={{textAlign: "left"}}
+          dataSource={consentRequest.scopes}
+          locale={{emptyText: i18next.t("consent:No additional access is requested")}}
+          renderItem={(scope) => <List.Item>{getScopeDescription(scope, consentRequest.scopeDescriptions ?? {})}</List.Item>}
+        />
+        {
+          (consentRequest.authorizationDetails ?? []).length > 0 ? (
+            <List size="small" bordered style={{textAlign: "left"}}
+              header={i18next.t("consent:Authorization details")}
+              dataSource={consentRequest.authorizationDetails}
+              renderItem={(detail) => <List.Item>{getAuthorizationDetailDescription(detail, consentRequest.authorizationDetailsTypes ?? {})}</List.Item>}
+            />
+          ) : null
+        }
+        <Space>
+          <Button type="primary" onClick={() => this.grantConsent()}>
+            {i18next.t("consent:Allow")}
+          </Button>
+          <Button onClick={() => this.props.onDeny()}>
+            {i18next.t("consent:Deny")}
+          </Button>
+        </Space>
+      </Space>
+    );
+  }
+}
+
+export default ConsentForm;
@@ -0,0 +1,135 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Input, Result, Space} from "antd";
+import i18next from "i18next";
+import * as TokenBackend from "../backend/TokenBackend";
+import * as Setting from "../Setting";
+
+class DeviceAuthPage extends React.Component {
+  constructor(props) {
+    super(props);
+    const params = new URLSearchParams(window.location.search);
+    this.state = {
+      classes: props,
+      userCode: params.get("user_code") ?? "",
+      deviceAuth: null,
+      application: null,
+      result: "",
+    };
+  }
+
+  componentDidMount() {
+    if (this.state.userCode !== "") {
+      this.getDeviceAuth();
+    }
+  }
+
+  getDeviceAuth() {
+    TokenBackend.getDeviceAuth(this.state.userCode)
+      .then((res) => {
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        this.props.onUpdateApplication(res.data2);
+        this.setState({
+          deviceAuth: res.data,
+          application: res.data2,
+        });
+      });
+  }
+
+  approveDeviceAuth(approved) {
+    TokenBackend.approveDeviceAuth(this.state.userCode, approved)
+      .then((res) => {
+        if (res.status === "error") {
+          Setting.showMessage("error", res.msg);
+          return;
+        }
+
+        this.setState({
+          result: approved ? "success" : "warning",
+        });
+      });
+  }
+
+  renderUserCodeForm() {
+    return (
+      <Space direction="vertical" style={{width: "100%"}}>
+        <div>{i18next.t("login:Enter the code displayed on your device")}</div>
+        <Input size="large" value={this.state.userCode} placeholder="XXXX-XXXX" onChange={e => {
+          this.setState({userCode: e.target.value});
+        }} onPressEnter={() => this.getDeviceAuth()} />
+        <Button type="primary" block onClick={() => this.getDeviceAuth()}>
+          {i18next.t("code:Submit and complete")}
+        </Button>
+      </Space>
+    );
+  }
+
+  renderConfirmation() {
+    const application = this.state.application;
+    return (
+      <Space direction="vertical" style={{width: "100%"}}>
+        {
+          Setting.renderHelmet(application)
+        }
+        {
+          Setting.renderLogo(application)
+        }
+        <div>
+          {`${application.displayName} ${i18next.t("login:is requesting access to your account")}`}
+        </div>
+        <div>
+          {`${i18next.t("general:Scope")}: ${this.state.deviceAuth.scope === "" ? "-" : this.state.deviceAuth.scope}`}
+        </div>
+        <Space>
+          <Button type="primary" onClick={() => this.approveDeviceAuth(true)}>
+            {i18next.t("general:Confirm")}
+          </Button>
+          <Button onClick={() => this.approveDeviceAuth(false)}>
+            {i18next.t("general:Cancel")}
+          </Button>
+        </Space>
+      </Space>
+    );
+  }
+
+  render() {
+    if (this.state.result !== "") {
+      return (
+        <Result
+          status={this.state.result}
+          title={this.state.result === "success" ? i18next.t("login:Your device has been signed in") : i18next.t("login:The device sign-in request has been denied")}
+          subTitle={i18next.t("login:You can close this page now")}
+        />
+      );
+    }
+
+    return (
+      <div style={{display: "flex", flex: "1", justifyContent: "center"}}>
+        <Card style={{width: "400px", marginTop: "10%", textAlign: "center"}}>
+          {
+            this.state.deviceAuth === null ? this.renderUserCodeForm() : this.renderConfirmation()
+          }
+        </Card>
+      </div>
+    );
+  }
+}
+
+export default DeviceAuthPage;
@@ -34,6 +34,7 @@ import LanguageSelect from "../common/select/LanguageSelect";
 import {CaptchaModal, CaptchaRule} from "../common/modal/CaptchaModal";
 import RedirectForm from "../common/RedirectForm";
 import {MfaAuthVerifyForm, NextMfa, RequiredMfa} from "./mfa/MfaAuthVerifyForm";
+import ConsentForm from "./ConsentForm";
 import {GoogleOneTapLoginVirtualButton} from "./GoogleLoginButton";
 import * as ProviderButton from "./ProviderButton";
 const FaceRecognitionModal = lazy(() => import("../common/modal/FaceRecognitionModal"));
@@ -58,6 +59,7 @@ class LoginPage extends React.Component {
       samlResponse: "",
       relayState: "",
       redirectUrl: "",
+      redirectFields: null,
       isTermsOfUseVisible: false,
       termsOfUseContent: "",
       orgChoiceMode: new URLSearchParams(props.location?.search).get("orgChoiceMode") ?? null,
@@ -106,7 +108,7 @@ class LoginPage extends React.Component {
     }
 
     if (prevProps.account !== this.props.account && this.props.account !== undefined) {
-      if (this.props.account && this.props.account.owner === this.props.application?.organization) {
+      if (this.props.account && this.props.account.owner === this.props.application?.organization && !this.isCasRenew()) {
         const params = new URLSearchParams(this.props.location.search);
         const silentSignin = params.get("silentSignin");
         if (silentSignin !== null) {
@@ -148,12 +150,30 @@ class LoginPage extends React.Component {
       });
   }
 
+  replaceWithAuthRequest(requestUri, authRequest) {
+    // the pushed or signed request is the source of truth, rewrite the URL so the rest of the login page reads it
+    const params = new URLSearchParams();
+    params.set("client_id", authRequest.clientId);
+    params.set("response_type", authRequest.responseType);
+    params.set("redirect_uri", authRequest.redirectUri);
+    params.set("scope", authRequest.scope);
+    params.set("state", authRequest.state);
+    params.set("nonce", authRequest.nonce);
+    params.set("code_challenge_method", authRequest.challengeMethod);
+    params.set("code_challenge", authRequest.codeChallenge);
+    params.set("request_uri", requestUri);
+    window.history.replaceState(null, "", `${window.location.pathname}?${params.toString()}`);
+  }
+
   getApplicationLogin() {
     const loginParams = (this.state.type === "cas") ? Util.getCasLoginParameters("admin", this.state.applicationName) : Util.getOAuthGetParameters();
     AuthBackend.getApplicationLogin(loginParams)
       .then((res) => {
         if (res.status === "ok") {
           const application = res.data;
+          if (res.data2?.authRequest) {
+            this.replaceWithAuthRequest(res.data2.requestUri, res.data2.authRequest);
+          }
           this.onUpdateApplication(application);
         } else {
           this.onUpdateApplication(null);
@@ -275,10 +295,19 @@ class LoginPage extends React.Component {
 
     values["type"] = oAuthParams?.responseType ?? this.state.type;
 
-    if (oAuthParams?.samlRequest) {
+    if (oAuthParams?.samlRequest || oAuthParams?.idpInitiated) {
       values["samlRequest"] = oAuthParams.samlRequest;
       values["type"] = "saml";
       values["relayState"] = oAuthParams.relayState;
+      values["samlQuery"] = oAuthParams.samlQuery;
+      values["idpInitiated"] = oAuthParams.idpInitiated;
+    }
+
+    if (oAuthParams?.wsFedRealm) {
+      values["type"] = "wsfed";
+      values["wsFedRealm"] = oAuthParams.wsFedRealm;
+      values["wsFedReply"] = oAuthParams.wsFedReply;
+      values["wsFedContext"] = oAuthParams.wsFedContext;
     }
   }
 
@@ -296,7 +325,8 @@ class LoginPage extends React.Component {
     const code = resp.data;
     const concatChar = oAuthParams?.redirectUri?.includes("?") ? "&" : "?";
     const noRedirect = oAuthParams.noRedirect;
-    const redirectUrl = `${oAuthParams.redirectUri}${concatChar}code=${code}&state=${oAuthParams.state}`;
+    const sessionState = resp.data3 ? `&session_state=${encodeURIComponent(resp.data3)}` : "";
+    const redirectUrl = `${oAuthParams.redirectUri}${concatChar}code=${code}&state=${oAuthParams.state}${sessionState}`;
     if (resp.data === RequiredMfa) {
       this.props.onLoginSuccess(window.location.href);
       return;
@@ -449,6 +479,15 @@ class LoginPage extends React.Component {
           const loginHandler = (res) => {
             const responseType = values["type"];
 
+            if (res.data === "Consent") {
+              // the third-party application needs the consent of the user
+              this.setState({
+                getVerifyTotp: undefined,
+                consentRequest: res.data2,
+              });
+              return;
+            }
+
             if (responseType === "login") {
               if (res.data2) {
                 sessionStorage.setItem("signinUrl", window.location.href);
@@ -465,23 +504,32 @@ class LoginPage extends React.Component {
               }
               const amendatoryResponseType = responseType === "token" ? "access_token" : responseType;
               const accessToken = res.data;
-              Setting.goToLink(`${oAuthParams.redirectUri}#${amendatoryResponseType}=${accessToken}&state=${oAuthParams.state}&token_type=bearer`);
+              const sessionState = res.data3 ? `&session_state=${encodeURIComponent(res.data3)}` : "";
+              Setting.goToLink(`${oAuthParams.redirectUri}#${amendatoryResponseType}=${accessToken}&state=${oAuthParams.state}&token_type=bearer${sessionState}`);
             } else if (responseType === "saml") {
               if (res.data2.needUpdatePassword) {
                 sessionStorage.setItem("signinUrl", window.location.href);
                 Setting.goToLink(this, `/forget/${this.state.applicationName}`);
               }
+              const relayState = res.data2.relayState ?? oAuthParams.relayState;
               if (res.data2.method === "POST") {
                 this.setState({
                   samlResponse: res.data,
                   redirectUrl: res.data2.redirectUrl,
-                  relayState: oAuthParams.relayState,
+                  relayState: relayState,
                 });
               } else {
-                const SAMLResponse = res.data;
-                const redirectUri = res.data2.redirectUrl;
-                Setting.goToLink(`${redirectUri}?SAMLResponse=${encodeURIComponent(SAMLResponse)}&RelayState=${oAuthParams.relayState}`);
+                Setting.goToLink(Util.getSamlResponseUrl(res, relayState));
               }
+            } else if (responseType === "wsfed") {
+              if (res.data2.needUpdatePassword) {
+                sessionStorage.setItem("signinUrl", window.location.href);
+                Setting.goToLink(this, `/forget/${this.state.applicationName}`);
+              }
+              this.setState({
+                redirectUrl: res.data2.redirectUrl,
+                redirectFields: Util.getWsFedFields(res),
+              });
             }
           };
 
@@ -950,6 +998,12 @@ class LoginPage extends React.Component {
     );
   }
 
+  // isCasRenew returns whether the CAS service asks the user to sign in again instead of using the existing session
+  isCasRenew() {
+    const renew = Util.getCasParameters().renew;
+    return this.state.type === "cas" && renew !== "" && renew !== "false";
+  }
+
   sendSilentSigninData(data) {
     if (Setting.inIframe()) {
       const message = {tag: "Casdoor", type: "SilentSignin", data: data};
@@ -964,7 +1018,7 @@ class LoginPage extends React.Component {
     }
 
     const application = this.getApplicationObj();
-    if (this.props.account.owner !== application?.organization) {
+    if (this.props.account.owner !== application?.organization || this.isCasRenew()) {
       return null;
     }
 
@@ -1020,7 +1074,7 @@ class LoginPage extends React.Component {
         const userHandle = assertion.response.userHandle;
         let finishUrl = `${Setting.ServerUrl}/api/webauthn/signin/finish?responseType=${values["type"]}`;
         if (values["type"] === "code") {
-          finishUrl = `${Setting.ServerUrl}/api/webauthn/signin/finish?responseType=${values["type"]}&clientId=${oAuthParams.clientId}&scope=${oAuthParams.scope}&redirectUri=${oAuthParams.redirectUri}&nonce=${oAuthParams.nonce}&state=${oAuthParams.state}&codeChallenge=${oAuthParams.codeChallenge}&challengeMethod=${oAuthParams.challengeMethod}`;
+          finishUrl = `${Setting.ServerUrl}/api/webauthn/signin/finish?responseType=${values["type"]}&clientId=${oAuthParams.clientId}&scope=${oAuthParams.scope}&redirectUri=${oAuthParams.redirectUri}&nonce=${oAuthParams.nonce}&state=${oAuthParams.state}&codeChallenge=${oAuthParams.codeChallenge}&challengeMethod=${oAuthParams.challengeMethod}&requestUri=${encodeURIComponent(oAuthParams.requestUri)}`;
         }
         return fetch(finishUrl, {
           method: "POST",
@@ -1149,6 +1203,12 @@ class LoginPage extends React.Component {
     }
   }
 
+  denyConsent() {
+    const oAuthParams = Util.getOAuthGetParameters();
+    const separator = (oAuthParams.responseType === "token" || oAuthParams.responseType === "id_token") ? "#" : (oAuthParams.redirectUri.includes("?") ? "&" : "?");
+    Setting.goToLink(`${oAuthParams.redirectUri}${separator}error=access_denied&state=${oAuthParams.state}`);
+  }
+
   renderLoginPanel(application) {
     const orgChoiceMode = application.orgChoiceMode;
 
@@ -1156,6 +1216,18 @@ class LoginPage extends React.Component {
       return this.renderOrganizationChoiceBox(orgChoiceMode);
     }
 
+    if (this.state.consentRequest) {
+      return (
+        <ConsentForm consentRequest={this.state.consentRequest}
+          onAllow={() => {
+            this.setState({consentRequest: null});
+            this.login({application: application.name});
+          }}
+          onDeny={() => this.denyConsent()}
+        />
+      );
+    }
+
     if (this.state.getVerifyTotp !== undefined) {
       return this.state.getVerifyTotp();
     } else {
@@ -1257,6 +1329,10 @@ class LoginPage extends React.Component {
       return <RedirectForm samlResponse={this.state.samlResponse} redirectUrl={this.state.redirectUrl} relayState={this.state.relayState} />;
     }
 
+    if (this.state.redirectFields !== null) {
+      return <RedirectForm redirectUrl={this.state.redirectUrl} fields={this.state.redirectFields} />;
+    }
+
     if (application.signinHtml !== "") {
       return (
         <div dangerouslySetInnerHTML={{__html: application.signinHtml}} />
@@ -97,6 +97,22 @@ function getRawGetParameter(key) {
   return res;
 }
 
+// getSamlResponseUrl returns the URL sending the SAML response to the redirect URL with the HTTP-Redirect binding,
+// or the artifact of it with the HTTP-Artifact binding
+export function getSamlResponseUrl(res, relayState) {
+  const param = res.data2.method === "Artifact" ? "SAMLart" : "SAMLResponse";
+  return `${res.data2.redirectUrl}?${param}=${encodeURIComponent(res.data)}&RelayState=${encodeURIComponent(relayState ?? "")}`;
+}
+
+// getWsFedFields returns the fields posting the WS-Federation sign-in response to the reply URL of the relying party
+export function getWsFedFields(res) {
+  const fields = {wa: "wsignin1.0", wresult: res.data};
+  if (res.data2.wctx) {
+    fields["wctx"] = res.data2.wctx;
+  }
+  return fields;
+}
+
 export function getCasLoginParameters(owner, name) {
   const queries = new URLSearchParams(window.location.search);
   // CAS service
@@ -140,9 +156,22 @@ export function getOAuthGetParameters(params) {
   const codeChallenge = getRefinedValue(queries.get("code_challenge"));
   const samlRequest = getRefinedValue(queries.get("SAMLRequest"));
   const relayState = getRefinedValue(queries.get("RelayState"));
+  const idpInitiated = getRefinedValue(queries.get("idpInitiated")) !== "";
+  const wsFedRealm = queries.get("wa") === "wsignin1.0" ? getRefinedValue(queries.get("wtrealm")) : "";
+  const wsFedReply = getRefinedValue(queries.get("wreply"));
+  const wsFedContext = getRefinedValue(queries.get("wctx"));
+  // the HTTP-Redirect binding signs the raw query
+  const samlQuery = (params !== undefined) ? queries.toString() : window.location.search.substring(1);
   const noRedirect = getRefinedValue(queries.get("noRedirect"));
+  const requestUri = getRefinedValue(queries.get("request_uri"));
+  const request = getRefinedValue(queries.get("request"));
+  const prompt = getRefinedValue(queries.get("prompt"));
+  const acrValues = getRefinedValue(queries.get("acr_values"));
+  const maxAge = getRefinedValue(queries.get("max_age"));
+  const claims = getRefinedValue(queries.get("claims"));
+  const authorizationDetails = getRefinedValue(queries.get("authorization_details"));
 
-  if (clientId === "" && samlRequest === "") {
+  if (clientId === "" && samlRequest === "" && !idpInitiated && wsFedRealm === "") {
     // login
     return null;
   } else {
@@ -158,7 +187,19 @@ export function getOAuthGetParameters(params) {
       codeChallenge: codeChallenge,
       samlRequest: samlRequest,
       relayState: relayState,
+      samlQuery: samlQuery,
+      idpInitiated: idpInitiated,
+      wsFedRealm: wsFedRealm,
+      wsFedReply: wsFedReply,
+      wsFedContext: wsFedContext,
       noRedirect: noRedirect,
+      requestUri: requestUri,
+      request: request,
+      prompt: prompt,
+      acrValues: acrValues,
+      maxAge: maxAge,
+      claims: claims,
+      authorizationDetails: authorizationDetails,
       type: "code",
     };
   }
@@ -98,3 +98,14 @@ export function getSamlMetadata(owner, name, enablePostBinding) {
     },
   }).then(res => res.text());
 }
+
+export function importSamlMetadata(owner, name, metadataUrl, metadata) {
+  return fetch(`${Setting.ServerUrl}/api/import-saml-metadata?id=${owner}/${encodeURIComponent(name)}&metadataUrl=${encodeURIComponent(metadataUrl)}`, {
+    method: "POST",
+    credentials: "include",
+    body: metadata,
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -69,3 +69,43 @@ export function deleteToken(token) {
     },
   }).then(res => res.json());
 }
+
+export function getDeviceAuth(userCode) {
+  return fetch(`${Setting.ServerUrl}/api/get-device-auth?userCode=${encodeURIComponent(userCode)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function approveDeviceAuth(userCode, approved) {
+  return fetch(`${Setting.ServerUrl}/api/approve-device-auth?userCode=${encodeURIComponent(userCode)}&approved=${approved}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function getCibaAuths() {
+  return fetch(`${Setting.ServerUrl}/api/get-ciba-auths`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function approveCibaAuth(id, approved) {
+  return fetch(`${Setting.ServerUrl}/api/approve-ciba-auth?id=${encodeURIComponent(id)}&approved=${approved}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
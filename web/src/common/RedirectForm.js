@@ -15,7 +15,9 @@
 import React, {useEffect} from "react";
 import i18next from "i18next";
 
+// RedirectForm posts the fields to the redirect URL, they are the SAML response and the RelayState by default
 export const RedirectForm = (props) => {
+  const fields = props.fields ?? {SAMLResponse: props.samlResponse, RelayState: props.relayState};
 
   useEffect(() => {
     document.getElementById("saml").submit();
@@ -25,18 +27,11 @@ export const RedirectForm = (props) => {
     <React.Fragment>
       <p>{i18next.t("login:Redirecting, please wait.")}</p>
       <form id="saml" method="post" action={props.redirectUrl}>
-        <input
-          type="hidden"
-          name="SAMLResponse"
-          id="samlResponse"
-          value={props.samlResponse}
-        />
-        <input
-          type="hidden"
-          name="RelayState"
-          id="relayState"
-          value={props.relayState}
-        />
+        {
+          Object.entries(fields).map(([name, value]) => (
+            <input key={name} type="hidden" name={name} value={value ?? ""} />
+          ))
+        }
       </form>
     </React.Fragment>
   );
@@ -0,0 +1,113 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Table, Tag} from "antd";
+import i18next from "i18next";
+import * as ConsentBackend from "../backend/ConsentBackend";
+import * as Setting from "../Setting";
+import PopconfirmModal from "../common/modal/PopconfirmModal";
+
+class ConsentTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      consents: [],
+    };
+  }
+
+  componentDidMount() {
+    this.getConsents();
+  }
+
+  getConsents() {
+    ConsentBackend.getUserConsents()
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({consents: res.data});
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      });
+  }
+
+  revokeConsent(consent) {
+    ConsentBackend.revokeConsent(`${consent.owner}/${consent.name}`)
+      .then((res) => {
+        if (res.status === "ok") {
+          Setting.showMessage("success", i18next.t("general:Successfully deleted"));
+          this.getConsents();
+        } else {
+          Setting.showMessage("error", `${i18next.t("general:Failed to delete")}: ${res.msg}`);
+        }
+      });
+  }
+
+  render() {
+    const columns = [
+      {
+        title: i18next.t("general:Application"),
+        dataIndex: "application",
+        key: "application",
+        width: "200px",
+      },
+      {
+        title: i18next.t("general:Scope"),
+        dataIndex: "scopes",
+        key: "scopes",
+        render: (text, record, index) => {
+          return text?.map((scope) => <Tag key={scope}>{scope}</Tag>);
+        },
+      },
+      {
+        title: i18next.t("general:Updated time"),
+        dataIndex: "updatedTime",
+        key: "updatedTime",
+        width: "180px",
+        render: (text, record, index) => {
+          return Setting.getFormattedDate(text);
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        key: "action",
+        width: "120px",
+        render: (text, record, index) => {
+          return (
+            <PopconfirmModal
+              text={i18next.t("user:Revoke")}
+              title={`${i18next.t("user:Revoke the consent to")}: ${record.application} ?`}
+              onConfirm={() => this.revokeConsent(record)}
+            />
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table rowKey={"name"} columns={columns} dataSource={this.state.consents} size="middle" bordered pagination={false}
+        title={() => (
+          <div>
+            {i18next.t("user:Consents")}&nbsp;&nbsp;&nbsp;&nbsp;
+            <Button style={{marginRight: "5px"}} size="small" onClick={() => this.getConsents()}>
+              {i18next.t("general:Refresh")}
+            </Button>
+          </div>
+        )}
+      />
+    );
+  }
+}
+
+export default ConsentTable;
@@ -0,0 +1,210 @@
+// Copyright 2024 The Casdoor Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {DeleteOutlined, DownOutlined, UpOutlined} from "@ant-design/icons";
+import {Button, Col, Input, Row, Select, Switch, Table, Tooltip} from "antd";
+import * as Setting from "../Setting";
+import i18next from "i18next";
+
+const {TextArea} = Input;
+
+const UserFields = ["Name", "Id", "Email", "Phone"];
+
+class TrustedIssuerTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+    };
+  }
+
+  updateTable(table) {
+    this.props.onUpdateTable(table);
+  }
+
+  updateField(table, index, key, value) {
+    table[index][key] = value;
+    this.updateTable(table);
+  }
+
+  addRow(table) {
+    const row = {issuer: "", jwks: "", jwksUri: "", audiences: [], subjectClaim: "sub", userField: "Name", createUser: false};
+    if (table === undefined) {
+      table = [];
+    }
+    table = Setting.addRow(table, row);
+    this.updateTable(table);
+  }
+
+  deleteRow(table, i) {
+    table = Setting.deleteRow(table, i);
+    this.updateTable(table);
+  }
+
+  upRow(table, i) {
+    table = Setting.swapRow(table, i - 1, i);
+    this.updateTable(table);
+  }
+
+  downRow(table, i) {
+    table = Setting.swapRow(table, i, i + 1);
+    this.updateTable(table);
+  }
+
+  renderTable(table) {
+    const columns = [
+      {
+        title: i18next.t("organization:Issuer"),
+        dataIndex: "issuer",
+        key: "issuer",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} onChange={e => {
+              this.updateField(table, index, "issuer", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Client JWKS URI"),
+        dataIndex: "jwksUri",
+        key: "jwksUri",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} onChange={e => {
+              this.updateField(table, index, "jwksUri", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Client JWKS"),
+        dataIndex: "jwks",
+        key: "jwks",
+        width: "250px",
+        render: (text, record, index) => {
+          return (
+            <TextArea autoSize={{minRows: 1, maxRows: 5}} value={text} onChange={e => {
+              this.updateField(table, index, "jwks", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("organization:Audiences"),
+        dataIndex: "audiences",
+        key: "audiences",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} mode="tags" style={{width: "100%"}} value={text ?? []} onChange={value => {
+              this.updateField(table, index, "audiences", value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("organization:Subject claim"),
+        dataIndex: "subjectClaim",
+        key: "subjectClaim",
+        width: "120px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} placeholder="sub" onChange={e => {
+              this.updateField(table, index, "subjectClaim", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("organization:User field"),
+        dataIndex: "userField",
+        key: "userField",
+        width: "120px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} style={{width: "100%"}} value={text}
+              options={UserFields.map((item) => Setting.getOption(item, item))}
+              onChange={value => {
+                this.updateField(table, index, "userField", value);
+              }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("organization:Create user"),
+        dataIndex: "createUser",
+        key: "createUser",
+        width: "100px",
+        render: (text, record, index) => {
+          return (
+            <Switch checked={text} onChange={checked => {
+              this.updateField(table, index, "createUser", checked);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        key: "action",
+        width: "100px",
+        render: (text, record, index) => {
+          return (
+            <div>
+              <Tooltip placement="bottomLeft" title={i18next.t("general:Up")}>
+                <Button style={{marginRight: "5px"}} disabled={index === 0} icon={<UpOutlined />} size="small" onClick={() => this.upRow(table, index)} />
+              </Tooltip>
+              <Tooltip placement="topLeft" title={i18next.t("general:Down")}>
+                <Button style={{marginRight: "5px"}} disabled={index === table.length - 1} icon={<DownOutlined />} size="small" onClick={() => this.downRow(table, index)} />
+              </Tooltip>
+              <Tooltip placement="topLeft" title={i18next.t("general:Delete")}>
+                <Button icon={<DeleteOutlined />} size="small" onClick={() => this.deleteRow(table, index)} />
+              </Tooltip>
+            </div>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table scroll={{x: "max-content"}} rowKey={(record) => table.indexOf(record)} columns={columns} dataSource={table} size="middle" bordered pagination={false}
+        title={() => (
+          <div>
+            {this.props.title}&nbsp;&nbsp;&nbsp;&nbsp;
+            <Button style={{marginRight: "5px"}} type="primary" size="small" onClick={() => this.addRow(table)}>{i18next.t("general:Add")}</Button>
+          </div>
+        )}
+      />
+    );
+  }
+
+  render() {
+    return (
+      <div>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={24}>
+            {
+              this.renderTable(this.props.table)
+            }
+          </Col>
+        </Row>
+      </div>
+    );
+  }
+}
+
+export default TrustedIssuerTable;